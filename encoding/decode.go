@@ -0,0 +1,398 @@
+package encoding
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Decode 将path路径对应的值反序列化到v中，v必须是非空指针，path的格式和GetJSON一致，path为空时反序列化整个JSON
+// 结构体字段的匹配规则和encoding/json一致，实现了json.Unmarshaler的类型(如time2.Duration)使用自身的反序列化方法
+// 如果path不存在或者值和v的类型不匹配，就会返回错误，错误中包含失败值的完整路径，如a.b.0.c
+func (j *JSON) Decode(path string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("decode target(%T) is not non-nil pointer", v)
+	}
+	res := j.res
+	if path != "" {
+		var err error
+		if res, err = j.getResult(path); err != nil {
+			return errors.Wrapf(err, "getResult(%v) fail.", path)
+		}
+	}
+	return decodeValue(res, path, rv.Elem())
+}
+
+// Encode 将v序列化后设置到path路径，path的格式和Set一致，path为空时用v替换整个JSON，会返回错误error
+// v不是指针时会复制到新的指针再序列化，使得指针方法实现的json.Marshaler(如time2.Duration)可以生效
+func (j *JSON) Encode(path string, v interface{}) error {
+	if rv := reflect.ValueOf(v); rv.IsValid() && rv.Kind() != reflect.Ptr {
+		pv := reflect.New(rv.Type())
+		pv.Elem().Set(rv)
+		v = pv.Interface()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "path(%v) marshal fail.", path)
+	}
+	if path == "" {
+		j.fromString(string(b))
+		return nil
+	}
+	return j.SetRawBytes(path, b)
+}
+
+// decodeValue 将res反序列化到rv中，path为res的完整路径
+func decodeValue(res gjson.Result, path string, rv reflect.Value) error {
+	if rv.CanAddr() {
+		pv := rv.Addr()
+		if pv.Type().Implements(jsonUnmarshalerType) {
+			if err := pv.Interface().(json.Unmarshaler).UnmarshalJSON([]byte(res.Raw)); err != nil {
+				return errors.Wrapf(err, "path(%v) unmarshal fail. val: %v", path, res.Raw)
+			}
+			return nil
+		}
+		if res.Type == gjson.String && pv.Type().Implements(textUnmarshalerType) {
+			if err := pv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(res.String())); err != nil {
+				return errors.Wrapf(err, "path(%v) unmarshal fail. val: %v", path, res.Raw)
+			}
+			return nil
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if res.Type == gjson.Null {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeValue(res, path, rv.Elem())
+	case reflect.Struct:
+		return decodeStruct(res, path, rv)
+	case reflect.Slice:
+		if res.Type == gjson.Null {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 && res.Type == gjson.String {
+			return decodeRaw(res, path, rv)
+		}
+		if !res.IsArray() {
+			return errors.Errorf("path(%v) is not array. val: %v", path, res.Raw)
+		}
+		items := res.Array()
+		s := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(item, joinPath(path, strconv.Itoa(i)), s.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(s)
+		return nil
+	case reflect.Array:
+		if !res.IsArray() {
+			return errors.Errorf("path(%v) is not array. val: %v", path, res.Raw)
+		}
+		items := res.Array()
+		for i := 0; i < rv.Len(); i++ {
+			if i >= len(items) {
+				rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+				continue
+			}
+			if err := decodeValue(items[i], joinPath(path, strconv.Itoa(i)), rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		return decodeMap(res, path, rv)
+	}
+	return decodeRaw(res, path, rv)
+}
+
+// decodeRaw 使用encoding/json反序列化基本类型和interface{}
+func decodeRaw(res gjson.Result, path string, rv reflect.Value) error {
+	if err := json.Unmarshal([]byte(res.Raw), rv.Addr().Interface()); err != nil {
+		return errors.Errorf("path(%v) is not %v. val: %v", path, rv.Type(), res.Raw)
+	}
+	return nil
+}
+
+func decodeStruct(res gjson.Result, path string, rv reflect.Value) (err error) {
+	if res.Type == gjson.Null {
+		return nil
+	}
+	if !res.IsObject() {
+		return errors.Errorf("path(%v) is not json. val: %v", path, res.Raw)
+	}
+	fields := structFields(rv.Type())
+	res.ForEach(func(key, value gjson.Result) bool {
+		f, ok := matchField(fields, key.String())
+		if !ok {
+			return true
+		}
+		var fv reflect.Value
+		if fv, err = fieldByIndex(rv, f.index); err != nil {
+			err = errors.Wrapf(err, "path(%v) fail.", joinPath(path, escapeKey(key.String())))
+			return false
+		}
+		if f.quoted && value.Type == gjson.String {
+			value = gjson.Parse(value.String())
+		}
+		err = decodeValue(value, joinPath(path, escapeKey(key.String())), fv)
+		return err == nil
+	})
+	return
+}
+
+func decodeMap(res gjson.Result, path string, rv reflect.Value) (err error) {
+	if res.Type == gjson.Null {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	if !res.IsObject() {
+		return errors.Errorf("path(%v) is not map. val: %v", path, res.Raw)
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(rv.Type()))
+	}
+	res.ForEach(func(key, value gjson.Result) bool {
+		p := joinPath(path, escapeKey(key.String()))
+		k := reflect.New(rv.Type().Key()).Elem()
+		if err = decodeKey(key.String(), k); err != nil {
+			err = errors.Wrapf(err, "path(%v) key fail.", p)
+			return false
+		}
+		v := reflect.New(rv.Type().Elem()).Elem()
+		if err = decodeValue(value, p, v); err != nil {
+			return false
+		}
+		rv.SetMapIndex(k, v)
+		return true
+	})
+	return
+}
+
+// decodeKey 和encoding/json一致，映射的键可以是字符串、整数或者实现了encoding.TextUnmarshaler的类型
+func decodeKey(key string, k reflect.Value) error {
+	if k.Addr().Type().Implements(textUnmarshalerType) {
+		return k.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key))
+	}
+	switch k.Kind() {
+	case reflect.String:
+		k.SetString(key)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, k.Type().Bits())
+		if err != nil {
+			return err
+		}
+		k.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(key, 10, k.Type().Bits())
+		if err != nil {
+			return err
+		}
+		k.SetUint(u)
+		return nil
+	}
+	return errors.Errorf("map key type %v is not supported", k.Type())
+}
+
+// field 结构体中可以反序列化的字段
+type field struct {
+	name   string
+	index  []int
+	typ    reflect.Type
+	tagged bool // 字段名来自标签
+	quoted bool // 标签中有string选项并且是基本类型，值是字符串形式的JSON
+}
+
+// structFields 按照encoding/json的规则获取结构体的字段，包括匿名结构体字段中提升的字段，
+// 同名的字段中层次浅的优先，层次相同时有标签的优先，仍然无法区分时都忽略
+func structFields(t reflect.Type) []field {
+	var fields []field
+	current, next := []field{}, []field{{typ: t}}
+	count, nextCount := map[reflect.Type]int{}, map[reflect.Type]int{}
+	visited := map[reflect.Type]bool{}
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+		for _, parent := range current {
+			if visited[parent.typ] {
+				continue
+			}
+			visited[parent.typ] = true
+
+			for i := 0; i < parent.typ.NumField(); i++ {
+				sf := parent.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(append([]int(nil), parent.index...), i)
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					// 同一层中多次出现的匿名结构体只展开一次，它的字段会重复记录，用于忽略无法区分的字段
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, field{index: index, typ: ft})
+					}
+					continue
+				}
+
+				f := field{name: name, index: index, typ: ft, tagged: name != "", quoted: quotable(ft, opts)}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				fields = append(fields, f)
+				if count[parent.typ] > 1 {
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		x := fields
+		if x[i].name != x[j].name {
+			return x[i].name < x[j].name
+		}
+		if len(x[i].index) != len(x[j].index) {
+			return len(x[i].index) < len(x[j].index)
+		}
+		if x[i].tagged != x[j].tagged {
+			return x[i].tagged
+		}
+		return lessIndex(x[i].index, x[j].index)
+	})
+
+	out := fields[:0]
+	for i := 0; i < len(fields); {
+		n := 1
+		for i+n < len(fields) && fields[i+n].name == fields[i].name {
+			n++
+		}
+		if n == 1 || len(fields[i].index) != len(fields[i+1].index) || fields[i].tagged != fields[i+1].tagged {
+			out = append(out, fields[i])
+		}
+		i += n
+	}
+	fields = out
+	sort.Slice(fields, func(i, j int) bool {
+		return lessIndex(fields[i].index, fields[j].index)
+	})
+	return fields
+}
+
+// quotable 和encoding/json一致，string选项只对布尔、数字和字符串类型生效
+func quotable(t reflect.Type, opts string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt != "string" {
+			continue
+		}
+		switch t.Kind() {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64,
+			reflect.String:
+			return true
+		}
+	}
+	return false
+}
+
+// lessIndex 按照字段在结构体中的顺序比较
+func lessIndex(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		}
+		if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// matchField 优先精确匹配字段名，其次忽略大小写匹配
+func matchField(fields []field, key string) (field, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// fieldByIndex 获取嵌套的字段，为空的匿名结构体指针会被初始化
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, errors.Errorf("embedded pointer to unexported struct %v", rv.Type().Elem())
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// escapeKey 转义键中gjson路径的特殊字符
+func escapeKey(key string) string {
+	b := &strings.Builder{}
+	for _, c := range key {
+		switch c {
+		case '.', '*', '?', '|', '#', '@', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,311 @@
+package encoding
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Breeze0806/go/time2"
+)
+
+type testServer struct {
+	Host    string            `json:"host"`
+	Port    int               `json:"port"`
+	Timeout time2.Duration    `json:"timeout"`
+	Retry   *time2.Duration   `json:"retry"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels"`
+	Weight  int64             `json:"weight,string"`
+	Ignored string            `json:"-"`
+	testAuth
+}
+
+type testAuth struct {
+	User string `json:"user"`
+}
+
+type testConfig struct {
+	Name    string
+	Servers []testServer         `json:"servers"`
+	Limits  map[int]float64      `json:"limits"`
+	Extra   interface{}          `json:"extra"`
+	Points  [2]int               `json:"points"`
+	Meta    map[string]*testMeta `json:"meta"`
+}
+
+type testMeta struct {
+	Version int `json:"version"`
+}
+
+var decodeJSON = `{
+	"name": "demo",
+	"servers": [{
+		"host": "a",
+		"port": 80,
+		"timeout": "1m30s",
+		"retry": "1s",
+		"tags": ["x", "y"],
+		"labels": {"zone": "cn"},
+		"weight": "3",
+		"Ignored": "ignored",
+		"user": "root"
+	}],
+	"limits": {"1": 0.5},
+	"extra": {"a": [1]},
+	"points": [1, 2, 3],
+	"meta": {"v.1": {"version": 1}, "v2": null}
+}`
+
+var invalidJSON = `{
+	"servers": [
+		{"host": "a", "port": 80},
+		{"host": "b", "port": "8080", "timeout": "1x"}
+	],
+	"meta": {"v.1": {"version": "1"}}
+}`
+
+func TestJSON_Decode(t *testing.T) {
+	j, err := NewJSONFromString(decodeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry := time2.NewDuration(time.Second)
+	first := testServer{
+		Host:     "a",
+		Port:     80,
+		Timeout:  time2.NewDuration(90 * time.Second),
+		Retry:    &retry,
+		Tags:     []string{"x", "y"},
+		Labels:   map[string]string{"zone": "cn"},
+		Weight:   3,
+		testAuth: testAuth{User: "root"},
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		v       interface{}
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name: "struct",
+			path: "servers.0",
+			v:    &testServer{},
+			want: &first,
+		},
+		{
+			name: "slice",
+			path: "servers.0.tags",
+			v:    &[]string{},
+			want: &[]string{"x", "y"},
+		},
+		{
+			name: "map",
+			path: "servers.0.labels",
+			v:    &map[string]string{},
+			want: &map[string]string{"zone": "cn"},
+		},
+		{
+			name: "duration",
+			path: "servers.0.timeout",
+			v:    &time2.Duration{},
+			want: &first.Timeout,
+		},
+		{
+			name: "root",
+			path: "",
+			v:    &struct{ Name string }{},
+			want: &struct{ Name string }{Name: "demo"},
+		},
+		{
+			name: "document",
+			path: "",
+			v:    &testConfig{},
+			want: &testConfig{
+				Name:    "demo",
+				Servers: []testServer{first},
+				Limits:  map[int]float64{1: 0.5},
+				Extra:   map[string]interface{}{"a": []interface{}{float64(1)}},
+				Points:  [2]int{1, 2},
+				Meta:    map[string]*testMeta{"v.1": {Version: 1}, "v2": nil},
+			},
+		},
+		{
+			name:    "notExist",
+			path:    "servers.3",
+			v:       &testServer{},
+			wantErr: "path(servers.3) does not exist",
+		},
+		{
+			name:    "notPointer",
+			path:    "servers.0",
+			v:       testServer{},
+			wantErr: "is not non-nil pointer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := j.Decode(tt.path, tt.v)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.v, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", tt.v, tt.want)
+			}
+		})
+	}
+}
+
+type testNameA struct {
+	Name string
+	Code string `json:"code"`
+	Rate int    `json:"rate,string"`
+}
+
+type testNameB struct {
+	Name  string
+	Code  string
+	Count int `json:"count,omitempty,string"`
+}
+
+type testNameC struct {
+	Name string `json:"Name"`
+}
+
+type testDominance struct {
+	testNameA
+	testNameB
+	testNameC
+	Tags []string `json:"tags,string"`
+}
+
+type testDuplicate struct {
+	testNameA
+	Inner struct{ testNameA }
+}
+
+func TestJSON_Decode_fields(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		v    func() interface{}
+	}{
+		{
+			name: "dominance",
+			data: `{"Name": "a", "code": "c", "Code": "C", "rate": "1", "count": "2", "tags": ["x"]}`,
+			v:    func() interface{} { return &testDominance{} },
+		},
+		{
+			name: "ambiguous",
+			data: `{"Name": "a", "Code": "c"}`,
+			v: func() interface{} {
+				return &struct {
+					testNameA
+					testNameB
+				}{}
+			},
+		},
+		{
+			name: "shallow",
+			data: `{"Name": "a", "Inner": {"Name": "b"}}`,
+			v:    func() interface{} { return &testDuplicate{} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := NewJSONFromString(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, want := tt.v(), tt.v()
+			if err = json.Unmarshal([]byte(tt.data), want); err != nil {
+				t.Fatal(err)
+			}
+			if err = j.Decode("", got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestJSON_Decode_path(t *testing.T) {
+	j, err := NewJSONFromString(invalidJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		v       interface{}
+		wantErr string
+	}{
+		{
+			name:    "typeMismatch",
+			path:    "",
+			v:       &testConfig{},
+			wantErr: "path(servers.1.port) is not int",
+		},
+		{
+			name:    "unmarshaler",
+			path:    "servers.1",
+			v:       &struct{ Timeout time2.Duration }{},
+			wantErr: "path(servers.1.timeout) unmarshal fail",
+		},
+		{
+			name:    "escapedKey",
+			path:    "meta",
+			v:       &map[string]testMeta{},
+			wantErr: `path(meta.v\.1.version) is not int`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := j.Decode(tt.path, tt.v); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSON_Encode(t *testing.T) {
+	j, err := NewJSONFromString(`{"a":{"b":1}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = j.Encode("a.c", struct {
+		Timeout time2.Duration `json:"timeout"`
+		Tags    []string       `json:"tags"`
+	}{time2.NewDuration(time.Second), []string{"x"}}); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := `{"a":{"b":1,"c":{"timeout":"1s","tags":["x"]}}}`; j.String() != want {
+		t.Errorf("Encode() = %v, want %v", j.String(), want)
+	}
+
+	var got struct {
+		Timeout time2.Duration `json:"timeout"`
+	}
+	if err = j.Decode("a.c", &got); err != nil || got.Timeout.Duration != time.Second {
+		t.Errorf("Decode() = %v, error = %v", got, err)
+	}
+
+	if err = j.Encode("", map[string]int{"x": 1}); err != nil || j.String() != `{"x":1}` {
+		t.Errorf("Encode() = %v, error = %v", j.String(), err)
+	}
+	if err = j.Encode("y", func() {}); err == nil || !strings.Contains(err.Error(), "path(y) marshal fail") {
+		t.Errorf("Encode() error = %v", err)
+	}
+}
@@ -0,0 +1,559 @@
+package mybaits
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+)
+
+// AuditContext ${}在语句中所处的位置
+type AuditContext string
+
+// ${}所处的位置
+const (
+	AuditLiteral    AuditContext = "literal"    // 字符串字面量中，如'${name}'
+	AuditLike       AuditContext = "like"       // LIKE的模式中，如LIKE '%${name}%'
+	AuditInList     AuditContext = "in_list"    // IN列表中，如IN (${ids})
+	AuditValue      AuditContext = "value"      // 作为值使用，如price > ${price}
+	AuditLimit      AuditContext = "limit"      // LIMIT或OFFSET中
+	AuditIdentifier AuditContext = "identifier" // 列名等标识符
+	AuditTable      AuditContext = "table"      // 表名
+	AuditOrderBy    AuditContext = "order_by"   // ORDER BY中
+	AuditFragment   AuditContext = "fragment"   // 语句无法解析，${}可能是任意的SQL片段
+)
+
+// AuditRisk ${}的风险等级
+type AuditRisk string
+
+// 风险等级
+const (
+	AuditHigh   AuditRisk = "high"   // 可以直接传入任意SQL，应该改写为#{}
+	AuditMedium AuditRisk = "medium" // 标识符无法使用#{}，需要使用白名单校验
+)
+
+// AuditReport ${}的审计报告
+type AuditReport []AuditFinding
+
+// AuditFinding 语句中的一处${}
+type AuditFinding struct {
+	Namespace  string       `json:"namespace"`
+	ID         string       `json:"id"`
+	Param      string       `json:"param"` // ${}的全文
+	Context    AuditContext `json:"context"`
+	Risk       AuditRisk    `json:"risk"`
+	Suggestion string       `json:"suggestion"`
+	Rewrite    string       `json:"rewrite,omitempty"` // 可以安全地改写为#{}时的写法
+	File       string       `json:"file"`
+	Line       int          `json:"line"` // ${}在语句中第一次出现的行号
+}
+
+// auditMarker ${}在渲染后的语句中的替换文本的前缀，替换文本形如mybaits_audit_0_，是合法的标识符
+const auditMarker = "mybaits_audit_"
+
+// Audit 按文档顺序找出映射文件中所有语句(不包括sql片段)的${}，根据解析后的语句判断${}所处的位置并评估风险，
+// include的片段中由property定义的${}是静态的属性，不会被报告，片段外同名的${}仍然会被报告
+func (m *Mapper) Audit() (report AuditReport, err error) {
+	for _, id := range m.ids {
+		child := m.root[id]
+		if child.Tag == "sql" {
+			continue
+		}
+
+		var info *StatementInfo
+		if info, err = m.Statement(id); err != nil {
+			return nil, err
+		}
+		var names []string
+		markers := make(map[string]string)
+		tokens := make(map[string][]string)
+		raw := make(map[string]string)
+		for _, p := range info.Params {
+			name := strings.TrimSpace(p.Name)
+			if !strings.HasPrefix(p.FullName, "$") {
+				continue
+			}
+			if _, ok := markers[name]; !ok {
+				markers[name] = auditMarker + strconv.Itoa(len(names)) + "_"
+				names = append(names, name)
+			}
+			raw[p.FullName] = markers[name]
+			tokens[name] = append(tokens[name], p.FullName)
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		cm := m.newChildMapper(child)
+		cm.mocker = &mocker{raw: raw}
+		if m.mocker != nil {
+			cm.mocker.mode, cm.mocker.samples = m.mocker.mode, m.mocker.samples
+		}
+		sql, serr := cm.getStatement()
+		if serr != nil {
+			sql = cm.getRawStatement()
+		}
+		contexts := auditContexts(sql, markers)
+
+		for _, name := range names {
+			for _, c := range contexts[markers[name]] {
+				finding := auditFinding(name, c)
+				finding.Namespace = m.namespace
+				finding.ID = id
+				finding.File = m.path
+				finding.Line = paramLine(child, m.Line(id), tokens[name])
+				report = append(report, finding)
+			}
+		}
+	}
+	return
+}
+
+// paramLine 获取语句e中第一个tokens中的${}所在的行号，start为语句所在的行号，
+// ${}只在sql片段中时返回start
+func paramLine(e *etree.Element, start int, tokens []string) int {
+	line := start
+	var walk func(e *etree.Element) bool
+	walk = func(e *etree.Element) bool {
+		for _, token := range e.Child {
+			switch t := token.(type) {
+			case *etree.CharData:
+				i := -1
+				for _, tok := range tokens {
+					if j := strings.Index(t.Data, tok); j >= 0 && (i < 0 || j < i) {
+						i = j
+					}
+				}
+				if i >= 0 {
+					line += strings.Count(t.Data[:i], "\n")
+					return true
+				}
+				line += strings.Count(t.Data, "\n")
+			case *etree.Comment:
+				line += strings.Count(t.Data, "\n")
+			case *etree.Element:
+				if walk(t) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if walk(e) {
+		return line
+	}
+	return start
+}
+
+// auditSite ${}在语句中的一个位置，literal为${}所在的字符串字面量
+type auditSite struct {
+	context AuditContext
+	literal string
+}
+
+// auditContexts 获取每个替换文本在sql中的位置，同一位置只记录一次
+func auditContexts(sql string, markers map[string]string) map[string][]auditSite {
+	contexts := make(map[string][]auditSite)
+	add := func(node string, site auditSite) {
+		for _, marker := range markers {
+			if !strings.Contains(node, marker) {
+				continue
+			}
+			seen := false
+			for _, s := range contexts[marker] {
+				seen = seen || s == site
+			}
+			if !seen {
+				contexts[marker] = append(contexts[marker], site)
+			}
+		}
+	}
+
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		// 无法解析时(如choose的所有分支都被渲染)根据前面的关键字判断
+		for _, marker := range markers {
+			for i := strings.Index(sql, marker); i >= 0; {
+				add(marker, textSite(sql, i))
+				j := strings.Index(sql[i+1:], marker)
+				if j < 0 {
+					break
+				}
+				i += j + 1
+			}
+		}
+		return contexts
+	}
+
+	// value 在值的位置上的标识符是被${}替换的值
+	value := func(expr sqlparser.SQLNode) bool {
+		if col, ok := expr.(*sqlparser.ColName); ok {
+			add(sqlparser.String(col), auditSite{context: AuditValue})
+			return true
+		}
+		return false
+	}
+	var visit sqlparser.Visit
+	visit = func(node sqlparser.SQLNode) (bool, error) {
+		switch n := node.(type) {
+		case sqlparser.OrderBy:
+			add(sqlparser.String(n), auditSite{context: AuditOrderBy})
+			return false, nil
+		case *sqlparser.Limit:
+			add(sqlparser.String(n), auditSite{context: AuditLimit})
+			return false, nil
+		case *sqlparser.ComparisonExpr:
+			switch n.Operator {
+			case sqlparser.LikeStr, sqlparser.NotLikeStr:
+				if v, ok := n.Right.(*sqlparser.SQLVal); ok && v.Type == sqlparser.StrVal {
+					add(string(v.Val), auditSite{context: AuditLike, literal: string(v.Val)})
+				} else if !value(n.Right) {
+					sqlparser.Walk(visit, n.Right)
+				}
+			case sqlparser.InStr, sqlparser.NotInStr:
+				add(sqlparser.String(n.Right), auditSite{context: AuditInList})
+			default:
+				if !value(n.Right) {
+					sqlparser.Walk(visit, n.Right)
+				}
+			}
+			sqlparser.Walk(visit, n.Left, n.Escape)
+			return false, nil
+		case *sqlparser.RangeCond:
+			for _, expr := range []sqlparser.Expr{n.From, n.To} {
+				if !value(expr) {
+					sqlparser.Walk(visit, expr)
+				}
+			}
+			sqlparser.Walk(visit, n.Left)
+			return false, nil
+		case *sqlparser.UpdateExpr:
+			if !value(n.Expr) {
+				sqlparser.Walk(visit, n.Expr)
+			}
+			sqlparser.Walk(visit, n.Name)
+			return false, nil
+		case sqlparser.Values:
+			for _, tuple := range n {
+				for _, expr := range tuple {
+					if !value(expr) {
+						sqlparser.Walk(visit, expr)
+					}
+				}
+			}
+			return false, nil
+		case *sqlparser.SQLVal:
+			if n.Type == sqlparser.StrVal {
+				add(string(n.Val), auditSite{context: AuditLiteral, literal: string(n.Val)})
+			}
+			return false, nil
+		case sqlparser.TableName:
+			add(sqlparser.String(n), auditSite{context: AuditTable})
+			return false, nil
+		case *sqlparser.ColName:
+			add(sqlparser.String(n), auditSite{context: AuditIdentifier})
+			return false, nil
+		}
+		return true, nil
+	}
+	sqlparser.Walk(visit, stmt)
+	return contexts
+}
+
+// textSite 根据sql中下标i前面的文本判断所处的位置
+func textSite(sql string, i int) auditSite {
+	if literal, like, ok := quotedAt(sql, i); ok {
+		if like {
+			return auditSite{context: AuditLike, literal: literal}
+		}
+		return auditSite{context: AuditLiteral, literal: literal}
+	}
+
+	var words []string
+	for _, t := range sqlTokens(sql[:i]) {
+		words = append(words, strings.ToUpper(t.text))
+	}
+	n := len(words)
+	switch {
+	case n >= 2 && words[n-1] == "(" && words[n-2] == "IN":
+		return auditSite{context: AuditInList}
+	case n >= 1 && (words[n-1] == "FROM" || words[n-1] == "JOIN" || words[n-1] == "INTO" || words[n-1] == "UPDATE"):
+		return auditSite{context: AuditTable}
+	}
+
+	// 最近的子句关键字，括号内的不计算在内
+	depth := 0
+clauses:
+	for j := n - 1; j >= 0; j-- {
+		switch words[j] {
+		case ")":
+			depth++
+		case "(":
+			depth--
+		case "BY":
+			if depth <= 0 && j > 0 && words[j-1] == "ORDER" {
+				return auditSite{context: AuditOrderBy}
+			}
+		case "LIMIT", "OFFSET":
+			if depth <= 0 {
+				return auditSite{context: AuditLimit}
+			}
+		}
+		if depth <= 0 && clauseKeywords[words[j]] {
+			break clauses
+		}
+	}
+	return auditSite{context: AuditFragment}
+}
+
+// quotedAt 判断sql的下标i是否在字符串字面量中，返回字面量的内容以及字面量前面是否是LIKE
+func quotedAt(sql string, i int) (literal string, like bool, ok bool) {
+	for j := 0; j < len(sql) && j <= i; j++ {
+		if sql[j] != '\'' {
+			continue
+		}
+		end := closingQuote(sql, j)
+		if i < end {
+			fields := strings.Fields(sql[:j])
+			like = len(fields) > 0 && strings.EqualFold(fields[len(fields)-1], "like")
+			return strings.ReplaceAll(sql[j+1:end-1], "''", "'"), like, true
+		}
+		j = end - 1
+	}
+	return "", false, false
+}
+
+// auditFinding 根据${name}所处的位置评估风险并给出改写建议
+func auditFinding(name string, site auditSite) AuditFinding {
+	param := "${" + name + "}"
+	f := AuditFinding{Param: param, Context: site.context, Risk: AuditHigh}
+	switch site.context {
+	case AuditLiteral, AuditLike:
+		if site.context == AuditLiteral && isMarker(site.literal) {
+			f.Suggestion = fmt.Sprintf("%v is quoted as a string literal; bind it with #{%v} instead", param, name)
+			f.Rewrite = "#{" + name + "}"
+			break
+		}
+		bindName := strings.ReplaceAll(name, ".", "_") + "Value"
+		if site.context == AuditLike {
+			bindName = strings.ReplaceAll(name, ".", "_") + "Pattern"
+		}
+		f.Suggestion = fmt.Sprintf("%v is concatenated into a string literal; build the string with <bind> and bind it with #{%v}", param, bindName)
+		f.Rewrite = fmt.Sprintf(`<bind name="%v" value="%v"/> ... #{%v}`, bindName, bindValue(site.literal, name), bindName)
+	case AuditInList:
+		f.Suggestion = fmt.Sprintf("%v expands into an IN list; iterate the collection with <foreach> and bind each item with #{}", param)
+		f.Rewrite = fmt.Sprintf(`<foreach collection="%v" item="item" open="(" separator="," close=")">#{item}</foreach>`, name)
+	case AuditValue, AuditLimit:
+		f.Suggestion = fmt.Sprintf("%v is used as a value; bind it with #{%v} instead", param, name)
+		f.Rewrite = "#{" + name + "}"
+	case AuditIdentifier, AuditTable, AuditOrderBy:
+		f.Risk = AuditMedium
+		f.Suggestion = fmt.Sprintf("%v is used as %v and cannot be bound with #{}; validate it against an allow-list, e.g. with <choose>", param, auditContextNames[site.context])
+	default:
+		f.Suggestion = fmt.Sprintf("%v inserts raw sql; build the statement with dynamic tags and bind values with #{}", param)
+	}
+	return f
+}
+
+var auditContextNames = map[AuditContext]string{
+	AuditIdentifier: "an identifier",
+	AuditTable:      "a table name",
+	AuditOrderBy:    "an ORDER BY expression",
+}
+
+// bindValue 将包含替换文本的字符串字面量转化为bind的OGNL表达式，如'%' + name + '%'
+func bindValue(literal, name string) string {
+	var terms []string
+	for i, part := range strings.Split(literal, auditMarker) {
+		if i > 0 {
+			terms = append(terms, name)
+			part = part[strings.Index(part, "_")+1:]
+		}
+		if part != "" {
+			terms = append(terms, "'"+strings.ReplaceAll(part, "'", `\'`)+"'")
+		}
+	}
+	return strings.Join(terms, " + ")
+}
+
+// isMarker 判断s是否是完整的替换文本
+func isMarker(s string) bool {
+	index := strings.TrimSuffix(strings.TrimPrefix(s, auditMarker), "_")
+	_, err := strconv.Atoi(index)
+	return err == nil && s == auditMarker+index+"_"
+}
+
+// WriteText 以文本格式输出审计报告，每处${}一行
+func (r AuditReport) WriteText(w io.Writer) error {
+	b := &strings.Builder{}
+	for _, f := range r {
+		fmt.Fprintf(b, "%v:%v: [%v] %v.%v %v (%v): %v\n", f.File, f.Line, f.Risk, f.Namespace, f.ID, f.Param, f.Context, f.Suggestion)
+		if f.Rewrite != "" {
+			fmt.Fprintf(b, "\trewrite: %v\n", f.Rewrite)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON 以JSON格式输出审计报告
+func (r AuditReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if r == nil {
+		r = AuditReport{}
+	}
+	return encoder.Encode(r)
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// auditRules SARIF中的规则，每个位置一条
+var auditRules = []struct {
+	context     AuditContext
+	description string
+}{
+	{AuditLiteral, "${} inside a string literal"},
+	{AuditLike, "${} inside a LIKE pattern"},
+	{AuditInList, "${} expanded into an IN list"},
+	{AuditValue, "${} used as a value"},
+	{AuditLimit, "${} used in LIMIT or OFFSET"},
+	{AuditIdentifier, "${} used as an identifier"},
+	{AuditTable, "${} used as a table name"},
+	{AuditOrderBy, "${} used in ORDER BY"},
+	{AuditFragment, "${} inserting raw sql"},
+}
+
+func auditRuleID(c AuditContext) string {
+	return "mybaits/dollar-" + strings.ReplaceAll(string(c), "_", "-")
+}
+
+// WriteSARIF 以SARIF 2.1.0格式输出审计报告，high对应error，medium对应warning
+func (r AuditReport) WriteSARIF(w io.Writer) error {
+	driver := sarifDriver{
+		Name:           "mybaits-audit",
+		InformationURI: "https://github.com/Breeze0806/go",
+	}
+	for _, rule := range auditRules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               auditRuleID(rule.context),
+			ShortDescription: sarifMessage{Text: rule.description},
+		})
+	}
+
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: []sarifResult{}}
+	for _, f := range r {
+		level := "error"
+		if f.Risk == AuditMedium {
+			level = "warning"
+		}
+		properties := map[string]interface{}{
+			"param":   f.Param,
+			"context": f.Context,
+			"risk":    f.Risk,
+		}
+		if f.Rewrite != "" {
+			properties["rewrite"] = f.Rewrite
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  auditRuleID(f.Context),
+			Level:   level,
+			Message: sarifMessage{Text: f.Suggestion},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.File},
+					Region:           sarifRegion{StartLine: f.Line},
+				},
+				LogicalLocations: []sarifLogicalLocation{{
+					FullyQualifiedName: f.Namespace + "." + f.ID,
+					Kind:               "function",
+				}},
+			}},
+			Properties: properties,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// Write 以format(text, json, sarif)格式输出审计报告
+func (r AuditReport) Write(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "text":
+		return r.WriteText(w)
+	case "json":
+		return r.WriteJSON(w)
+	case "sarif":
+		return r.WriteSARIF(w)
+	}
+	return fmt.Errorf("audit format(%v) is not supported", format)
+}
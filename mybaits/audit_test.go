@@ -0,0 +1,145 @@
+package mybaits
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMapper_Audit(t *testing.T) {
+	m, err := NewMapper("testdata/audit.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := m.Audit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type finding struct {
+		id      string
+		param   string
+		context AuditContext
+		risk    AuditRisk
+		rewrite string
+		line    int
+	}
+	want := []finding{
+		{"selectUsers", "${schema}", AuditTable, AuditMedium, "", 9},
+		{"selectUsers", "${name}", AuditLiteral, AuditHigh, "#{name}", 10},
+		{"selectUsers", "${keyword}", AuditLike, AuditHigh, `<bind name="keywordPattern" value="'%' + keyword + '%'"/> ... #{keywordPattern}`, 11},
+		{"selectUsers", "${ids}", AuditInList, AuditHigh, `<foreach collection="ids" item="item" open="(" separator="," close=")">#{item}</foreach>`, 12},
+		{"selectUsers", "${minAge}", AuditValue, AuditHigh, "#{minAge}", 13},
+		{"selectUsers", "${orderBy}", AuditOrderBy, AuditMedium, "", 14},
+		{"selectUsers", "${limit}", AuditLimit, AuditHigh, "#{limit}", 15},
+		{"selectByID", "${column}", AuditIdentifier, AuditMedium, "", 18},
+		{"updateName", "${name}", AuditValue, AuditHigh, "#{name}", 21},
+		{"deleteWhere", "${where}", AuditFragment, AuditHigh, "", 24},
+		{"selectExtra", "${extra}", AuditIdentifier, AuditMedium, "", 27},
+	}
+	var got []finding
+	for _, f := range report {
+		got = append(got, finding{f.ID, f.Param, f.Context, f.Risk, f.Rewrite, f.Line})
+		if f.File != "testdata/audit.xml" || f.Suggestion == "" {
+			t.Errorf("finding %+v has wrong file or no suggestion", f)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Audit() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_auditContexts(t *testing.T) {
+	markers := map[string]string{"a": auditMarker + "0_", "b": auditMarker + "10_"}
+	tests := []struct {
+		name string
+		sql  string
+		want []auditSite
+	}{
+		{
+			name: "between",
+			sql:  "select id from t where age between mybaits_audit_0_ and 10",
+			want: []auditSite{{context: AuditValue}},
+		},
+		{
+			name: "insert",
+			sql:  "insert into t (id, name) values (?, mybaits_audit_0_)",
+			want: []auditSite{{context: AuditValue}},
+		},
+		{
+			name: "literalAndIdentifier",
+			sql:  "select mybaits_audit_0_ from t where name = 'x_mybaits_audit_0_'",
+			want: []auditSite{{context: AuditIdentifier}, {context: AuditLiteral, literal: "x_mybaits_audit_0_"}},
+		},
+		{
+			name: "unparsedLike",
+			sql:  "select id from t where name like 'mybaits_audit_0_%' mybaits",
+			want: []auditSite{{context: AuditLike, literal: "mybaits_audit_0_%"}},
+		},
+		{
+			name: "unparsedOrderBy",
+			sql:  "select id from t order by id order by mybaits_audit_0_, lower(mybaits_audit_10_)",
+			want: []auditSite{{context: AuditOrderBy}},
+		},
+		{
+			name: "unparsedInListAndTable",
+			sql:  "select id from mybaits_audit_0_ where id in (mybaits_audit_0_) mybaits",
+			want: []auditSite{{context: AuditTable}, {context: AuditInList}},
+		},
+		{
+			name: "unparsedFragment",
+			sql:  "select id from t mybaits_audit_0_ mybaits",
+			want: []auditSite{{context: AuditFragment}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditContexts(tt.sql, markers)[markers["a"]]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditContexts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_auditFinding_bind(t *testing.T) {
+	f := auditFinding("user.name", auditSite{context: AuditLiteral, literal: "x_mybaits_audit_0_'s"})
+	want := `<bind name="user_nameValue" value="'x_' + user.name + '\'s'"/> ... #{user_nameValue}`
+	if f.Rewrite != want {
+		t.Errorf("Rewrite = %v, want %v", f.Rewrite, want)
+	}
+}
+
+func TestAuditReport_WriteSARIF(t *testing.T) {
+	report := AuditReport{
+		{Namespace: "Audit", ID: "a", Param: "${x}", Context: AuditLiteral, Risk: AuditHigh, Suggestion: "s", Rewrite: "#{x}", File: "a.xml", Line: 3},
+		{Namespace: "Audit", ID: "b", Param: "${y}", Context: AuditOrderBy, Risk: AuditMedium, Suggestion: "t", File: "a.xml", Line: 9},
+	}
+	buf := &bytes.Buffer{}
+	if err := report.Write(buf, "sarif"); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Tool.Driver.Rules) != len(auditRules) {
+		t.Fatalf("WriteSARIF() = %v", buf.String())
+	}
+	results := log.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("results = %v, want 2", len(results))
+	}
+	if r := results[0]; r.RuleID != "mybaits/dollar-literal" || r.Level != "error" || r.Properties["rewrite"] != "#{x}" ||
+		r.Locations[0].PhysicalLocation.Region.StartLine != 3 || r.Locations[0].LogicalLocations[0].FullyQualifiedName != "Audit.a" {
+		t.Errorf("results[0] = %+v", r)
+	}
+	if r := results[1]; r.RuleID != "mybaits/dollar-order-by" || r.Level != "warning" {
+		t.Errorf("results[1] = %+v", r)
+	}
+
+	if err := report.Write(buf, "xml"); err == nil {
+		t.Errorf("Write() error = nil, want unsupported format")
+	}
+}
@@ -0,0 +1,164 @@
+package mybaits
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Breeze0806/go/log"
+)
+
+// NamingStrategy 自动映射时列名和字段名的匹配方式
+type NamingStrategy int
+
+// 命名策略
+const (
+	NamingCaseInsensitive       NamingStrategy = iota // 默认值，和mybatis的默认行为一致，列名和字段名只忽略大小写
+	NamingUnderscoreToCamelCase                       // 和mybatis的mapUnderscoreToCamelCase一致，user_name对应UserName
+)
+
+// UnknownColumnBehavior 自动映射时遇到没有对应字段的列的处理方式，和mybatis的autoMappingUnknownColumnBehavior一致
+type UnknownColumnBehavior int
+
+// 未知列的处理方式
+const (
+	UnknownColumnIgnore UnknownColumnBehavior = iota // 忽略
+	UnknownColumnWarn                                // 通过log.GetLogger()打印告警日志，每个类型的每个列只打印一次
+	UnknownColumnFail                                // 映射失败
+)
+
+// WithNamingStrategy 设置自动映射的命名策略，默认为NamingCaseInsensitive，
+// 通过Registry创建的会话按照配置的mapUnderscoreToCamelCase设置
+func WithNamingStrategy(strategy NamingStrategy) SessionOption {
+	return func(s *Session) {
+		s.naming = strategy
+	}
+}
+
+// WithUnknownColumnBehavior 设置自动映射时遇到没有对应字段的列的处理方式，默认为UnknownColumnIgnore
+func WithUnknownColumnBehavior(behavior UnknownColumnBehavior) SessionOption {
+	return func(s *Session) {
+		s.unknownColumn = behavior
+	}
+}
+
+// fieldPlan 结构体类型自动映射的字段，db标签的列名优先于字段名
+type fieldPlan struct {
+	naming NamingStrategy
+	tags   map[string][]int // 大写的db标签到字段下标的映射
+	names  map[string][]int // 按照命名策略转换后的字段名到字段下标的映射
+}
+
+type fieldPlanKey struct {
+	typ    reflect.Type
+	naming NamingStrategy
+}
+
+var fieldPlans sync.Map
+
+// planOf 获取结构体类型typ的字段映射计划，结果会被缓存
+func planOf(typ reflect.Type, naming NamingStrategy) *fieldPlan {
+	key := fieldPlanKey{typ: typ, naming: naming}
+	if p, ok := fieldPlans.Load(key); ok {
+		return p.(*fieldPlan)
+	}
+
+	p := &fieldPlan{
+		naming: naming,
+		tags:   make(map[string][]int),
+		names:  make(map[string][]int),
+	}
+	p.add(typ)
+	actual, _ := fieldPlans.LoadOrStore(key, p)
+	return actual.(*fieldPlan)
+}
+
+// add 按照广度优先添加字段，浅层的字段优先于嵌入结构体中的同名字段，同一层的同名字段使用先定义的
+func (p *fieldPlan) add(typ reflect.Type) {
+	type level struct {
+		typ   reflect.Type
+		index []int
+	}
+	queue := []level{{typ: typ}}
+	visited := make(map[reflect.Type]bool)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current.typ] {
+			continue
+		}
+		visited[current.typ] = true
+
+		for i := 0; i < current.typ.NumField(); i++ {
+			f := current.typ.Field(i)
+			tag, hasTag := f.Tag.Lookup("db")
+			if tag == "-" {
+				continue
+			}
+			index := append(append([]int(nil), current.index...), i)
+
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && !hasTag && ft.Kind() == reflect.Struct {
+				// 未导出的嵌入结构体指针无法分配
+				if f.IsExported() || f.Type.Kind() != reflect.Ptr {
+					queue = append(queue, level{typ: ft, index: index})
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+
+			if hasTag && tag != "" {
+				if _, ok := p.tags[strings.ToUpper(tag)]; !ok {
+					p.tags[strings.ToUpper(tag)] = index
+				}
+			} else if _, ok := p.names[p.normalize(f.Name)]; !ok {
+				p.names[p.normalize(f.Name)] = index
+			}
+		}
+	}
+}
+
+func (p *fieldPlan) normalize(name string) string {
+	if p.naming == NamingUnderscoreToCamelCase {
+		name = strings.ReplaceAll(name, "_", "")
+	}
+	return strings.ToUpper(name)
+}
+
+// lookup 获取列column对应字段的下标
+func (p *fieldPlan) lookup(column string) ([]int, bool) {
+	if index, ok := p.tags[strings.ToUpper(column)]; ok {
+		return index, true
+	}
+	if name := p.normalize(column); name != "" {
+		index, ok := p.names[name]
+		return index, ok
+	}
+	return nil, false
+}
+
+// autoMappedField 获取列column自动映射的字段，没有对应的字段时按照会话的设置处理
+func (rm *rowMapper) autoMappedField(target reflect.Value, column string) (reflect.Value, error) {
+	if index, ok := planOf(target.Type(), rm.session.naming).lookup(column); ok {
+		if field, ok := fieldByIndex(target, index, true); ok {
+			return field, nil
+		}
+		return reflect.Value{}, fmt.Errorf("column(%v) is mapped to a field of nil embedded pointer in %v", column, target.Type())
+	}
+	switch rm.session.unknownColumn {
+	case UnknownColumnWarn:
+		key := target.Type().String() + "." + strings.ToUpper(column)
+		if _, warned := rm.session.warned.LoadOrStore(key, true); !warned {
+			log.GetLogger().Warnf("unknown column(%v) is not mapped to %v", column, target.Type())
+		}
+	case UnknownColumnFail:
+		return reflect.Value{}, fmt.Errorf("unknown column(%v) is not mapped to %v", column, target.Type())
+	}
+	return reflect.Value{}, nil
+}
@@ -0,0 +1,173 @@
+package mybaits
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Breeze0806/go/log"
+)
+
+type testAudit struct {
+	CreatedBy string
+	UpdatedBy string `db:"modifier"`
+}
+
+type Profile struct {
+	Nickname string
+	ID       int64 // 被外层的ID覆盖
+}
+
+type testAccount struct {
+	ID       int64
+	UserName string
+	Email    string `db:"mail_address"`
+	Password string `db:"-"`
+	testAudit
+	*Profile
+	internal string
+}
+
+func Test_planOf(t *testing.T) {
+	typ := reflect.TypeOf(testAccount{})
+	tests := []struct {
+		naming NamingStrategy
+		column string
+		want   []int
+		wantOk bool
+	}{
+		{NamingUnderscoreToCamelCase, "id", []int{0}, true},
+		{NamingUnderscoreToCamelCase, "user_name", []int{1}, true},
+		{NamingUnderscoreToCamelCase, "USERNAME", []int{1}, true},
+		{NamingCaseInsensitive, "user_name", nil, false},
+		{NamingCaseInsensitive, "username", []int{1}, true},
+		{NamingUnderscoreToCamelCase, "mail_address", []int{2}, true},
+		{NamingUnderscoreToCamelCase, "email", nil, false},
+		{NamingUnderscoreToCamelCase, "password", nil, false},
+		{NamingUnderscoreToCamelCase, "created_by", []int{4, 0}, true},
+		{NamingUnderscoreToCamelCase, "modifier", []int{4, 1}, true},
+		{NamingUnderscoreToCamelCase, "updated_by", nil, false},
+		{NamingUnderscoreToCamelCase, "nickname", []int{5, 0}, true},
+		{NamingUnderscoreToCamelCase, "internal", nil, false},
+		{NamingUnderscoreToCamelCase, "_", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			got, ok := planOf(typ, tt.naming).lookup(tt.column)
+			if ok != tt.wantOk || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lookup() = %v %v, want %v %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+	if planOf(typ, NamingCaseInsensitive) != planOf(typ, NamingCaseInsensitive) {
+		t.Errorf("plan is not cached")
+	}
+}
+
+func TestSession_ScanRows(t *testing.T) {
+	m, err := NewMapper("testdata/session.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := []string{"id", "user_name", "mail_address", "created_by", "modifier", "nickname", "last_login"}
+	d := &fakeDriver{query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		return &fakeRows{
+			columns: columns,
+			values:  [][]driver.Value{{int64(1), "alice", "a@b.c", "root", []byte("admin"), "al", nil}},
+		}, nil
+	}}
+	db := newFakeDB(d)
+	want := []testAccount{{
+		ID:        1,
+		UserName:  "alice",
+		Email:     "a@b.c",
+		testAudit: testAudit{CreatedBy: "root", UpdatedBy: "admin"},
+		Profile:   &Profile{Nickname: "al"},
+	}}
+
+	tests := []struct {
+		name     string
+		behavior UnknownColumnBehavior
+		wantErr  bool
+		wantLog  string
+	}{
+		{name: "ignore", behavior: UnknownColumnIgnore},
+		{name: "warn", behavior: UnknownColumnWarn, wantLog: "unknown column(last_login) is not mapped to mybaits.testAccount"},
+		{name: "fail", behavior: UnknownColumnFail, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			old := log.GetLogger()
+			log.SetLogger(log.NewDefaultLogger(buf, log.WarnLevel, ""))
+			defer log.SetLogger(old)
+
+			s := NewSession(db, []*Mapper{m}, WithNamingStrategy(NamingUnderscoreToCamelCase), WithUnknownColumnBehavior(tt.behavior))
+			for i := 0; i < 2; i++ {
+				rows, err := db.Query("select * from account")
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []testAccount
+				err = s.ScanRows(context.Background(), rows, "selectAccounts", &got)
+				rows.Close()
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ScanRows() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err == nil && !reflect.DeepEqual(got, want) {
+					t.Errorf("ScanRows() = %+v, want %+v", got, want)
+				}
+			}
+			if got := strings.Count(buf.String(), tt.wantLog); tt.wantLog != "" && got != 1 {
+				t.Errorf("warnings = %v, want 1 in %q", got, buf.String())
+			}
+			if tt.wantLog == "" && buf.Len() != 0 {
+				t.Errorf("unexpected log %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestSession_ScanRows_registeredType(t *testing.T) {
+	m, err := NewMapper("testdata/session.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDriver{query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		return &fakeRows{columns: []string{"id", "user_name"}, values: [][]driver.Value{{int64(2), "bob"}}}, nil
+	}}
+	s := NewSession(newFakeDB(d), []*Mapper{m}, WithNamingStrategy(NamingUnderscoreToCamelCase), WithType("Account", &testAccount{}))
+
+	var got []interface{}
+	if err := s.SelectList(context.Background(), "selectAccounts", nil, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{&testAccount{ID: 2, UserName: "bob"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SelectList() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewSession_defaultNaming(t *testing.T) {
+	m, err := NewMapper("testdata/session.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDriver{query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		return &fakeRows{
+			columns: []string{"ID", "USERNAME", "created_by"},
+			values:  [][]driver.Value{{int64(2), "bob", "root"}},
+		}, nil
+	}}
+	s := NewSession(newFakeDB(d), []*Mapper{m})
+
+	var got []testAccount
+	if err := s.SelectList(context.Background(), "selectAccounts", nil, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []testAccount{{ID: 2, UserName: "bob"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SelectList() = %+v, want %+v", got, want)
+	}
+}
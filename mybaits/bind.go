@@ -0,0 +1,252 @@
+package mybaits
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// PlaceholderStyle 绑定参数时#{}替换成的占位符风格
+type PlaceholderStyle int
+
+// 占位符风格
+const (
+	PlaceholderQuestion PlaceholderStyle = iota // ?，如mysql
+	PlaceholderDollar                           // $1, $2，如postgresql
+)
+
+// WithPlaceholder 设置绑定参数时使用的占位符风格，默认为?
+func WithPlaceholder(style PlaceholderStyle) MapperOption {
+	return func(m *Mapper) {
+		m.placeholder = style
+	}
+}
+
+// BoundSQL 绑定参数后可以直接执行的语句
+type BoundSQL struct {
+	ID     string
+	SQL    string
+	Args   []interface{}
+	Params []Param // 和Args一一对应的#{}参数
+}
+
+// Bind 使用参数param生成id对应语句可执行的SQL，和mybatis一致，
+// 会对if、when的test求值，展开foreach和include，#{}替换为占位符并记录参数值，${}直接替换为参数值。
+// param可以是map、结构体或者它们的指针，其他类型时语句中的任意参数名都表示param本身
+func (m *Mapper) Bind(id string, param interface{}) (*BoundSQL, error) {
+	return m.bind(id, param, m.placeholder)
+}
+
+// bind 使用占位符风格style绑定参数
+func (m *Mapper) bind(id string, param interface{}, style PlaceholderStyle) (*BoundSQL, error) {
+	child, ok := m.root[id]
+	if !ok {
+		return nil, fmt.Errorf("statement(%v) not found", id)
+	}
+
+	b := &binder{
+		mapper: m,
+		scope: &scope{
+			param:      param,
+			databaseID: m.databaseID,
+			locals:     make(map[string]interface{}),
+		},
+		bound:       &BoundSQL{ID: id},
+		fragments:   newFragments(m.root),
+		placeholder: style,
+	}
+	sql, err := b.render(child, nil)
+	if err != nil {
+		return nil, fmt.Errorf("bind statement(%v) fail. err: %v", id, err)
+	}
+	b.bound.SQL = strings.TrimSpace(sql)
+	return b.bound, nil
+}
+
+// binder 使用参数值遍历语句
+type binder struct {
+	mapper      *Mapper
+	scope       *scope
+	bound       *BoundSQL
+	fragments   *fragments
+	placeholder PlaceholderStyle
+}
+
+func (b *binder) render(e *etree.Element, properties map[string]string) (string, error) {
+	sb := &strings.Builder{}
+	for _, token := range e.Child {
+		var s string
+		var err error
+		switch t := token.(type) {
+		case *etree.CharData:
+			s, err = b.text(convertCDATA(t.Data, false), properties)
+		case *etree.Element:
+			s, err = b.element(t, properties)
+		}
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(s)
+	}
+	return sb.String(), nil
+}
+
+func (b *binder) text(s string, properties map[string]string) (string, error) {
+	var err error
+	replaced := replaceParams(s, func(match string) string {
+		if err != nil {
+			return match
+		}
+		p := newParam(match)
+		name := strings.TrimSpace(p.Name)
+		if match[0] == '$' {
+			if v, ok := properties[name]; ok {
+				return v
+			}
+		}
+
+		var v interface{}
+		if v, err = evalExpression(name, b.scope); err != nil {
+			return match
+		}
+		if match[0] == '$' {
+			return toString(v)
+		}
+
+		b.bound.Args = append(b.bound.Args, v)
+		b.bound.Params = append(b.bound.Params, p)
+		if b.placeholder == PlaceholderDollar {
+			return "$" + strconv.Itoa(len(b.bound.Args))
+		}
+		return "?"
+	})
+	return replaced, err
+}
+
+func (b *binder) element(e *etree.Element, properties map[string]string) (string, error) {
+	switch e.Tag {
+	case "include":
+		var sql string
+		err := b.fragments.expand(e, properties, func(_ string, fragment *etree.Element, props map[string]string) (err error) {
+			sql, err = b.render(fragment, props)
+			return
+		})
+		return sql, err
+	case "if":
+		ok, err := evalBool(e.SelectAttrValue("test", ""), b.scope)
+		if err != nil || !ok {
+			return "", err
+		}
+		return b.render(e, properties)
+	case "choose":
+		for _, c := range e.ChildElements() {
+			if c.Tag == "otherwise" {
+				return b.render(c, properties)
+			}
+			if c.Tag != "when" {
+				continue
+			}
+			ok, err := evalBool(c.SelectAttrValue("test", ""), b.scope)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return b.render(c, properties)
+			}
+		}
+		return "", nil
+	case "trim", "where", "set":
+		return b.trim(e, properties)
+	case "foreach":
+		return b.foreach(e, properties)
+	case "bind":
+		v, err := evalExpression(e.SelectAttrValue("value", ""), b.scope)
+		if err != nil {
+			return "", err
+		}
+		b.scope.locals[e.SelectAttrValue("name", "")] = v
+		return "", nil
+	case "selectKey":
+		return "", nil
+	}
+	return b.render(e, properties)
+}
+
+func (b *binder) trim(e *etree.Element, properties map[string]string) (string, error) {
+	rule := whereRule
+	switch e.Tag {
+	case "trim":
+		rule = newTrimRule(
+			e.SelectAttrValue("prefix", ""),
+			e.SelectAttrValue("suffix", ""),
+			e.SelectAttrValue("prefixOverrides", ""),
+			e.SelectAttrValue("suffixOverrides", ""),
+		)
+	case "set":
+		rule = setRule
+	}
+
+	content, err := b.render(e, properties)
+	if err != nil {
+		return "", err
+	}
+	if content = rule.apply(content); content == "" {
+		return "", nil
+	}
+	return " " + content + " ", nil
+}
+
+func (b *binder) foreach(e *etree.Element, properties map[string]string) (string, error) {
+	collection := e.SelectAttrValue("collection", "")
+	v, err := evalExpression(collection, b.scope)
+	if err != nil {
+		return "", err
+	}
+	if isNil(v) {
+		if nullable, _ := strconv.ParseBool(e.SelectAttrValue("nullable", "false")); nullable {
+			return "", nil
+		}
+		return "", fmt.Errorf("the expression(%v) evaluated to a null value", collection)
+	}
+
+	itemName := e.SelectAttrValue("item", "")
+	indexName := e.SelectAttrValue("index", "")
+	saved := make(map[string]interface{})
+	for _, name := range []string{itemName, indexName} {
+		if old, ok := b.scope.locals[name]; ok && name != "" {
+			saved[name] = old
+		}
+	}
+	defer func() {
+		for _, name := range []string{itemName, indexName} {
+			delete(b.scope.locals, name)
+			if old, ok := saved[name]; ok {
+				b.scope.locals[name] = old
+			}
+		}
+	}()
+
+	var items []string
+	err = iterate(v, func(index, item interface{}) error {
+		if itemName != "" {
+			b.scope.locals[itemName] = item
+		}
+		if indexName != "" {
+			b.scope.locals[indexName] = index
+		}
+		s, err := b.render(e, properties)
+		if err != nil {
+			return err
+		}
+		items = append(items, strings.TrimSpace(s))
+		return nil
+	})
+	if err != nil || len(items) == 0 {
+		return "", err
+	}
+	return e.SelectAttrValue("open", "") +
+		strings.Join(items, e.SelectAttrValue("separator", "")) +
+		e.SelectAttrValue("close", ""), nil
+}
@@ -0,0 +1,161 @@
+package mybaits
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testFruit struct {
+	ID    int64
+	Name  *string
+	Price *float64
+}
+
+func TestMapper_Bind(t *testing.T) {
+	m, err := NewMapper("testdata/bind.xml", WithDatabaseIDProvider(StaticDatabaseID("postgresql")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "apple"
+
+	tests := []struct {
+		name     string
+		id       string
+		param    interface{}
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			name: "allConditions",
+			id:   "selectFruits",
+			param: map[string]interface{}{
+				"name":     "apple",
+				"minPrice": 10,
+				"ids":      []int{1, 2},
+				"orderBy":  "price",
+			},
+			wantSQL:  "SELECT id, name, price FROM fruits WHERE name = ? AND price >= ? AND id IN (?,?) ORDER BY price",
+			wantArgs: []interface{}{"apple", 10, 1, 2},
+		},
+		{
+			name: "noConditions",
+			id:   "selectFruits",
+			param: map[string]interface{}{
+				"name":    "",
+				"ids":     []int{},
+				"orderBy": "name DESC",
+			},
+			wantSQL: "SELECT id, name, price FROM fruits ORDER BY name DESC",
+		},
+		{
+			name:     "bind",
+			id:       "selectByName",
+			param:    map[string]interface{}{"name": "app"},
+			wantSQL:  "SELECT id FROM fruits WHERE name LIKE ?",
+			wantArgs: []interface{}{"%app%"},
+		},
+		{
+			name:     "struct",
+			id:       "updateFruit",
+			param:    map[string]interface{}{"fruit": &testFruit{ID: 3, Name: &name}},
+			wantSQL:  "UPDATE fruits SET name = ? WHERE id = ?",
+			wantArgs: []interface{}{&name, int64(3)},
+		},
+		{
+			name:    "databaseID",
+			id:      "selectByDatabase",
+			wantSQL: "SELECT now()",
+		},
+		{
+			name:     "scalar",
+			id:       "selectByID",
+			param:    7,
+			wantSQL:  "SELECT name FROM fruits WHERE id = ?",
+			wantArgs: []interface{}{7},
+		},
+		{
+			name:     "nilName",
+			id:       "selectFruits",
+			param:    map[string]interface{}{"ids": []int{1}, "orderBy": "price", "name": nil},
+			wantSQL:  "SELECT id, name, price FROM fruits WHERE id IN (?) ORDER BY price",
+			wantArgs: []interface{}{1},
+		},
+		{
+			// 和mybatis一致，结构体中没有的属性返回错误，map中没有的键为null
+			name:    "unknownProperty",
+			id:      "selectByID",
+			param:   &testFruit{ID: 7},
+			wantErr: true,
+		},
+		{
+			name:    "notFound",
+			id:      "notFound",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Bind(tt.id, tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Bind() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			gotSQL := whitespaceRegex.ReplaceAllString(got.SQL, " ")
+			if gotSQL != tt.wantSQL {
+				t.Errorf("Bind() SQL = %v, want %v", gotSQL, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(got.Args, tt.wantArgs) {
+				t.Errorf("Bind() Args = %v, want %v", got.Args, tt.wantArgs)
+			}
+			if len(got.Params) != len(got.Args) {
+				t.Errorf("Bind() Params = %v, Args = %v", got.Params, got.Args)
+			}
+		})
+	}
+}
+
+func TestMapper_Bind_placeholder(t *testing.T) {
+	m, err := NewMapper("testdata/bind.xml", WithPlaceholder(PlaceholderDollar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Bind("selectFruits", map[string]interface{}{
+		"name":    "apple",
+		"ids":     []int{1, 2},
+		"orderBy": "price",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT id, name, price FROM fruits WHERE name = $1 AND id IN ($2,$3) ORDER BY price"
+	if gotSQL := whitespaceRegex.ReplaceAllString(got.SQL, " "); gotSQL != want {
+		t.Errorf("Bind() SQL = %v, want %v", gotSQL, want)
+	}
+}
+
+func TestMapper_Bind_shortCircuit(t *testing.T) {
+	m, err := NewMapperFromBytes("fruits.xml", []byte(`<mapper namespace="fruits">
+    <select id="selectExpensive">
+        SELECT id FROM fruits
+        <where>
+            <if test="price != null and price > 100">price > #{price}</if>
+            <if test="name == null or name.length() > 0">AND name IS NOT NULL</if>
+        </where>
+    </select>
+</mapper>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Bind("selectExpensive", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	want := "SELECT id FROM fruits WHERE name IS NOT NULL"
+	if gotSQL := strings.TrimSpace(whitespaceRegex.ReplaceAllString(got.SQL, " ")); gotSQL != want {
+		t.Errorf("Bind() SQL = %v, want %v", gotSQL, want)
+	}
+}
@@ -0,0 +1,213 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Executor 执行SQL的接口，*sql.DB、*sql.Tx和*sql.Conn都实现了该接口
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+var callEscapeRegex = regexp.MustCompile(`(?is)^\{\s*call\s+(.*?)\s*\}$`)
+
+// Call 执行statementType为CALLABLE的语句id，语句中jdbc的{call proc(...)}会转化为CALL proc(...)，
+// exec为*sql.DB并且上下文中有它的事务时在事务中执行。
+// mode为OUT和INOUT的参数使用sql.Out传递，执行后写回param，因此param需要是map或者结构体指针
+func (m *Mapper) Call(ctx context.Context, exec Executor, id string, param interface{}) (sql.Result, error) {
+	info, err := m.Statement(id)
+	if err != nil {
+		return nil, err
+	}
+	if info.StatementType != "CALLABLE" {
+		return nil, fmt.Errorf("statement(%v) is not callable", id)
+	}
+
+	bound, err := m.Bind(id, param)
+	if err != nil {
+		return nil, err
+	}
+	query := bound.SQL
+	if matches := callEscapeRegex.FindStringSubmatch(query); len(matches) > 1 {
+		query = "CALL " + matches[1]
+	}
+
+	args := append([]interface{}(nil), bound.Args...)
+	outs := make(map[int]reflect.Value)
+	for i, p := range bound.Params {
+		if p.Mode != "OUT" && p.Mode != "INOUT" {
+			continue
+		}
+		var dest reflect.Value
+		if dest, err = outDest(param, p, bound.Args[i]); err != nil {
+			return nil, fmt.Errorf("statement(%v) parameter(%v) fail. err: %v", id, p.Name, err)
+		}
+		outs[i] = dest
+		args[i] = sql.Out{Dest: dest.Interface(), In: p.Mode == "INOUT"}
+	}
+
+	result, err := executor(ctx, exec).ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for i, dest := range outs {
+		p := bound.Params[i]
+		if err = setProperty(param, strings.TrimSpace(p.Name), dest.Elem().Interface()); err != nil {
+			return nil, fmt.Errorf("statement(%v) parameter(%v) fail. err: %v", id, p.Name, err)
+		}
+	}
+	return result, nil
+}
+
+var jdbcGoTypes = map[string]reflect.Type{
+	"TINYINT":       reflect.TypeOf(int64(0)),
+	"SMALLINT":      reflect.TypeOf(int64(0)),
+	"INTEGER":       reflect.TypeOf(int64(0)),
+	"BIGINT":        reflect.TypeOf(int64(0)),
+	"DECIMAL":       reflect.TypeOf(float64(0)),
+	"NUMERIC":       reflect.TypeOf(float64(0)),
+	"DOUBLE":        reflect.TypeOf(float64(0)),
+	"FLOAT":         reflect.TypeOf(float64(0)),
+	"REAL":          reflect.TypeOf(float64(0)),
+	"BIT":           reflect.TypeOf(false),
+	"BOOLEAN":       reflect.TypeOf(false),
+	"DATE":          reflect.TypeOf(time.Time{}),
+	"TIME":          reflect.TypeOf(time.Time{}),
+	"TIMESTAMP":     reflect.TypeOf(time.Time{}),
+	"CHAR":          reflect.TypeOf(""),
+	"VARCHAR":       reflect.TypeOf(""),
+	"NCHAR":         reflect.TypeOf(""),
+	"NVARCHAR":      reflect.TypeOf(""),
+	"LONGVARCHAR":   reflect.TypeOf(""),
+	"LONGNVARCHAR":  reflect.TypeOf(""),
+	"CLOB":          reflect.TypeOf(""),
+	"BINARY":        reflect.TypeOf([]byte(nil)),
+	"VARBINARY":     reflect.TypeOf([]byte(nil)),
+	"LONGVARBINARY": reflect.TypeOf([]byte(nil)),
+	"BLOB":          reflect.TypeOf([]byte(nil)),
+}
+
+// outDest 生成输出参数的指针，类型依次取自结构体字段、当前值和jdbcType，INOUT参数会设置为当前值
+func outDest(param interface{}, p Param, current interface{}) (dest reflect.Value, err error) {
+	if p.JdbcType == "CURSOR" {
+		return dest, fmt.Errorf("jdbcType CURSOR is not supported")
+	}
+
+	var typ reflect.Type
+	if container, last, err := walkProperty(param, strings.TrimSpace(p.Name)); err == nil && container.Kind() == reflect.Struct {
+		if f, ok := fieldByName(container.Type(), last); ok {
+			typ = f.Type
+		}
+	}
+	if typ == nil && current != nil {
+		typ = reflect.TypeOf(current)
+	}
+	if typ == nil {
+		typ = jdbcGoTypes[p.JdbcType]
+	}
+	if typ == nil {
+		typ = reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+
+	dest = reflect.New(typ)
+	if p.Mode == "INOUT" && current != nil {
+		if err = assign(dest.Elem(), current); err != nil {
+			return dest, err
+		}
+	}
+	return dest, nil
+}
+
+// walkProperty 获取属性路径name中最后一段所在的map或者结构体，以及最后一段的名字
+func walkProperty(param interface{}, name string) (container reflect.Value, last string, err error) {
+	segs := strings.Split(name, ".")
+	rv := reflect.ValueOf(param)
+	for i, seg := range segs {
+		for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return container, "", fmt.Errorf("%v is nil", strings.Join(segs[:i], "."))
+			}
+			rv = rv.Elem()
+		}
+		if i == len(segs)-1 {
+			return rv, seg, nil
+		}
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				return container, "", fmt.Errorf("key of %v is not string", strings.Join(segs[:i+1], "."))
+			}
+			rv = rv.MapIndex(reflect.ValueOf(seg).Convert(rv.Type().Key()))
+		case reflect.Struct:
+			rv = structField(rv, seg)
+		default:
+			return container, "", fmt.Errorf("%v is not map or struct", strings.Join(segs[:i+1], "."))
+		}
+		if !rv.IsValid() {
+			return container, "", fmt.Errorf("%v not found", strings.Join(segs[:i+1], "."))
+		}
+	}
+	return
+}
+
+// setProperty 将param中属性路径name的值设置为value
+func setProperty(param interface{}, name string, value interface{}) error {
+	container, last, err := walkProperty(param, name)
+	if err != nil {
+		return err
+	}
+	if !container.IsValid() {
+		return fmt.Errorf("parameter is nil")
+	}
+	switch container.Kind() {
+	case reflect.Map:
+		if container.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("key of %v is not string", container.Type())
+		}
+		if container.IsNil() {
+			return fmt.Errorf("map is nil")
+		}
+		v := reflect.New(container.Type().Elem()).Elem()
+		if err = assign(v, value); err != nil {
+			return err
+		}
+		container.SetMapIndex(reflect.ValueOf(last).Convert(container.Type().Key()), v)
+		return nil
+	case reflect.Struct:
+		f := structField(container, last)
+		if !f.IsValid() {
+			return fmt.Errorf("field %v not found in %v", last, container.Type())
+		}
+		if !f.CanSet() {
+			return fmt.Errorf("field %v of %v can not be set, use a pointer", last, container.Type())
+		}
+		return assign(f, value)
+	}
+	return fmt.Errorf("%v is not map or struct", container.Type())
+}
+
+// assign 将value赋值给dst，类型不同时尝试转化
+func assign(dst reflect.Value, value interface{}) error {
+	v := reflect.ValueOf(value)
+	switch {
+	case !v.IsValid():
+		dst.Set(reflect.Zero(dst.Type()))
+	case v.Type().AssignableTo(dst.Type()):
+		dst.Set(v)
+	case dst.Kind() == reflect.String && v.Kind() != reflect.String:
+		// 避免整数按照rune转化为字符串
+		dst.SetString(fmt.Sprint(value))
+	case v.Type().ConvertibleTo(dst.Type()):
+		dst.Set(v.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("%T can not be assigned to %v", value, dst.Type())
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// callOut 模拟存储过程，将OUT参数设置为100，INOUT参数乘以2
+func callOut(query string, args []driver.NamedValue) (driver.Result, error) {
+	for _, a := range args {
+		out, ok := a.Value.(sql.Out)
+		if !ok {
+			continue
+		}
+		switch dest := out.Dest.(type) {
+		case *int64:
+			*dest = 100
+		case *float64:
+			*dest *= 2
+		default:
+			return nil, fmt.Errorf("unexpected dest %T", out.Dest)
+		}
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type testPrice struct {
+	ID    int
+	Price float64
+}
+
+func TestMapper_Call(t *testing.T) {
+	m, err := NewMapper("testdata/bind.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		id        string
+		param     interface{}
+		wantQuery string
+		want      interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "outToMap",
+			id:        "countFruits",
+			param:     map[string]interface{}{"category": "apple"},
+			wantQuery: "CALL count_fruits(?, ?)",
+			want:      map[string]interface{}{"category": "apple", "total": int64(100)},
+		},
+		{
+			name:      "inoutToStruct",
+			id:        "adjustPrice",
+			param:     &testPrice{ID: 1, Price: 1.5},
+			wantQuery: "CALL adjust_price(?, ?)",
+			want:      &testPrice{ID: 1, Price: 3},
+		},
+		{
+			name:    "structNotPointer",
+			id:      "adjustPrice",
+			param:   testPrice{ID: 1, Price: 1.5},
+			wantErr: true,
+		},
+		{
+			name:    "notCallable",
+			id:      "selectByID",
+			param:   1,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDriver{exec: callOut}
+			db := newFakeDB(d)
+			defer db.Close()
+
+			_, err := m.Call(context.Background(), db, tt.id, tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Call() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if calls := d.recorded(); len(calls) != 1 || calls[0].Query != tt.wantQuery {
+				t.Errorf("Call() calls = %v, want %v", calls, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(tt.param, tt.want) {
+				t.Errorf("Call() param = %v, want %v", tt.param, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package mybaits
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+)
+
+// Catalog 语句目录
+type Catalog []CatalogEntry
+
+// CatalogEntry 语句目录中的一条语句
+type CatalogEntry struct {
+	Namespace string         `json:"namespace"`
+	ID        string         `json:"id"`
+	Kind      string         `json:"kind"`
+	SQL       string         `json:"sql"`
+	Params    []CatalogParam `json:"params"`
+	Tables    []string       `json:"tables"`
+	File      string         `json:"file"`
+	Line      int            `json:"line"`
+	Error     string         `json:"error,omitempty"` // 语句无法规范化时的错误
+}
+
+// CatalogParam 语句目录中的参数
+type CatalogParam struct {
+	Name     string `json:"name"`
+	JdbcType string `json:"jdbcType,omitempty"`
+	JavaType string `json:"javaType,omitempty"`
+}
+
+// Catalog 按文档顺序获取映射文件中所有语句(不包括sql片段)的目录，
+// 语句无法规范化时不会返回错误，而是记录在目录的Error中
+func (m *Mapper) Catalog() (catalog Catalog, err error) {
+	for _, id := range m.ids {
+		child := m.root[id]
+		if child.Tag == "sql" {
+			continue
+		}
+
+		var info *StatementInfo
+		if info, err = m.Statement(id); err != nil {
+			return nil, err
+		}
+		entry := CatalogEntry{
+			Namespace: m.namespace,
+			ID:        id,
+			Kind:      info.Kind,
+			Params:    []CatalogParam{},
+			Tables:    []string{},
+			File:      m.path,
+			Line:      m.Line(id),
+		}
+		for _, p := range info.Params {
+			entry.Params = append(entry.Params, CatalogParam{
+				Name:     p.Name,
+				JdbcType: p.JdbcType,
+				JavaType: p.JavaType,
+			})
+		}
+
+		cm := m.newChildMapper(child)
+		if entry.SQL, err = cm.getStatement(); err != nil {
+			entry.Error = err.Error()
+			err = nil
+		} else if entry.Tables, err = referencedTables(entry.SQL); err != nil {
+			entry.Error = err.Error()
+			err = nil
+		}
+		catalog = append(catalog, entry)
+	}
+	return
+}
+
+// referencedTables 获取sql中引用的所有表名，按字母顺序排列
+func referencedTables(sql string) ([]string, error) {
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	tables := []string{}
+	err = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		// 列名中的限定符也是TableName，但不是引用的表
+		if _, ok := node.(*sqlparser.ColName); ok {
+			return false, nil
+		}
+		if t, ok := node.(sqlparser.TableName); ok && !t.IsEmpty() {
+			name := t.Name.String()
+			if !t.Qualifier.IsEmpty() {
+				name = t.Qualifier.String() + "." + name
+			}
+			if name != "dual" && !seen[name] {
+				seen[name] = true
+				tables = append(tables, name)
+			}
+			return false, nil
+		}
+		return true, nil
+	}, stmt)
+	sort.Strings(tables)
+	return tables, err
+}
+
+// WriteJSON 以JSON格式输出目录
+func (c Catalog) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if c == nil {
+		c = Catalog{}
+	}
+	return encoder.Encode(c)
+}
+
+var catalogHeader = []string{"namespace", "id", "kind", "sql", "params", "tables", "file", "line", "error"}
+
+// WriteCSV 以CSV格式输出目录，参数以name:jdbcType:javaType的形式用分号分隔
+func (c Catalog) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(catalogHeader); err != nil {
+		return err
+	}
+	for _, e := range c {
+		record := []string{
+			e.Namespace, e.ID, e.Kind, e.SQL, formatCatalogParams(e.Params, ";"),
+			strings.Join(e.Tables, ";"), e.File, strconv.Itoa(e.Line), e.Error,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMarkdown 以Markdown表格格式输出目录
+func (c Catalog) WriteMarkdown(w io.Writer) error {
+	b := &strings.Builder{}
+	b.WriteString("| " + strings.Join(catalogHeader, " | ") + " |\n")
+	b.WriteString(strings.Repeat("| --- ", len(catalogHeader)) + "|\n")
+	for _, e := range c {
+		cells := []string{
+			e.Namespace, e.ID, e.Kind, codeCell(e.SQL), formatCatalogParams(e.Params, "<br>"),
+			strings.Join(e.Tables, "<br>"), e.File, strconv.Itoa(e.Line), e.Error,
+		}
+		for i := range cells {
+			cells[i] = strings.ReplaceAll(cells[i], "|", `\|`)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Write 以format(json, csv, markdown)格式输出目录
+func (c Catalog) Write(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "json":
+		return c.WriteJSON(w)
+	case "csv":
+		return c.WriteCSV(w)
+	case "markdown", "md":
+		return c.WriteMarkdown(w)
+	}
+	return fmt.Errorf("catalog format(%v) is not supported", format)
+}
+
+func formatCatalogParams(params []CatalogParam, sep string) string {
+	var s []string
+	for _, p := range params {
+		s = append(s, p.Name+":"+p.JdbcType+":"+p.JavaType)
+	}
+	return strings.Join(s, sep)
+}
+
+func codeCell(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,111 @@
+package mybaits
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMapper_Catalog(t *testing.T) {
+	m, err := NewMapper("testdata/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Catalog{
+		{
+			Namespace: "Catalog",
+			ID:        "selectOrders",
+			Kind:      "select",
+			SQL:       "select o.id, o.name from sales.orders as o join customers as c on o.customer_id = c.id where o.id = :v1",
+			Params:    []CatalogParam{{Name: "id", JdbcType: "BIGINT", JavaType: "Long"}},
+			Tables:    []string{"customers", "sales.orders"},
+			File:      "testdata/catalog.xml",
+			Line:      7,
+		},
+		{
+			Namespace: "Catalog",
+			ID:        "insertOrder",
+			Kind:      "insert",
+			SQL:       "insert into orders(id, name) values (:v1, :v2)",
+			Params:    []CatalogParam{{Name: "id"}, {Name: "name", JdbcType: "VARCHAR"}},
+			Tables:    []string{"orders"},
+			File:      "testdata/catalog.xml",
+			Line:      14,
+		},
+		{
+			Namespace: "Catalog",
+			ID:        "broken",
+			Kind:      "update",
+			Params:    []CatalogParam{},
+			Tables:    []string{},
+			File:      "testdata/catalog.xml",
+			Line:      18,
+			Error:     "syntax error at position 33 near 'where'",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Mapper.Catalog() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCatalog_Write(t *testing.T) {
+	c := Catalog{
+		{
+			Namespace: "Catalog",
+			ID:        "selectOrders",
+			Kind:      "select",
+			SQL:       "select a from t where a = :v1 or b | c",
+			Params:    []CatalogParam{{Name: "id", JdbcType: "BIGINT"}, {Name: "name"}},
+			Tables:    []string{"t"},
+			File:      "catalog.xml",
+			Line:      7,
+		},
+	}
+	tests := []struct {
+		format  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			format: "json",
+			want:   []string{`"id": "selectOrders"`, `"jdbcType": "BIGINT"`, `"line": 7`},
+		},
+		{
+			format: "csv",
+			want: []string{
+				"namespace,id,kind,sql,params,tables,file,line,error\n",
+				"Catalog,selectOrders,select,select a from t where a = :v1 or b | c,id:BIGINT:;name::,t,catalog.xml,7,\n",
+			},
+		},
+		{
+			format: "markdown",
+			want: []string{
+				"| namespace | id | kind | sql | params | tables | file | line | error |\n",
+				"| Catalog | selectOrders | select | `select a from t where a = :v1 or b \\| c` | id:BIGINT:<br>name:: | t | catalog.xml | 7 |  |\n",
+			},
+		},
+		{
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := c.Write(buf, tt.format); (err != nil) != tt.wantErr {
+				t.Fatalf("Catalog.Write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(buf.String(), w) {
+					t.Errorf("Catalog.Write() = %v, want contains %v", buf.String(), w)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package mybaits
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -13,6 +14,8 @@ type childMapper struct {
 	properties map[string]string
 	native     bool
 	whenCnt    int
+	databaseID string
+	mocker     *mocker
 }
 
 // func GetChildStatement(mybatisMapper map[string]*etree.Element, childID string, kwargs map[string]interface{}) (string, error) {
@@ -29,6 +32,14 @@ type childMapper struct {
 //		return formatSQL(stmt, kwargs), nil
 //	}
 func (cm *childMapper) getStatement() (stmt string, err error) {
+	myStmt := Statement{
+		sql: cm.getRawStatement(),
+	}
+	return myStmt.formatSQL()
+}
+
+// getRawStatement 获取未经过规范化的语句
+func (cm *childMapper) getRawStatement() string {
 	stmtB := &strings.Builder{}
 	stmtB.WriteString(cm.convert())
 	for _, c := range cm.child.ChildElements() {
@@ -37,16 +48,14 @@ func (cm *childMapper) getStatement() (stmt string, err error) {
 			child:      c,
 			properties: cm.properties,
 			native:     cm.native,
+			databaseID: cm.databaseID,
+			mocker:     cm.mocker,
 			whenCnt:    cm.whenCnt,
 		}
 
 		stmtB.WriteString(ccm.convert())
 	}
-
-	myStmt := Statement{
-		sql: stmtB.String(),
-	}
-	return myStmt.formatSQL()
+	return stmtB.String()
 }
 
 func (cm *childMapper) convert() string {
@@ -73,13 +82,14 @@ func (cm *childMapper) convert() string {
 func (cm *childMapper) convertParameters(text, tail bool) (convertString string) {
 	p := regexp.MustCompile(`\S`)
 
+	// 和mybatis一致，只有空白字符的文本作为分隔各部分的空格
 	childText := cm.child.Text()
-	if !p.MatchString(childText) {
-		childText = ""
+	if childText != "" && !p.MatchString(childText) {
+		childText = " "
 	}
 	childTail := cm.child.Tail()
-	if !p.MatchString(childTail) {
-		childTail = ""
+	if childTail != "" && !p.MatchString(childTail) {
+		childTail = " "
 	}
 
 	if text && tail {
@@ -94,7 +104,12 @@ func (cm *childMapper) convertParameters(text, tail bool) (convertString string)
 	paramsMap := GetParams(childText, childTail)
 	allParams := append(paramsMap["#"], paramsMap["$"]...)
 	for _, p := range allParams {
-		convertString = strings.ReplaceAll(convertString, p.FullName, p.MockValue)
+		value, ok := cm.properties[strings.TrimSpace(p.Name)]
+		// 和mybatis一致，include中property定义的${}在包含时替换为属性值
+		if !ok || !strings.HasPrefix(p.FullName, "$") {
+			value = cm.mocker.value(p)
+		}
+		convertString = strings.ReplaceAll(convertString, p.FullName, value)
 	}
 
 	convertString = convertCDATA(convertString, false)
@@ -102,31 +117,8 @@ func (cm *childMapper) convertParameters(text, tail bool) (convertString string)
 }
 
 func (cm *childMapper) convertInclude() string {
-	properties := make(map[string]string)
-	if cm.properties != nil {
-		for k, v := range cm.properties {
-			properties[k] = v
-		}
-	}
-
-	for _, c := range cm.child.ChildElements() {
-		if c.Tag == "property" {
-			name := c.SelectAttrValue("name", "")
-			value := c.SelectAttrValue("value", "")
-			properties[name] = value
-		}
-	}
-
-	refID := cm.child.SelectAttrValue("refid", "")
-	for _, char := range []string{"#", "$"} {
-		pattern := regexp.MustCompile(`\` + char + `\{(.+?)\}`)
-		if matches := pattern.FindStringSubmatch(refID); len(matches) > 1 {
-			if val, ok := properties[matches[1]]; ok {
-				refID = val
-				break
-			}
-		}
-	}
+	properties := includeProperties(cm.properties, cm.child)
+	refID := resolveRefID(cm.child.SelectAttrValue("refid", ""), properties)
 	cb := &strings.Builder{}
 	includeChild, ok := cm.root[refID]
 	if !ok {
@@ -136,8 +128,10 @@ func (cm *childMapper) convertInclude() string {
 	includeCM := &childMapper{
 		root:       cm.root,
 		child:      includeChild,
-		properties: cm.properties,
+		properties: properties,
 		native:     cm.native,
+		databaseID: cm.databaseID,
+		mocker:     cm.mocker,
 		whenCnt:    cm.whenCnt,
 	}
 
@@ -150,6 +144,8 @@ func (cm *childMapper) convertInclude() string {
 			child:      c,
 			properties: properties,
 			native:     cm.native,
+			databaseID: cm.databaseID,
+			mocker:     cm.mocker,
 			whenCnt:    cm.whenCnt,
 		}
 		cb.WriteString(ccm.convert())
@@ -159,7 +155,71 @@ func (cm *childMapper) convertInclude() string {
 	return cb.String()
 }
 
+// includeProperties 合并上层的属性以及include中的property子元素
+func includeProperties(parent map[string]string, include *etree.Element) map[string]string {
+	properties := make(map[string]string)
+	for k, v := range parent {
+		properties[k] = v
+	}
+
+	for _, c := range include.ChildElements() {
+		if c.Tag == "property" {
+			name := c.SelectAttrValue("name", "")
+			value := c.SelectAttrValue("value", "")
+			properties[name] = value
+		}
+	}
+	return properties
+}
+
+// resolveRefID 使用属性properties替换refID中的#{}或者${}
+func resolveRefID(refID string, properties map[string]string) string {
+	for _, char := range []string{"#", "$"} {
+		pattern := regexp.MustCompile(`\` + char + `\{(.+?)\}`)
+		if matches := pattern.FindStringSubmatch(refID); len(matches) > 1 {
+			if val, ok := properties[matches[1]]; ok {
+				return val
+			}
+		}
+	}
+	return refID
+}
+
+// fragments 展开include引用的sql片段，记录正在展开的片段以发现循环引用
+type fragments struct {
+	root     map[string]*etree.Element
+	included map[string]bool
+}
+
+func newFragments(root map[string]*etree.Element) *fragments {
+	return &fragments{
+		root:     root,
+		included: make(map[string]bool),
+	}
+}
+
+// expand 使用上层的属性properties展开include元素，对引用的片段及其属性调用fn，
+// 片段不存在或者循环引用时返回错误
+func (f *fragments) expand(include *etree.Element, properties map[string]string,
+	fn func(refID string, fragment *etree.Element, properties map[string]string) error) error {
+	includeProps := includeProperties(properties, include)
+	refID := resolveRefID(include.SelectAttrValue("refid", ""), includeProps)
+	fragment, ok := f.root[refID]
+	if !ok {
+		return fmt.Errorf("sql(%v) not found", refID)
+	}
+	if f.included[refID] {
+		return fmt.Errorf("sql(%v) includes itself", refID)
+	}
+	f.included[refID] = true
+	defer func() { f.included[refID] = false }()
+	return fn(refID, fragment, includeProps)
+}
+
 func (cm *childMapper) convertIf() string {
+	if matched, ok := evalDatabaseIDTest(cm.child.SelectAttrValue("test", ""), cm.databaseID); ok && !matched {
+		return cm.convertParameters(false, true)
+	}
 
 	cb := &strings.Builder{}
 	// test := cm.child.SelectAttrValue("test", "")
@@ -177,6 +237,8 @@ func (cm *childMapper) convertIf() string {
 
 			properties: cm.properties,
 			native:     cm.native,
+			databaseID: cm.databaseID,
+			mocker:     cm.mocker,
 			whenCnt:    cm.whenCnt,
 		}
 		str = ccm.convert()
@@ -202,8 +264,14 @@ func (cm *childMapper) convertChooseWhenOtherwise() string {
 			child:      c,
 			properties: cm.properties,
 			native:     cm.native,
+			databaseID: cm.databaseID,
+			mocker:     cm.mocker,
 		}
+		matched, known := false, false
 		if c.Tag == "when" {
+			if matched, known = evalDatabaseIDTest(c.SelectAttrValue("test", ""), cm.databaseID); known && !matched {
+				continue
+			}
 			if !(native && whenCnt >= 1) {
 				// test := c.SelectAttrValue("test", "")
 				// cb.WriteString("-- if(")
@@ -219,6 +287,9 @@ func (cm *childMapper) convertChooseWhenOtherwise() string {
 
 		}
 		cb.WriteString(ccm.convert())
+		if known && matched {
+			break
+		}
 	}
 
 	if cm.child.Tag == "choose" {
@@ -228,20 +299,20 @@ func (cm *childMapper) convertChooseWhenOtherwise() string {
 }
 
 func (cm *childMapper) convertTrimWhereSet() string {
-	var prefix, suffix, prefixOverrides, suffixOverrides string
+	var rule trimRule
 
 	switch cm.child.Tag {
 	case "trim":
-		prefix = cm.child.SelectAttrValue("prefix", "")
-		suffix = cm.child.SelectAttrValue("suffix", "")
-		prefixOverrides = cm.child.SelectAttrValue("prefixOverrides", "")
-		suffixOverrides = cm.child.SelectAttrValue("suffixOverrides", "")
+		rule = newTrimRule(
+			cm.child.SelectAttrValue("prefix", ""),
+			cm.child.SelectAttrValue("suffix", ""),
+			cm.child.SelectAttrValue("prefixOverrides", ""),
+			cm.child.SelectAttrValue("suffixOverrides", ""),
+		)
 	case "set":
-		prefix = "SET"
-		suffixOverrides = ","
+		rule = setRule
 	case "where":
-		prefix = "WHERE"
-		prefixOverrides = "AND|and|OR|or"
+		rule = whereRule
 	default:
 		return ""
 	}
@@ -254,32 +325,20 @@ func (cm *childMapper) convertTrimWhereSet() string {
 			child:      c,
 			properties: cm.properties,
 			native:     cm.native,
+			databaseID: cm.databaseID,
+			mocker:     cm.mocker,
 			whenCnt:    cm.whenCnt,
 		}
 		cb.WriteString(ccm.convert())
 	}
 
-	convertString := cb.String()
-	if prefixOverrides != "" {
-		convertString = replaceFirst(convertString, `^[\s]*?(`+prefixOverrides+`)`, "")
-	}
-
-	if suffixOverrides != "" {
-		pattern := `(` + suffixOverrides + `)[\s]*$`
-		convertString = replaceFirst(convertString, pattern, "")
-	}
-
+	convertString := rule.apply(cb.String())
 	cb.Reset()
-	if regexp.MustCompile(`\S`).MatchString(convertString) {
-		if prefix != "" {
-			cb.WriteString(prefix)
-			cb.WriteString(" ")
-			cb.WriteString(convertString)
-		}
-		if suffix != "" {
-			cb.WriteString(" ")
-			cb.WriteString(suffix)
-		}
+	if convertString != "" {
+		// 和mybatis一致，动态sql的各部分之间以空格分隔
+		cb.WriteString(" ")
+		cb.WriteString(convertString)
+		cb.WriteString(" ")
 	}
 
 	cb.WriteString(cm.convertParameters(false, true))
@@ -299,6 +358,8 @@ func (cm *childMapper) convertForeach() string {
 			child:      c,
 			properties: cm.properties,
 			native:     cm.native,
+			databaseID: cm.databaseID,
+			mocker:     cm.mocker,
 			whenCnt:    cm.whenCnt,
 		}
 		cb.WriteString(ccm.convert())
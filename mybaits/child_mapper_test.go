@@ -70,7 +70,7 @@ func Test_childMapper_getStatement(t *testing.T) {
 				native:     false,
 				whenCnt:    0,
 			},
-			wantStmt: "select name, category, price from fruits where 1 = 1 and category = :v1 and price = :v2 and name = 'Fuji'",
+			wantStmt: "select name, category, price from fruits where category = 'apple' or price = 200 and (type = 40 or type = 60) and yn = 1",
 		},
 		{
 			name: "testWhere",
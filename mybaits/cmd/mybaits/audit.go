@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Breeze0806/go/mybaits"
+)
+
+var errAuditFound = errors.New("risky ${} usage found")
+
+func runAudit(args []string) (err error) {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	output := flags.String("o", "", "output file, default is stdout")
+	databaseID := flags.String("database-id", "", "database id used to choose statement variants")
+	failOn := flags.String("fail-on", "", "exit with 1 when a finding has this risk or higher: high or medium")
+	flags.Parse(args)
+
+	switch mybaits.AuditRisk(*failOn) {
+	case "", mybaits.AuditHigh, mybaits.AuditMedium:
+	default:
+		return fmt.Errorf("fail-on risk(%v) is not supported", *failOn)
+	}
+
+	var opts []mybaits.MapperOption
+	if *databaseID != "" {
+		opts = append(opts, mybaits.WithDatabaseIDProvider(mybaits.StaticDatabaseID(*databaseID)))
+	}
+	mappers, err := loadMappers(flags.Args(), opts...)
+	if err != nil {
+		return err
+	}
+
+	var report mybaits.AuditReport
+	for _, m := range mappers {
+		var r mybaits.AuditReport
+		if r, err = m.Audit(); err != nil {
+			return err
+		}
+		report = append(report, r...)
+	}
+
+	w, closeOutput, err := openOutput(*output)
+	if err != nil {
+		return err
+	}
+	if err = report.Write(w, *format); err != nil {
+		closeOutput()
+		return err
+	}
+	if err = closeOutput(); err != nil {
+		return err
+	}
+
+	for _, f := range report {
+		if mybaits.AuditRisk(*failOn) == mybaits.AuditMedium || (*failOn != "" && f.Risk == mybaits.AuditHigh) {
+			return errAuditFound
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Breeze0806/go/mybaits"
+)
+
+func runCatalog(args []string) (err error) {
+	flags := flag.NewFlagSet("catalog", flag.ExitOnError)
+	format := flags.String("format", "json", "output format: json, csv or markdown")
+	output := flags.String("o", "", "output file, default is stdout")
+	databaseID := flags.String("database-id", "", "database id used to choose statement variants")
+	flags.Parse(args)
+
+	var opts []mybaits.MapperOption
+	if *databaseID != "" {
+		opts = append(opts, mybaits.WithDatabaseIDProvider(mybaits.StaticDatabaseID(*databaseID)))
+	}
+	mappers, err := loadMappers(flags.Args(), opts...)
+	if err != nil {
+		return err
+	}
+
+	var catalog mybaits.Catalog
+	for _, m := range mappers {
+		var c mybaits.Catalog
+		if c, err = m.Catalog(); err != nil {
+			return err
+		}
+		catalog = append(catalog, c...)
+	}
+
+	w, closeOutput, err := openOutput(*output)
+	if err != nil {
+		return err
+	}
+	if err = catalog.Write(w, *format); err != nil {
+		closeOutput()
+		return err
+	}
+	return closeOutput()
+}
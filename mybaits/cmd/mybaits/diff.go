@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Breeze0806/go/mybaits"
+)
+
+var errDiffFound = errors.New("mapper statements differ")
+
+func runDiff(args []string) (err error) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	exitCode := flags.Bool("exit-code", false, "exit with 1 when statements differ")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: mybaits diff [flags] <old> <new>, where <old> and <new> are files or git revisions like HEAD~1:path")
+	}
+
+	var before, after *mybaits.Mapper
+	if before, err = loadRevision(flags.Arg(0)); err != nil {
+		return err
+	}
+	if after, err = loadRevision(flags.Arg(1)); err != nil {
+		return err
+	}
+
+	diff, err := mybaits.DiffMappers(before, after)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "text":
+		err = diff.WriteText(os.Stdout)
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	default:
+		err = fmt.Errorf("diff format(%v) is not supported", *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *exitCode && !diff.Empty() {
+		return errDiffFound
+	}
+	return nil
+}
+
+// loadRevision 加载映射文件，文件不存在并且形如rev:path时通过git show读取
+func loadRevision(name string) (*mybaits.Mapper, error) {
+	if _, err := os.Stat(name); err == nil || !strings.Contains(name, ":") {
+		return mybaits.NewMapper(name)
+	}
+
+	data, err := exec.Command("git", "show", name).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git show %v fail. err: %v", name, err)
+	}
+	return mybaits.NewMapperFromBytes(name, data)
+}
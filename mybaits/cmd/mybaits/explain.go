@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	// 注册postgres和pgTimeout驱动，其他数据库的驱动需要在这里引入
+	_ "github.com/Breeze0806/go/database/pqto"
+	"github.com/Breeze0806/go/mybaits"
+)
+
+var errPlanFlagged = errors.New("plans with sequential scans or high-cost nodes found")
+
+func runExplain(args []string) (err error) {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	driverName := flags.String("driver", "postgres", "database/sql driver name")
+	dsn := flags.String("dsn", "", "data source name of the database")
+	product := flags.String("product", "", "database product name, default is inferred from the driver; PostgreSQL uses EXPLAIN (FORMAT JSON)")
+	cost := flags.Float64("cost", 1000, "cost threshold of high-cost nodes, estimated rows for databases other than PostgreSQL")
+	format := flags.String("format", "text", "output format: text or json")
+	output := flags.String("o", "", "output file, default is stdout")
+	databaseID := flags.String("database-id", "", "database id used to choose statement variants")
+	samples := flags.String("samples", "", "JSON file of parameter sample values")
+	exitCode := flags.Bool("exit-code", false, "exit with 1 when a plan is flagged")
+	flags.Parse(args)
+
+	if *dsn == "" {
+		return fmt.Errorf("no dsn")
+	}
+	var opts []mybaits.MapperOption
+	if *databaseID != "" {
+		opts = append(opts, mybaits.WithDatabaseIDProvider(mybaits.StaticDatabaseID(*databaseID)))
+	}
+	if *samples != "" {
+		var s map[string]interface{}
+		if s, err = mybaits.LoadMockSamples(*samples); err != nil {
+			return err
+		}
+		opts = append(opts, mybaits.WithMockSamples(s))
+	}
+	mappers, err := loadMappers(flags.Args(), opts...)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open(*driverName, *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	explainOpts := []mybaits.ExplainOption{mybaits.WithCostThreshold(*cost)}
+	if *product != "" {
+		explainOpts = append(explainOpts, mybaits.WithExplainProduct(*product))
+	}
+	explainer := mybaits.NewExplainer(db, explainOpts...)
+
+	var report mybaits.ExplainReport
+	for _, m := range mappers {
+		var r mybaits.ExplainReport
+		if r, err = explainer.Explain(context.Background(), m); err != nil {
+			return err
+		}
+		report = append(report, r...)
+	}
+
+	w, closeOutput, err := openOutput(*output)
+	if err != nil {
+		return err
+	}
+	if err = report.Write(w, *format); err != nil {
+		closeOutput()
+		return err
+	}
+	if err = closeOutput(); err != nil {
+		return err
+	}
+
+	if *exitCode {
+		for _, e := range report {
+			if e.Flagged() {
+				return errPlanFlagged
+			}
+		}
+	}
+	return nil
+}
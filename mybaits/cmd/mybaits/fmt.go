@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Breeze0806/go/mybaits"
+)
+
+var errNotFormatted = errors.New("mapper files are not formatted")
+
+func runFmt(args []string) (err error) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list files whose formatting differs and exit with 1")
+	write := flags.Bool("w", false, "write result to the source file instead of stdout")
+	indent := flags.Int("indent", 4, "number of spaces per indentation level")
+	keywordCase := flags.String("case", "upper", "sql keyword case: upper, lower or preserve")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("no mapper path")
+	}
+	opts := []mybaits.FormatOption{mybaits.WithIndent(strings.Repeat(" ", *indent))}
+	switch *keywordCase {
+	case "upper":
+		opts = append(opts, mybaits.WithKeywordCase(mybaits.KeywordUpper))
+	case "lower":
+		opts = append(opts, mybaits.WithKeywordCase(mybaits.KeywordLower))
+	case "preserve":
+		opts = append(opts, mybaits.WithKeywordCase(mybaits.KeywordPreserve))
+	default:
+		return fmt.Errorf("keyword case(%v) is not supported", *keywordCase)
+	}
+
+	unformatted := false
+	for _, path := range flags.Args() {
+		var files []string
+		if files, err = mapperFiles(path); err != nil {
+			return err
+		}
+		for _, file := range files {
+			var data, formatted []byte
+			if data, err = os.ReadFile(file); err != nil {
+				return err
+			}
+			if formatted, err = mybaits.Format(data, opts...); err != nil {
+				if errors.Is(err, mybaits.ErrNotMapper) && file != path {
+					continue
+				}
+				return fmt.Errorf("format %v fail. err: %v", file, err)
+			}
+
+			switch {
+			case *check:
+				if !bytes.Equal(data, formatted) {
+					fmt.Println(file)
+					unformatted = true
+				}
+			case *write:
+				if !bytes.Equal(data, formatted) {
+					if err = os.WriteFile(file, formatted, 0644); err != nil {
+						return err
+					}
+				}
+			default:
+				os.Stdout.Write(formatted)
+			}
+		}
+	}
+	if unformatted {
+		return errNotFormatted
+	}
+	return nil
+}
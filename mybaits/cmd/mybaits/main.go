@@ -0,0 +1,162 @@
+// mybaits 是mybatis映射文件的命令行工具
+//
+//	mybaits <command> [flags] <path>...
+//
+// path可以是映射文件或者目录，目录会递归查找其中所有的映射文件
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Breeze0806/go/mybaits"
+)
+
+// errNotMapper 文件的根元素不是mapper
+var errNotMapper = errors.New("not mybatis mapper")
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"audit": {
+		usage: "report ${} usage with its sql context and injection risk",
+		run:   runAudit,
+	},
+	"catalog": {
+		usage: "export a catalog of every statement",
+		run:   runCatalog,
+	},
+	"diff": {
+		usage: "compare statements between two versions of a mapper",
+		run:   runDiff,
+	},
+	"explain": {
+		usage: "run EXPLAIN for every statement and flag costly plans",
+		run:   runExplain,
+	},
+	"fmt": {
+		usage: "format mapper files and their sql",
+		run:   runFmt,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "mybaits %v: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: mybaits <command> [flags] <path>...")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10v %v\n", name, commands[name].usage)
+	}
+}
+
+// loadMappers 加载paths中的所有映射文件，目录中不是映射文件的xml文件会被忽略
+func loadMappers(paths []string, opts ...mybaits.MapperOption) (mappers []*mybaits.Mapper, err error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no mapper path")
+	}
+	for _, path := range paths {
+		var files []string
+		if files, err = mapperFiles(path); err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			var ok bool
+			if ok, err = isMapperFile(file); err != nil {
+				return nil, fmt.Errorf("load %v fail. err: %v", file, err)
+			}
+			if !ok {
+				if file != path {
+					continue
+				}
+				return nil, fmt.Errorf("load %v fail. err: %v", file, errNotMapper)
+			}
+
+			var m *mybaits.Mapper
+			if m, err = mybaits.NewMapper(file, opts...); err != nil {
+				return nil, fmt.Errorf("load %v fail. err: %v", file, err)
+			}
+			mappers = append(mappers, m)
+		}
+	}
+	return
+}
+
+// isMapperFile 判断文件的根元素是否是mapper
+func isMapperFile(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	decoder := xml.NewDecoder(f)
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local == "mapper", nil
+		}
+	}
+}
+
+func mapperFiles(path string) (files []string, err error) {
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && (p == path || strings.EqualFold(filepath.Ext(p), ".xml")) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return
+}
+
+// openOutput 打开输出文件，filename为空时使用标准输出
+func openOutput(filename string) (*os.File, func() error, error) {
+	if filename == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
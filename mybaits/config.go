@@ -0,0 +1,308 @@
+package mybaits
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// Config mybatis-config.xml中的配置
+type Config struct {
+	Properties         map[string]string
+	Settings           Settings
+	TypeAliases        map[string]string // 别名到类型名的映射
+	TypeAliasPackages  []string
+	Environments       Environments
+	DatabaseIDProvider *VendorDatabaseIDProvider // 未配置databaseIdProvider时为nil
+	Mappers            []string                  // 映射文件路径，相对路径已经转化为相对于配置文件所在目录的路径
+}
+
+// Settings 配置中的settings
+type Settings struct {
+	MapUnderscoreToCamelCase bool
+	DefaultStatementTimeout  int    // 单位秒，0表示未设置
+	JdbcTypeForNull          string // 默认为OTHER
+	Raw                      map[string]string
+}
+
+// Environments 配置中的environments
+type Environments struct {
+	Default      string
+	Environments map[string]*Environment
+}
+
+// Environment 配置中的environment
+type Environment struct {
+	ID                 string
+	TransactionManager string
+	DataSource         DataSource
+}
+
+// DataSource 配置中的dataSource
+type DataSource struct {
+	Type       string
+	Properties map[string]string
+}
+
+// LoadConfig 从文件filename中加载mybatis-config.xml配置
+func LoadConfig(filename string) (config *Config, err error) {
+	doc := etree.NewDocument()
+	if err = doc.ReadFromFile(filename); err != nil {
+		err = fmt.Errorf("ReadFromFile fail. err: %v", err)
+		return
+	}
+
+	root := doc.Root()
+	if root == nil || root.Tag != "configuration" {
+		err = fmt.Errorf("%v is not mybatis configuration", filename)
+		return
+	}
+
+	config = &Config{
+		Properties:  make(map[string]string),
+		TypeAliases: make(map[string]string),
+		Settings: Settings{
+			JdbcTypeForNull: "OTHER",
+			Raw:             make(map[string]string),
+		},
+		Environments: Environments{
+			Environments: make(map[string]*Environment),
+		},
+	}
+	dir := filepath.Dir(filename)
+
+	if e := root.SelectElement("properties"); e != nil {
+		if err = config.loadProperties(dir, e); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range root.ChildElements() {
+		switch e.Tag {
+		case "settings":
+			err = config.loadSettings(e)
+		case "typeAliases":
+			config.loadTypeAliases(e)
+		case "environments":
+			config.loadEnvironments(e)
+		case "databaseIdProvider":
+			config.loadDatabaseIDProvider(e)
+		case "mappers":
+			err = config.loadMappers(dir, e)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// Environment 获取id对应的环境，id为空时获取默认环境
+func (c *Config) Environment(id string) (*Environment, error) {
+	if id == "" {
+		id = c.Environments.Default
+	}
+	env, ok := c.Environments.Environments[id]
+	if !ok {
+		return nil, fmt.Errorf("environment(%v) not found", id)
+	}
+	return env, nil
+}
+
+func (c *Config) loadProperties(dir string, e *etree.Element) error {
+	for _, p := range e.SelectElements("property") {
+		c.Properties[p.SelectAttrValue("name", "")] = p.SelectAttrValue("value", "")
+	}
+
+	resource := e.SelectAttrValue("resource", "")
+	if resource == "" {
+		resource = e.SelectAttrValue("url", "")
+	}
+	if resource == "" {
+		return nil
+	}
+
+	filename, err := resolveResource(dir, resource)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("ReadFile fail. err: %v", err)
+	}
+	// 和mybatis一致，properties文件中的属性覆盖property子元素中的属性
+	for k, v := range parseJavaProperties(data) {
+		c.Properties[k] = v
+	}
+	return nil
+}
+
+func (c *Config) loadSettings(e *etree.Element) (err error) {
+	for _, s := range e.SelectElements("setting") {
+		name := s.SelectAttrValue("name", "")
+		value := c.resolve(s.SelectAttrValue("value", ""))
+		c.Settings.Raw[name] = value
+		switch name {
+		case "mapUnderscoreToCamelCase":
+			if c.Settings.MapUnderscoreToCamelCase, err = strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("setting %v(%v) is not bool. err: %v", name, value, err)
+			}
+		case "defaultStatementTimeout":
+			if c.Settings.DefaultStatementTimeout, err = strconv.Atoi(value); err != nil {
+				return fmt.Errorf("setting %v(%v) is not int. err: %v", name, value, err)
+			}
+		case "jdbcTypeForNull":
+			c.Settings.JdbcTypeForNull = value
+		}
+	}
+	return nil
+}
+
+func (c *Config) loadTypeAliases(e *etree.Element) {
+	for _, a := range e.ChildElements() {
+		switch a.Tag {
+		case "typeAlias":
+			typ := a.SelectAttrValue("type", "")
+			alias := a.SelectAttrValue("alias", "")
+			if alias == "" {
+				alias = typ[strings.LastIndex(typ, ".")+1:]
+			}
+			c.TypeAliases[alias] = typ
+		case "package":
+			c.TypeAliasPackages = append(c.TypeAliasPackages, a.SelectAttrValue("name", ""))
+		}
+	}
+}
+
+func (c *Config) loadEnvironments(e *etree.Element) {
+	c.Environments.Default = c.resolve(e.SelectAttrValue("default", ""))
+	for _, envElem := range e.SelectElements("environment") {
+		env := &Environment{
+			ID: envElem.SelectAttrValue("id", ""),
+			DataSource: DataSource{
+				Properties: make(map[string]string),
+			},
+		}
+		if tm := envElem.SelectElement("transactionManager"); tm != nil {
+			env.TransactionManager = tm.SelectAttrValue("type", "")
+		}
+		if ds := envElem.SelectElement("dataSource"); ds != nil {
+			env.DataSource.Type = ds.SelectAttrValue("type", "")
+			for _, p := range ds.SelectElements("property") {
+				env.DataSource.Properties[p.SelectAttrValue("name", "")] = c.resolve(p.SelectAttrValue("value", ""))
+			}
+		}
+		c.Environments.Environments[env.ID] = env
+	}
+}
+
+func (c *Config) loadDatabaseIDProvider(e *etree.Element) {
+	provider := &VendorDatabaseIDProvider{
+		Properties: make(map[string]string),
+	}
+	for _, p := range e.SelectElements("property") {
+		name := p.SelectAttrValue("name", "")
+		provider.Properties[name] = c.resolve(p.SelectAttrValue("value", ""))
+		provider.Keys = append(provider.Keys, name)
+	}
+	if env, err := c.Environment(""); err == nil {
+		provider.ProductName = jdbcProductName(env.DataSource.Properties["url"])
+	}
+	c.DatabaseIDProvider = provider
+}
+
+func (c *Config) loadMappers(dir string, e *etree.Element) error {
+	for _, m := range e.ChildElements() {
+		if m.Tag == "package" {
+			return fmt.Errorf("mapper package(%v) is not supported", m.SelectAttrValue("name", ""))
+		}
+
+		resource := c.resolve(m.SelectAttrValue("resource", ""))
+		if resource == "" {
+			resource = c.resolve(m.SelectAttrValue("url", ""))
+		}
+		if resource == "" {
+			return fmt.Errorf("mapper class(%v) is not supported", m.SelectAttrValue("class", ""))
+		}
+
+		filename, err := resolveResource(dir, resource)
+		if err != nil {
+			return err
+		}
+		c.Mappers = append(c.Mappers, filename)
+	}
+	return nil
+}
+
+// resolve 使用配置中的属性替换s中的${}，无法解析的占位符保持原样
+func (c *Config) resolve(s string) string {
+	resolved, _ := NewProperties(c.Properties).Resolve(s)
+	return resolved
+}
+
+// resolveResource 将resource或者file:协议的url转化为文件路径，相对路径相对于dir
+func resolveResource(dir, resource string) (string, error) {
+	if strings.HasPrefix(resource, "file:") {
+		u, err := url.Parse(resource)
+		if err != nil {
+			return "", fmt.Errorf("url(%v) is not valid. err: %v", resource, err)
+		}
+		resource = u.Path
+		if resource == "" {
+			resource = u.Opaque
+		}
+	} else if strings.Contains(resource, "://") {
+		return "", fmt.Errorf("url(%v) is not supported", resource)
+	}
+
+	if filepath.IsAbs(resource) {
+		return resource, nil
+	}
+	return filepath.Join(dir, filepath.FromSlash(resource)), nil
+}
+
+// parseJavaProperties 解析java的properties文件，不支持续行
+func parseJavaProperties(data []byte) map[string]string {
+	properties := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			properties[line] = ""
+			continue
+		}
+		properties[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+	return properties
+}
+
+var jdbcProductNames = map[string]string{
+	"mysql":      "MySQL",
+	"mariadb":    "MariaDB",
+	"postgresql": "PostgreSQL",
+	"oracle":     "Oracle",
+	"sqlserver":  "Microsoft SQL Server",
+	"sqlite":     "SQLite",
+	"h2":         "H2",
+	"db2":        "DB2",
+}
+
+// jdbcProductName 通过jdbc的url推断数据库产品名
+func jdbcProductName(jdbcURL string) string {
+	parts := strings.SplitN(jdbcURL, ":", 3)
+	if len(parts) < 3 || parts[0] != "jdbc" {
+		return ""
+	}
+	return jdbcProductNames[strings.ToLower(parts[1])]
+}
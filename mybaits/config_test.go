@@ -0,0 +1,172 @@
+package mybaits
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("testdata/mybatis-config.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantProperties := map[string]string{
+		"driver":   "org.postgresql.Driver",
+		"url":      "jdbc:postgresql://127.0.0.1:5432/fruits",
+		"username": "postgres",
+		"password": "secret",
+		"timeout":  "30",
+	}
+	if !reflect.DeepEqual(config.Properties, wantProperties) {
+		t.Errorf("Properties = %v, want %v", config.Properties, wantProperties)
+	}
+
+	wantSettings := Settings{
+		MapUnderscoreToCamelCase: true,
+		DefaultStatementTimeout:  30,
+		JdbcTypeForNull:          "NULL",
+		Raw: map[string]string{
+			"mapUnderscoreToCamelCase": "true",
+			"defaultStatementTimeout":  "30",
+			"jdbcTypeForNull":          "NULL",
+			"cacheEnabled":             "false",
+		},
+	}
+	if !reflect.DeepEqual(config.Settings, wantSettings) {
+		t.Errorf("Settings = %v, want %v", config.Settings, wantSettings)
+	}
+
+	wantAliases := map[string]string{
+		"Fruit": "com.example.domain.Fruit",
+		"Order": "com.example.domain.Order",
+	}
+	if !reflect.DeepEqual(config.TypeAliases, wantAliases) {
+		t.Errorf("TypeAliases = %v, want %v", config.TypeAliases, wantAliases)
+	}
+	if !reflect.DeepEqual(config.TypeAliasPackages, []string{"com.example.model"}) {
+		t.Errorf("TypeAliasPackages = %v", config.TypeAliasPackages)
+	}
+
+	env, err := config.Environment("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEnv := &Environment{
+		ID:                 "development",
+		TransactionManager: "JDBC",
+		DataSource: DataSource{
+			Type: "POOLED",
+			Properties: map[string]string{
+				"driver":   "org.postgresql.Driver",
+				"url":      "jdbc:postgresql://127.0.0.1:5432/fruits",
+				"username": "postgres",
+				"password": "secret",
+			},
+		},
+	}
+	if !reflect.DeepEqual(env, wantEnv) {
+		t.Errorf("Environment() = %v, want %v", env, wantEnv)
+	}
+	if _, err = config.Environment("test"); err == nil {
+		t.Errorf("Environment(test) error = nil")
+	}
+
+	id, err := config.DatabaseIDProvider.DatabaseID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "postgresql" {
+		t.Errorf("DatabaseID() = %v, want postgresql", id)
+	}
+
+	wantMappers := []string{
+		filepath.Join("testdata", "test.xml"),
+		filepath.Join("testdata", "database_id.xml"),
+	}
+	if !reflect.DeepEqual(config.Mappers, wantMappers) {
+		t.Errorf("Mappers = %v, want %v", config.Mappers, wantMappers)
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	config, err := LoadConfig("testdata/mybatis-config.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRegistry(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Namespaces(); !reflect.DeepEqual(got, []string{"Test", "DatabaseID"}) {
+		t.Errorf("Namespaces() = %v", got)
+	}
+
+	m, err := r.Mapper("DatabaseID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.DatabaseID() != "postgresql" {
+		t.Errorf("DatabaseID() = %v, want postgresql", m.DatabaseID())
+	}
+
+	info, err := r.Statement("DatabaseID.testNow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DatabaseID != "postgresql" {
+		t.Errorf("Statement().DatabaseID = %v, want postgresql", info.DatabaseID)
+	}
+
+	if _, err = r.Statement("Unknown.testNow"); err == nil {
+		t.Errorf("Statement(Unknown.testNow) error = nil")
+	}
+}
+
+func TestNewRegistry_withoutEnvironment(t *testing.T) {
+	config, err := LoadConfig("testdata/mybatis-config-noenv.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRegistry(config)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	m, err := r.Mapper("Properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.DatabaseID() != "" {
+		t.Errorf("DatabaseID() = %v, want empty", m.DatabaseID())
+	}
+	bound, err := m.Bind("testSchema", map[string]interface{}{"status": 1, "region": "eu", "price": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(bound.SQL, "sales.orders") {
+		t.Errorf("Bind() = %v, want config properties applied", bound.SQL)
+	}
+}
+
+func TestRegistry_Session_naming(t *testing.T) {
+	tests := []struct {
+		name   string
+		camel  bool
+		opts   []SessionOption
+		naming NamingStrategy
+	}{
+		{name: "camelCase", camel: true, naming: NamingUnderscoreToCamelCase},
+		{name: "default", camel: false, naming: NamingCaseInsensitive},
+		{name: "override", camel: true, opts: []SessionOption{WithNamingStrategy(NamingCaseInsensitive)}, naming: NamingCaseInsensitive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Registry{config: &Config{Settings: Settings{MapUnderscoreToCamelCase: tt.camel}}}
+			if s := r.Session(nil, tt.opts...); s.naming != tt.naming {
+				t.Errorf("Session().naming = %v, want %v", s.naming, tt.naming)
+			}
+		})
+	}
+}
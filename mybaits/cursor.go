@@ -0,0 +1,300 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Breeze0806/go/log"
+)
+
+// WithDatabaseProduct 设置数据库产品名，默认通过*sql.DB的驱动推断，
+// 产品名为PostgreSQL时游标查询使用服务端游标按照fetchSize分批获取结果
+func WithDatabaseProduct(product string) SessionOption {
+	return func(s *Session) {
+		s.product = product
+	}
+}
+
+// Cursor 逐行读取查询语句的结果，内存占用和结果集的大小无关，用法和sql.Rows一致:
+//
+//	cursor, err := session.SelectCursor(ctx, "selectAll", nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer cursor.Close()
+//	for cursor.Next() {
+//		var v Value
+//		if err := cursor.Scan(&v); err != nil {
+//			return err
+//		}
+//	}
+//	return cursor.Err()
+type Cursor struct {
+	session *Session
+	ctx     context.Context
+	mapper  *Mapper
+	id      string
+	results map[reflect.Type]cursorResult
+	bound   *BoundSQL
+	start   time.Time
+	count   int64 // 已经读取的行数
+
+	fetch     func() (*sql.Rows, error) // 获取下一批结果
+	fetchSize int                       // 大于0时每批最多fetchSize行，不足时说明已经读完
+	release   func() error              // 关闭服务端游标以及游标开启的事务
+	rows      *sql.Rows
+	columns   []string
+	fetched   int
+	row       *row
+
+	err    error
+	closed bool
+}
+
+// cursorResult 目标类型对应的resultMap
+type cursorResult struct {
+	mapper    *Mapper
+	resultMap *ResultMap
+	typ       reflect.Type
+}
+
+var cursorSeq int64
+
+// SelectCursor 执行查询语句id并返回游标，游标读完、出错或者ctx取消时会自动关闭，调用方仍然需要调用Close。
+// 语句设置了fetchSize并且数据库是PostgreSQL时使用服务端游标每次获取fetchSize行，
+// 上下文中没有事务时游标会开启只读事务并在关闭时结束，其他情况下由驱动逐行读取结果，
+// database/sql无法设置其他驱动的fetchSize，此时fetchSize被忽略，每个语句第一次执行时会打印告警日志
+func (s *Session) SelectCursor(ctx context.Context, id string, param interface{}) (*Cursor, error) {
+	m, sid, err := s.lookup(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	info, err := m.Statement(sid)
+	if err != nil {
+		return nil, err
+	}
+	bound, err := m.Bind(sid, param)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cursor{
+		session: s,
+		ctx:     ctx,
+		mapper:  m,
+		id:      sid,
+		results: make(map[reflect.Type]cursorResult),
+		bound:   bound,
+		start:   time.Now(),
+	}
+	exec := executor(ctx, s.exec)
+	if info.FetchSize > 0 {
+		if s.product == "PostgreSQL" {
+			if err = c.declare(exec, bound, info.FetchSize); err != nil {
+				s.observe(m, bound, c.start, -1, err)
+				return nil, err
+			}
+			return c, nil
+		}
+		key := "fetchSize:" + statementKey(m, sid)
+		if _, warned := s.warned.LoadOrStore(key, true); !warned {
+			log.GetLogger().Warnf("fetchSize(%v) of statement(%v) is ignored because database(%v) is not PostgreSQL",
+				info.FetchSize, statementKey(m, sid), s.product)
+		}
+	}
+
+	rows, err := exec.QueryContext(ctx, bound.SQL, bound.Args...)
+	if err != nil {
+		s.observe(m, bound, c.start, -1, err)
+		return nil, fmt.Errorf("query statement(%v) fail. err: %v", id, err)
+	}
+	c.fetch = func() (*sql.Rows, error) {
+		if rows == nil {
+			return nil, nil
+		}
+		r := rows
+		rows = nil
+		return r, nil
+	}
+	return c, nil
+}
+
+// declare 在事务中声明服务端游标，每次通过FETCH获取fetchSize行
+func (c *Cursor) declare(exec Executor, bound *BoundSQL, fetchSize int) (err error) {
+	tx, ok := exec.(*sql.Tx)
+	if !ok {
+		db, ok := exec.(*sql.DB)
+		if !ok {
+			return fmt.Errorf("executor(%T) can not open transaction for cursor", exec)
+		}
+		if tx, err = db.BeginTx(c.ctx, &sql.TxOptions{ReadOnly: true}); err != nil {
+			return fmt.Errorf("begin transaction fail. err: %v", err)
+		}
+		c.release = func() error {
+			if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				return fmt.Errorf("rollback cursor transaction fail. err: %v", err)
+			}
+			return nil
+		}
+	}
+
+	name := "mybaits_cursor_" + strconv.FormatInt(atomic.AddInt64(&cursorSeq, 1), 10)
+	if _, err = tx.ExecContext(c.ctx, "DECLARE "+name+" NO SCROLL CURSOR FOR "+bound.SQL, bound.Args...); err != nil {
+		if c.release != nil {
+			c.release()
+		}
+		return fmt.Errorf("declare cursor for statement(%v) fail. err: %v", bound.ID, err)
+	}
+
+	owned := c.release
+	c.release = func() error {
+		// 上下文取消后事务已经回滚，不需要关闭游标
+		if c.ctx.Err() == nil {
+			if _, err := tx.ExecContext(c.ctx, "CLOSE "+name); err != nil && owned == nil {
+				return fmt.Errorf("close cursor fail. err: %v", err)
+			}
+		}
+		if owned != nil {
+			return owned()
+		}
+		return nil
+	}
+	c.fetchSize = fetchSize
+	c.fetch = func() (*sql.Rows, error) {
+		return tx.QueryContext(c.ctx, "FETCH FORWARD "+strconv.Itoa(fetchSize)+" FROM "+name)
+	}
+	return nil
+}
+
+// Next 读取下一行，没有更多的行或者出错时返回false并关闭游标
+func (c *Cursor) Next() bool {
+	if c.closed {
+		return false
+	}
+	if err := c.ctx.Err(); err != nil {
+		c.fail(err)
+		return false
+	}
+
+	for {
+		if c.rows == nil {
+			rows, err := c.fetch()
+			if err != nil {
+				c.fail(fmt.Errorf("fetch statement(%v) fail. err: %v", c.id, err))
+				return false
+			}
+			if rows == nil {
+				c.Close()
+				return false
+			}
+			if c.columns, err = rows.Columns(); err != nil {
+				rows.Close()
+				c.fail(err)
+				return false
+			}
+			c.rows, c.fetched = rows, 0
+		}
+
+		if c.rows.Next() {
+			r, err := scanRow(c.rows, c.columns)
+			if err != nil {
+				c.fail(err)
+				return false
+			}
+			c.row = r
+			c.fetched++
+			c.count++
+			return true
+		}
+
+		err := c.rows.Err()
+		c.rows.Close()
+		c.rows = nil
+		if err != nil {
+			c.fail(err)
+			return false
+		}
+		if c.fetchSize == 0 || c.fetched < c.fetchSize {
+			c.Close()
+			return false
+		}
+	}
+}
+
+// Scan 按照语句的resultMap或者resultType将当前行映射到dest并执行嵌套查询，dest是指针
+func (c *Cursor) Scan(dest interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("dest(%T) is not pointer", dest)
+	}
+	if c.row == nil {
+		return fmt.Errorf("Scan called without calling Next")
+	}
+
+	result, ok := c.results[dv.Elem().Type()]
+	if !ok {
+		var err error
+		if result.mapper, result.resultMap, result.typ, err = c.session.resultOf(c.mapper, c.id, dv.Elem().Type()); err != nil {
+			return err
+		}
+		c.results[dv.Elem().Type()] = result
+	}
+
+	rowMapper := &rowMapper{session: c.session, mapper: result.mapper, row: c.row}
+	v, err := rowMapper.value(result.typ, result.resultMap)
+	if err != nil {
+		return fmt.Errorf("map statement(%v) fail. err: %v", c.id, err)
+	}
+	if err = c.session.loadNested(c.ctx, rowMapper.pending, 1); err != nil {
+		return err
+	}
+	dv.Elem().Set(v)
+	return nil
+}
+
+// Err 获取读取过程中的错误，ctx取消时返回ctx的错误
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Close 关闭结果集、服务端游标以及游标开启的事务，可以多次调用，
+// 设置了SQL日志时在第一次关闭时打印读取的行数和从执行开始的耗时
+func (c *Cursor) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.row = nil
+
+	var err error
+	if c.rows != nil {
+		err = c.rows.Close()
+		c.rows = nil
+	}
+	// 逐行读取时还没有取出的结果集也需要关闭
+	if c.fetchSize == 0 {
+		if rows, _ := c.fetch(); rows != nil {
+			rows.Close()
+		}
+	}
+	if c.release != nil {
+		if rerr := c.release(); err == nil {
+			err = rerr
+		}
+	}
+	c.session.observe(c.mapper, c.bound, c.start, c.count, c.err)
+	return err
+}
+
+func (c *Cursor) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+	c.Close()
+}
@@ -0,0 +1,196 @@
+package mybaits
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Breeze0806/go/log"
+)
+
+// cursorDriver 模拟author表，支持直接查询和通过FETCH分批查询，记录返回的结果集
+type cursorDriver struct {
+	*fakeDriver
+	rows []*fakeRows
+	pos  int
+}
+
+func newCursorDriver() *cursorDriver {
+	authors := [][]driver.Value{{int64(1), "alice"}, {int64(2), "bob"}, {int64(3), "carol"}}
+	d := &cursorDriver{}
+	d.fakeDriver = &fakeDriver{
+		query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+			rows := &fakeRows{columns: []string{"id", "user_name"}}
+			if strings.HasPrefix(query, "FETCH FORWARD 2") {
+				end := d.pos + 2
+				if end > len(authors) {
+					end = len(authors)
+				}
+				rows.values = authors[d.pos:end]
+				d.pos = end
+			} else {
+				rows.values = authors
+			}
+			d.rows = append(d.rows, rows)
+			return rows, nil
+		},
+	}
+	return d
+}
+
+func (d *cursorDriver) allClosed() bool {
+	for _, r := range d.rows {
+		if !r.closed {
+			return false
+		}
+	}
+	return len(d.rows) > 0
+}
+
+func newCursorSession(t *testing.T, opts ...SessionOption) (*Session, *cursorDriver) {
+	m, err := NewMapper("testdata/session.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newCursorDriver()
+	return NewSession(newFakeDB(d.fakeDriver), []*Mapper{m}, opts...), d
+}
+
+var cursorNameRegex = regexp.MustCompile(`mybaits_cursor_\d+`)
+
+func TestSession_SelectCursor(t *testing.T) {
+	authors := []*testAuthor{{ID: 1, UserName: "alice"}, {ID: 2, UserName: "bob"}, {ID: 3, UserName: "carol"}}
+	tests := []struct {
+		name      string
+		opts      []SessionOption
+		wantCalls []string
+	}{
+		{
+			name:      "stream",
+			wantCalls: []string{"select id, user_name from author where id > ? order by id"},
+		},
+		{
+			name: "fetchSize",
+			opts: []SessionOption{WithDatabaseProduct("PostgreSQL")},
+			wantCalls: []string{
+				"BEGIN READ ONLY",
+				"DECLARE mybaits_cursor NO SCROLL CURSOR FOR select id, user_name from author where id > ? order by id",
+				"FETCH FORWARD 2 FROM mybaits_cursor",
+				"FETCH FORWARD 2 FROM mybaits_cursor",
+				"CLOSE mybaits_cursor",
+				"ROLLBACK",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, d := newCursorSession(t, tt.opts...)
+			cursor, err := s.SelectCursor(context.Background(), "exportAuthors", map[string]interface{}{"minID": 0})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cursor.Close()
+
+			var got []*testAuthor
+			for cursor.Next() {
+				var a *testAuthor
+				if err = cursor.Scan(&a); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, a)
+			}
+			if err = cursor.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, authors) {
+				t.Errorf("got %+v, want %+v", got, authors)
+			}
+			if !d.allClosed() {
+				t.Errorf("rows are not closed")
+			}
+
+			var calls []string
+			for _, c := range d.recorded() {
+				calls = append(calls, cursorNameRegex.ReplaceAllString(c.Query, "mybaits_cursor"))
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %q, want %q", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSession_SelectCursor_ignoredFetchSize(t *testing.T) {
+	buf := &bytes.Buffer{}
+	old := log.GetLogger()
+	log.SetLogger(log.NewDefaultLogger(buf, log.WarnLevel, ""))
+	defer log.SetLogger(old)
+
+	s, _ := newCursorSession(t, WithDatabaseProduct("MySQL"))
+	for i := 0; i < 2; i++ {
+		cursor, err := s.SelectCursor(context.Background(), "exportAuthors", map[string]interface{}{"minID": 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cursor.Close()
+	}
+	want := "fetchSize(2) of statement(Session.exportAuthors) is ignored because database(MySQL) is not PostgreSQL"
+	if got := strings.Count(buf.String(), want); got != 1 {
+		t.Errorf("warnings = %v, want 1 in %q", got, buf.String())
+	}
+}
+
+func TestCursor_Close(t *testing.T) {
+	for _, product := range []string{"", "PostgreSQL"} {
+		t.Run("early"+product, func(t *testing.T) {
+			s, d := newCursorSession(t, WithDatabaseProduct(product))
+			cursor, err := s.SelectCursor(context.Background(), "exportAuthors", map[string]interface{}{"minID": 0})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cursor.Next() {
+				t.Fatalf("Next() = false, err = %v", cursor.Err())
+			}
+			if err = cursor.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err = cursor.Close(); err != nil {
+				t.Errorf("second Close() error = %v", err)
+			}
+			if cursor.Next() || !d.allClosed() {
+				t.Errorf("cursor is not closed")
+			}
+			var a testAuthor
+			if err = cursor.Scan(&a); err == nil {
+				t.Errorf("Scan() after Close error = nil")
+			}
+		})
+
+		t.Run("cancel"+product, func(t *testing.T) {
+			s, d := newCursorSession(t, WithDatabaseProduct(product))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cursor, err := s.SelectCursor(ctx, "exportAuthors", map[string]interface{}{"minID": 0})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cursor.Next() {
+				t.Fatalf("Next() = false, err = %v", cursor.Err())
+			}
+			cancel()
+			if cursor.Next() {
+				t.Errorf("Next() after cancel = true")
+			}
+			if cursor.Err() != context.Canceled {
+				t.Errorf("Err() = %v, want %v", cursor.Err(), context.Canceled)
+			}
+			if !d.allClosed() {
+				t.Errorf("rows are not closed")
+			}
+		})
+	}
+}
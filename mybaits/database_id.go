@@ -0,0 +1,125 @@
+package mybaits
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DatabaseIDProvider 提供当前使用数据库的标识，用于选择databaseId对应的语句
+type DatabaseIDProvider interface {
+	DatabaseID() (string, error)
+}
+
+// StaticDatabaseID 固定的数据库标识
+type StaticDatabaseID string
+
+// DatabaseID 获取数据库标识
+func (s StaticDatabaseID) DatabaseID() (string, error) {
+	return string(s), nil
+}
+
+// VendorDatabaseIDProvider 和mybatis的VENDOR一致，通过数据库产品名获取数据库标识,
+// 如果Properties为空，数据库标识就是产品名，否则按照Keys的顺序返回第一个包含在产品名中的键对应的值
+type VendorDatabaseIDProvider struct {
+	ProductName string
+	Properties  map[string]string
+	Keys        []string // Properties中键的声明顺序，不在其中的键按照字典序排在后面
+}
+
+// VendorProperty 产品名中包含的关键字Name以及对应的数据库标识Value，和mybatis中databaseIdProvider的property一致
+type VendorProperty struct {
+	Name  string
+	Value string
+}
+
+// NewVendorDatabaseIDProvider 通过db的驱动推断数据库产品名，生成VendorDatabaseIDProvider，
+// 多个properties都包含在产品名中时使用靠前的
+func NewVendorDatabaseIDProvider(db *sql.DB, properties ...VendorProperty) *VendorDatabaseIDProvider {
+	v := &VendorDatabaseIDProvider{
+		ProductName: productName(db),
+		Properties:  make(map[string]string),
+	}
+	for _, p := range properties {
+		v.Properties[p.Name] = p.Value
+		v.Keys = append(v.Keys, p.Name)
+	}
+	return v
+}
+
+// DatabaseID 获取数据库标识
+func (v *VendorDatabaseIDProvider) DatabaseID() (string, error) {
+	if v.ProductName == "" {
+		return "", fmt.Errorf("database product name is empty")
+	}
+	if len(v.Properties) == 0 {
+		return v.ProductName, nil
+	}
+	for _, k := range v.orderedKeys() {
+		if strings.Contains(v.ProductName, k) {
+			return v.Properties[k], nil
+		}
+	}
+	return "", nil
+}
+
+// orderedKeys 按照声明顺序获取Properties的键，保证多个键都匹配时结果是确定的
+func (v *VendorDatabaseIDProvider) orderedKeys() []string {
+	seen := make(map[string]bool)
+	var keys, rest []string
+	for _, k := range v.Keys {
+		if _, ok := v.Properties[k]; ok && !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for k := range v.Properties {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+var driverProductNames = []struct {
+	keyword string
+	product string
+}{
+	{"pq", "PostgreSQL"},
+	{"pgx", "PostgreSQL"},
+	{"mysql", "MySQL"},
+	{"sqlite", "SQLite"},
+	{"mssql", "Microsoft SQL Server"},
+	{"godror", "Oracle"},
+	{"oci8", "Oracle"},
+}
+
+// productName 通过驱动类型名推断数据库产品名
+func productName(db *sql.DB) string {
+	driverName := strings.ToLower(fmt.Sprintf("%T", db.Driver()))
+	driverName = strings.TrimLeft(driverName, "*")
+	if i := strings.Index(driverName, "."); i >= 0 {
+		driverName = driverName[:i]
+	}
+	for _, v := range driverProductNames {
+		if strings.Contains(driverName, v.keyword) {
+			return v.product
+		}
+	}
+	return ""
+}
+
+// evalDatabaseIDTest 在加载映射文件时计算test表达式，只有表达式的值只取决于_databaseId时ok为true，
+// 访问了其他参数时ok为false，留到绑定参数时计算
+func evalDatabaseIDTest(test, databaseID string) (matched bool, ok bool) {
+	if !strings.Contains(test, "_databaseId") {
+		return false, false
+	}
+	v, err := evalExpression(test, &scope{databaseID: databaseID, databaseIDOnly: true})
+	if err != nil {
+		return false, false
+	}
+	return truthy(v), true
+}
@@ -0,0 +1,213 @@
+package mybaits
+
+import (
+	"testing"
+)
+
+func TestNewMapper_databaseID(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider DatabaseIDProvider
+		id       string
+		wantStmt string
+	}{
+		{
+			name:     "postgresql",
+			provider: StaticDatabaseID("postgresql"),
+			id:       "testNow",
+			wantStmt: "select now() from dual",
+		},
+		{
+			name:     "mysql",
+			provider: StaticDatabaseID("mysql"),
+			id:       "testNow",
+			wantStmt: "select current_timestamp() from dual",
+		},
+		{
+			name:     "fallback",
+			provider: StaticDatabaseID("oracle"),
+			id:       "testNow",
+			wantStmt: "select sysdate() from dual",
+		},
+		{
+			name:     "none",
+			id:       "testNow",
+			wantStmt: "select sysdate() from dual",
+		},
+		{
+			name: "vendor",
+			provider: &VendorDatabaseIDProvider{
+				ProductName: "MySQL",
+				Properties: map[string]string{
+					"MySQL":      "mysql",
+					"PostgreSQL": "postgresql",
+				},
+			},
+			id:       "testNow",
+			wantStmt: "select current_timestamp() from dual",
+		},
+		{
+			name:     "ifMysql",
+			provider: StaticDatabaseID("mysql"),
+			id:       "testIf",
+			wantStmt: "select name from fruits where 1 = 1 and category = 'mysql'",
+		},
+		{
+			name:     "ifOracle",
+			provider: StaticDatabaseID("oracle"),
+			id:       "testIf",
+			wantStmt: "select name from fruits where 1 = 1",
+		},
+		{
+			name:     "ifPostgresql",
+			provider: StaticDatabaseID("postgresql"),
+			id:       "testIf",
+			wantStmt: "select name from fruits where 1 = 1 and category = 'other'",
+		},
+		{
+			name:     "choosePostgresql",
+			provider: StaticDatabaseID("postgresql"),
+			id:       "testChoose",
+			wantStmt: "select name from fruits where lower(name) = lower(:v1)",
+		},
+		{
+			name:     "chooseMysql",
+			provider: StaticDatabaseID("mysql"),
+			id:       "testChoose",
+			wantStmt: "select name from fruits where name like :v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []MapperOption
+			if tt.provider != nil {
+				opts = append(opts, WithDatabaseIDProvider(tt.provider))
+			}
+			m, err := NewMapper("testdata/database_id.xml", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cm := &childMapper{
+				root:       m.root,
+				child:      m.root[tt.id],
+				databaseID: m.DatabaseID(),
+			}
+			gotStmt, err := cm.getStatement()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotStmt != tt.wantStmt {
+				t.Errorf("childMapper.getStatement() = %v, want %v", gotStmt, tt.wantStmt)
+			}
+		})
+	}
+}
+
+func Test_evalDatabaseIDTest(t *testing.T) {
+	tests := []struct {
+		test        string
+		databaseID  string
+		wantMatched bool
+		wantOk      bool
+	}{
+		{test: "_databaseId == 'mysql'", databaseID: "mysql", wantMatched: true, wantOk: true},
+		{test: `_databaseId eq "mysql"`, databaseID: "oracle", wantMatched: false, wantOk: true},
+		{test: "_databaseId == 'mysql' or _databaseId == 'oracle'", databaseID: "oracle", wantMatched: true, wantOk: true},
+		{test: "_databaseId != 'mysql' && _databaseId != 'oracle'", databaseID: "oracle", wantMatched: false, wantOk: true},
+		{test: "_databaseId == 'mysql' and name != null", databaseID: "mysql", wantOk: false},
+		{test: "name != null", databaseID: "mysql", wantOk: false},
+		{test: "_databaseId != 'mysql' and name != null", databaseID: "mysql", wantMatched: false, wantOk: true},
+		{test: "(_databaseId == 'oracle' or _databaseId == 'mysql') and !(_databaseId == 'db2')", databaseID: "mysql", wantMatched: true, wantOk: true},
+		{test: "_databaseId == 'x and y'", databaseID: "x and y", wantMatched: true, wantOk: true},
+		{test: "_databaseId.toLowerCase() == 'mysql'", databaseID: "MySQL", wantMatched: true, wantOk: true},
+		{test: "_databaseId == 'mysql' and", databaseID: "mysql", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.test, func(t *testing.T) {
+			gotMatched, gotOk := evalDatabaseIDTest(tt.test, tt.databaseID)
+			if gotMatched != tt.wantMatched || gotOk != tt.wantOk {
+				t.Errorf("evalDatabaseIDTest() = %v, %v, want %v, %v", gotMatched, gotOk, tt.wantMatched, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestVendorDatabaseIDProvider_DatabaseID(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *VendorDatabaseIDProvider
+		want     string
+	}{
+		{
+			name:     "productName",
+			provider: &VendorDatabaseIDProvider{ProductName: "MySQL"},
+			want:     "MySQL",
+		},
+		{
+			name: "declarationOrder",
+			provider: &VendorDatabaseIDProvider{
+				ProductName: "Microsoft SQL Server",
+				Properties:  map[string]string{"SQL Server": "sqlserver", "SQL": "sql"},
+				Keys:        []string{"SQL Server", "SQL"},
+			},
+			want: "sqlserver",
+		},
+		{
+			name: "sortedWithoutKeys",
+			provider: &VendorDatabaseIDProvider{
+				ProductName: "Microsoft SQL Server",
+				Properties:  map[string]string{"SQL Server": "sqlserver", "SQL": "sql"},
+			},
+			want: "sql",
+		},
+		{
+			name: "noMatch",
+			provider: &VendorDatabaseIDProvider{
+				ProductName: "Oracle",
+				Properties:  map[string]string{"MySQL": "mysql"},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				got, err := tt.provider.DatabaseID()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Fatalf("DatabaseID() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewVendorDatabaseIDProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties []VendorProperty
+		want       string
+	}{
+		{
+			name:       "first",
+			properties: []VendorProperty{{"SQL Server", "sqlserver"}, {"SQL", "sql"}},
+			want:       "sqlserver",
+		},
+		{
+			name:       "reversed",
+			properties: []VendorProperty{{"SQL", "sql"}, {"SQL Server", "sqlserver"}},
+			want:       "sql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewVendorDatabaseIDProvider(newFakeDB(&fakeDriver{}), tt.properties...)
+			provider.ProductName = "Microsoft SQL Server"
+			if got, err := provider.DatabaseID(); err != nil || got != tt.want {
+				t.Errorf("DatabaseID() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package mybaits
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// MapperDiff 两个版本映射文件之间语句的差异
+type MapperDiff struct {
+	OldNamespace string          `json:"oldNamespace"`
+	NewNamespace string          `json:"newNamespace"`
+	Added        []string        `json:"added"`
+	Removed      []string        `json:"removed"`
+	Modified     []StatementDiff `json:"modified"`
+}
+
+// StatementDiff 语句的差异，SQL使用规范化后的SQL比较，不受空白字符的影响
+type StatementDiff struct {
+	ID            string  `json:"id"`
+	OldKind       string  `json:"oldKind"`
+	NewKind       string  `json:"newKind"`
+	OldSQL        string  `json:"oldSQL"`
+	NewSQL        string  `json:"newSQL"`
+	SQLChanged    bool    `json:"sqlChanged"`
+	AddedParams   []Param `json:"addedParams"`
+	RemovedParams []Param `json:"removedParams"`
+}
+
+// Empty 判断是否有差异
+func (d *MapperDiff) Empty() bool {
+	return d.OldNamespace == d.NewNamespace && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// DiffMappers 比较旧版本before和新版本after中所有的语句(不包括sql片段)，
+// 语句按照ID对应，sql片段的修改会体现在引用它的语句中
+func DiffMappers(before, after *Mapper) (diff *MapperDiff, err error) {
+	diff = &MapperDiff{
+		OldNamespace: before.namespace,
+		NewNamespace: after.namespace,
+		Added:        []string{},
+		Removed:      []string{},
+		Modified:     []StatementDiff{},
+	}
+
+	for _, id := range before.ids {
+		if before.root[id].Tag == "sql" {
+			continue
+		}
+		if _, ok := after.root[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	for _, id := range after.ids {
+		if after.root[id].Tag == "sql" {
+			continue
+		}
+		if _, ok := before.root[id]; !ok {
+			diff.Added = append(diff.Added, id)
+			continue
+		}
+
+		var sd StatementDiff
+		var changed bool
+		if sd, changed, err = diffStatement(before, after, id); err != nil {
+			return nil, err
+		}
+		if changed {
+			diff.Modified = append(diff.Modified, sd)
+		}
+	}
+	return
+}
+
+func diffStatement(before, after *Mapper, id string) (sd StatementDiff, changed bool, err error) {
+	var oldInfo, newInfo *StatementInfo
+	if oldInfo, err = before.Statement(id); err != nil {
+		return
+	}
+	if newInfo, err = after.Statement(id); err != nil {
+		return
+	}
+
+	sd = StatementDiff{
+		ID:            id,
+		OldKind:       oldInfo.Kind,
+		NewKind:       newInfo.Kind,
+		OldSQL:        normalizedSQL(before, id),
+		NewSQL:        normalizedSQL(after, id),
+		AddedParams:   diffParams(newInfo.Params, oldInfo.Params),
+		RemovedParams: diffParams(oldInfo.Params, newInfo.Params),
+	}
+	sd.SQLChanged = sd.OldSQL != sd.NewSQL
+	changed = sd.SQLChanged || sd.OldKind != sd.NewKind ||
+		len(sd.AddedParams) != 0 || len(sd.RemovedParams) != 0
+	return
+}
+
+var whitespaceRegex = regexp.MustCompile(`\s+`)
+
+// normalizedSQL 获取规范化后的SQL，无法规范化时使用合并空白字符后的SQL
+func normalizedSQL(m *Mapper, id string) string {
+	cm := m.newChildMapper(m.root[id])
+	if stmt, err := cm.getStatement(); err == nil {
+		return stmt
+	}
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(cm.getRawStatement(), " "))
+}
+
+// diffParams 获取在a中但不在b中的参数，#{}和${}视为不同的参数，参数的选项不影响比较
+func diffParams(a, b []Param) []Param {
+	inB := make(map[string]bool)
+	for _, p := range b {
+		inB[p.FullName[:1]+p.Name] = true
+	}
+	diff := []Param{}
+	for _, p := range a {
+		if !inB[p.FullName[:1]+p.Name] {
+			diff = append(diff, p)
+		}
+	}
+	return diff
+}
+
+// WriteText 以文本格式输出差异，+表示新增，-表示删除，~表示修改
+func (d *MapperDiff) WriteText(w io.Writer) error {
+	b := &strings.Builder{}
+	if d.OldNamespace != d.NewNamespace {
+		fmt.Fprintf(b, "namespace: %v -> %v\n", d.OldNamespace, d.NewNamespace)
+	}
+	for _, id := range d.Added {
+		fmt.Fprintf(b, "+ %v\n", id)
+	}
+	for _, id := range d.Removed {
+		fmt.Fprintf(b, "- %v\n", id)
+	}
+	for _, sd := range d.Modified {
+		fmt.Fprintf(b, "~ %v\n", sd.ID)
+		if sd.OldKind != sd.NewKind {
+			fmt.Fprintf(b, "    kind: %v -> %v\n", sd.OldKind, sd.NewKind)
+		}
+		if sd.SQLChanged {
+			fmt.Fprintf(b, "    - %v\n", sd.OldSQL)
+			fmt.Fprintf(b, "    + %v\n", sd.NewSQL)
+		}
+		for _, p := range sd.AddedParams {
+			fmt.Fprintf(b, "    + param %v\n", p.FullName)
+		}
+		for _, p := range sd.RemovedParams {
+			fmt.Fprintf(b, "    - param %v\n", p.FullName)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
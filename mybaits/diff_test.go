@@ -0,0 +1,75 @@
+package mybaits
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDiffMappers(t *testing.T) {
+	before, err := NewMapper("testdata/diff_old.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := NewMapper("testdata/diff_new.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DiffMappers(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &MapperDiff{
+		OldNamespace: "Diff",
+		NewNamespace: "Diff",
+		Added:        []string{"countOrders"},
+		Removed:      []string{"deleteOrder"},
+		Modified: []StatementDiff{
+			{
+				ID:            "selectByStatus",
+				OldKind:       "select",
+				NewKind:       "select",
+				OldSQL:        "select id, name from orders where name = :v1",
+				NewSQL:        "select id, name from orders where name = :v1 and status = :v2",
+				SQLChanged:    true,
+				AddedParams:   []Param{{FullName: "#{status}", Name: "status", MockValue: "?"}},
+				RemovedParams: []Param{},
+			},
+			{
+				ID:            "selectSorted",
+				OldKind:       "select",
+				NewKind:       "select",
+				OldSQL:        "select id, name from orders where name = :v1",
+				NewSQL:        "select id, name from orders where name = :v1",
+				AddedParams:   []Param{{FullName: "#{name}", Name: "name", MockValue: "?"}},
+				RemovedParams: []Param{{FullName: "${name}", Name: "name", MockValue: "?"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffMappers() = %+v, want %+v", got, want)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = got.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	wantText := `+ countOrders
+- deleteOrder
+~ selectByStatus
+    - select id, name from orders where name = :v1
+    + select id, name from orders where name = :v1 and status = :v2
+    + param #{status}
+~ selectSorted
+    + param #{name}
+    - param ${name}
+`
+	if buf.String() != wantText {
+		t.Errorf("MapperDiff.WriteText() = %v, want %v", buf.String(), wantText)
+	}
+
+	if same, _ := DiffMappers(before, before); !same.Empty() {
+		t.Errorf("DiffMappers(before, before) = %+v, want empty", same)
+	}
+}
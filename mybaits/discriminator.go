@@ -0,0 +1,150 @@
+package mybaits
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithType 注册resultMap的type或者case的resultType对应的Go类型，v是该类型的值，如&Car{}，
+// 映射到接口类型时会使用注册的类型
+func WithType(name string, v interface{}) SessionOption {
+	return func(s *Session) {
+		s.types[name] = reflect.TypeOf(v)
+	}
+}
+
+// WithDiscriminatorFallback 设置discriminator的值没有对应的case时是否和mybatis一致使用当前的resultMap，
+// 默认返回错误
+func WithDiscriminatorFallback(enabled bool) SessionOption {
+	return func(s *Session) {
+		s.discriminatorFallback = enabled
+	}
+}
+
+// discriminate 按照discriminator选择当前行使用的resultMap，返回选中的resultMap以及它所在的映射文件，
+// prefix是association的columnPrefix，case中没有对应的值时返回错误，开启WithDiscriminatorFallback时使用当前的resultMap
+func (rm *rowMapper) discriminate(m *Mapper, resultMap *ResultMap, prefix string) (*Mapper, *ResultMap, error) {
+	seen := make(map[string]bool)
+	for resultMap != nil && resultMap.Discriminator != nil {
+		d := resultMap.Discriminator
+		key := m.namespace + "." + resultMap.ID
+		if seen[key] {
+			return nil, nil, fmt.Errorf("discriminator of resultMap(%v) selects itself", resultMap.ID)
+		}
+		seen[key] = true
+
+		v, ok := rm.row.get(prefix + d.Column)
+		if !ok {
+			return nil, nil, fmt.Errorf("discriminator column(%v) of resultMap(%v) not found", prefix+d.Column, resultMap.ID)
+		}
+		value, err := discriminatorValue(v, d.JavaType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("discriminator column(%v) of resultMap(%v) %v", prefix+d.Column, resultMap.ID, err)
+		}
+
+		var selected *DiscriminatorCase
+		for i := range d.Cases {
+			if d.Cases[i].Value == value {
+				selected = &d.Cases[i]
+				break
+			}
+		}
+		if selected == nil {
+			if rm.session.discriminatorFallback {
+				break
+			}
+			return nil, nil, fmt.Errorf("discriminator column(%v) value(%v) is not mapped by any case of resultMap(%v)",
+				prefix+d.Column, value, resultMap.ID)
+		}
+
+		if selected.ResultMap != "" {
+			if m, resultMap, err = rm.session.lookupResultMap(selected.ResultMap, m); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		// 内联的case包含外层resultMap的映射
+		merged := *resultMap
+		merged.ID = resultMap.ID + "-" + selected.Value
+		if selected.ResultType != "" {
+			merged.Type = selected.ResultType
+		}
+		merged.Results = append(append([]ResultMapping(nil), resultMap.Results...), selected.Results...)
+		merged.Nested = append(append([]NestedMapping(nil), resultMap.Nested...), selected.Nested...)
+		merged.Discriminator = nil
+		resultMap = &merged
+	}
+	return m, resultMap, nil
+}
+
+// discriminatorValue 和mybatis一致，按照javaType转换列值v后转化为字符串，再和case的value比较，
+// 如DECIMAL的1.00在javaType为int时为1，NULL为null，未设置javaType或者无法识别时使用列值的文本
+func discriminatorValue(v interface{}, javaType string) (string, error) {
+	if v == nil {
+		return "null", nil
+	}
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+
+	var field reflect.Value
+	switch strings.TrimPrefix(javaType, "java.lang.") {
+	case "int", "Integer", "long", "Long", "short", "Short", "byte", "Byte":
+		var i int64
+		field = reflect.ValueOf(&i).Elem()
+	case "double", "Double", "float", "Float":
+		var f float64
+		field = reflect.ValueOf(&f).Elem()
+	case "boolean", "Boolean":
+		var b bool
+		field = reflect.ValueOf(&b).Elem()
+	default:
+		return fmt.Sprint(v), nil
+	}
+
+	if s, ok := v.(string); ok && field.Kind() == reflect.Int64 {
+		// 文本协议返回的DECIMAL，如1.00，和java的Number.intValue一样截断小数
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return "", err
+		}
+		v = f
+	}
+	if f, ok := v.(float64); ok && field.Kind() == reflect.Int64 {
+		v = int64(f)
+	}
+	if err := assignColumn(field, v); err != nil {
+		return "", err
+	}
+	if field.Kind() == reflect.Float64 {
+		return javaDouble(field.Float()), nil
+	}
+	return fmt.Sprint(field.Interface()), nil
+}
+
+// javaDouble 和java的Double.toString一致，整数值保留一位小数，如1.0
+func javaDouble(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.ContainsAny(s, ".NI") {
+		s += ".0"
+	}
+	return s
+}
+
+// resultType 获取resultMap映射的Go类型，type注册了Go类型时使用注册的类型，否则使用typ
+func (s *Session) resultType(resultMap *ResultMap, typ reflect.Type) (reflect.Type, error) {
+	registered, ok := s.types[resultMap.Type]
+	if !ok {
+		if typ.Kind() == reflect.Interface {
+			return nil, fmt.Errorf("type(%v) of resultMap(%v) is not registered", resultMap.Type, resultMap.ID)
+		}
+		return typ, nil
+	}
+	if !registered.AssignableTo(typ) {
+		return nil, fmt.Errorf("type(%v) of resultMap(%v) is %v which can not be assigned to %v",
+			resultMap.Type, resultMap.ID, registered, typ)
+	}
+	return registered, nil
+}
@@ -0,0 +1,212 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testVehicle interface {
+	vehicleID() int64
+}
+
+type testCar struct {
+	ID    int64
+	Doors int
+}
+
+type testTruck struct {
+	ID      int64
+	Payload float64
+}
+
+func (c *testCar) vehicleID() int64  { return c.ID }
+func (t testTruck) vehicleID() int64 { return t.ID }
+
+func TestSession_discriminator(t *testing.T) {
+	m, err := NewMapper("testdata/discriminator.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := []SessionOption{WithType("Car", &testCar{}), WithType("Truck", testTruck{})}
+
+	tests := []struct {
+		name    string
+		opts    []SessionOption
+		values  [][]driver.Value
+		want    []testVehicle
+		wantErr string
+	}{
+		{
+			name: "cases",
+			opts: types,
+			values: [][]driver.Value{
+				{int64(1), int64(1), int64(4), nil},
+				{int64(2), []byte("2"), nil, 1.5},
+			},
+			want: []testVehicle{&testCar{ID: 1, Doors: 4}, testTruck{ID: 2, Payload: 1.5}},
+		},
+		{
+			name: "decimal",
+			opts: types,
+			values: [][]driver.Value{
+				{int64(1), []byte("1.00"), int64(4), nil},
+				{int64(2), 2.0, nil, 1.5},
+			},
+			want: []testVehicle{&testCar{ID: 1, Doors: 4}, testTruck{ID: 2, Payload: 1.5}},
+		},
+		{
+			name:    "unmapped",
+			opts:    types,
+			values:  [][]driver.Value{{int64(3), int64(3), nil, nil}},
+			wantErr: "discriminator column(vehicle_type) value(3) is not mapped by any case of resultMap(vehicleResult)",
+		},
+		{
+			name:    "unmappedNull",
+			opts:    types,
+			values:  [][]driver.Value{{int64(4), nil, nil, nil}},
+			wantErr: "discriminator column(vehicle_type) value(null) is not mapped by any case of resultMap(vehicleResult)",
+		},
+		{
+			name:   "unmappedFallback",
+			opts:   append([]SessionOption{WithDiscriminatorFallback(true), WithType("Vehicle", testTruck{})}, types...),
+			values: [][]driver.Value{{int64(3), int64(3), nil, nil}, {int64(4), nil, nil, nil}},
+			want:   []testVehicle{testTruck{ID: 3}, testTruck{ID: 4}},
+		},
+		{
+			name:    "unmappedUnregistered",
+			opts:    append([]SessionOption{WithDiscriminatorFallback(true)}, types...),
+			values:  [][]driver.Value{{int64(3), int64(3), nil, nil}},
+			wantErr: "type(Vehicle) of resultMap(vehicleResult) is not registered",
+		},
+		{
+			name:    "notNumber",
+			opts:    types,
+			values:  [][]driver.Value{{int64(3), "car", nil, nil}},
+			wantErr: "discriminator column(vehicle_type) of resultMap(vehicleResult)",
+		},
+		{
+			name:    "unregistered",
+			values:  [][]driver.Value{{int64(1), int64(1), int64(4), nil}},
+			wantErr: "type(Car) of resultMap(carResult) is not registered",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDriver{query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+				return &fakeRows{columns: []string{"id", "vehicle_type", "door_count", "payload"}, values: tt.values}, nil
+			}}
+			s := NewSession(newFakeDB(d), []*Mapper{m}, tt.opts...)
+			var got []testVehicle
+			err := s.SelectList(context.Background(), "selectVehicles", nil, &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SelectList() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectList() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectList() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testGarage struct {
+	ID      int64
+	Vehicle testVehicle
+}
+
+func TestSession_discriminator_nested(t *testing.T) {
+	m, err := NewMapper("testdata/discriminator.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDriver{query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		return &fakeRows{
+			columns: []string{"id", "vehicle_id", "vehicle_vehicle_type", "vehicle_door_count", "vehicle_payload"},
+			values: [][]driver.Value{
+				{int64(1), int64(7), []byte("1"), int64(2), nil},
+				{int64(2), int64(8), int64(2), nil, 3.5},
+				{int64(3), nil, nil, nil, nil},
+			},
+		}, nil
+	}}
+	s := NewSession(newFakeDB(d), []*Mapper{m}, WithType("Car", &testCar{}), WithType("Truck", testTruck{}))
+
+	var got []testGarage
+	if err = s.SelectList(context.Background(), "selectGarages", nil, &got); err != nil {
+		t.Fatalf("SelectList() error = %v", err)
+	}
+	want := []testGarage{
+		{ID: 1, Vehicle: &testCar{ID: 7, Doors: 2}},
+		{ID: 2, Vehicle: testTruck{ID: 8, Payload: 3.5}},
+		{ID: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SelectList() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_discriminatorValue(t *testing.T) {
+	tests := []struct {
+		v        interface{}
+		javaType string
+		want     string
+	}{
+		{v: nil, javaType: "int", want: "null"},
+		{v: []byte("1.00"), javaType: "int", want: "1"},
+		{v: "1.00", javaType: "java.lang.Long", want: "1"},
+		{v: 2.0, javaType: "Integer", want: "2"},
+		{v: int64(1), javaType: "double", want: "1.0"},
+		{v: []byte("1.5"), javaType: "Double", want: "1.5"},
+		{v: int64(1), javaType: "boolean", want: "true"},
+		{v: []byte("1.00"), javaType: "BigDecimal", want: "1.00"},
+		{v: []byte("car"), javaType: "", want: "car"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.v, tt.javaType), func(t *testing.T) {
+			got, err := discriminatorValue(tt.v, tt.javaType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("discriminatorValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession_MapRows(t *testing.T) {
+	m, err := NewMapper("testdata/discriminator.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDriver{query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		return &fakeRows{
+			columns: []string{"id", "vehicle_type", "door_count", "payload"},
+			values:  [][]driver.Value{{int64(7), int64(1), int64(2), nil}},
+		}, nil
+	}}
+	db := newFakeDB(d)
+	s := NewSession(db, []*Mapper{m}, WithType("Car", &testCar{}))
+
+	rows, err := db.Query("select * from vehicle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []*testCar
+	if err = s.MapRows(context.Background(), rows, "Vehicle.vehicleResult", &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []*testCar{{ID: 7, Doors: 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MapRows() = %+v, want %+v", got, want)
+	}
+}
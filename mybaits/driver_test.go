@@ -0,0 +1,146 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// fakeDriver 测试用的驱动，记录执行的语句，通过exec和query模拟执行结果
+type fakeDriver struct {
+	mu    sync.Mutex
+	calls []fakeCall
+	exec  func(query string, args []driver.NamedValue) (driver.Result, error)
+	query func(query string, args []driver.NamedValue) (driver.Rows, error)
+}
+
+type fakeCall struct {
+	Query string
+	Args  []interface{}
+}
+
+func newFakeDB(d *fakeDriver) *sql.DB {
+	return sql.OpenDB(d)
+}
+
+func (d *fakeDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+func (d *fakeDriver) Driver() driver.Driver {
+	return d
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+func (d *fakeDriver) record(query string, args []driver.NamedValue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	call := fakeCall{Query: query}
+	for _, a := range args {
+		call.Args = append(call.Args, a.Value)
+	}
+	d.calls = append(d.calls, call)
+}
+
+func (d *fakeDriver) recorded() []fakeCall {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]fakeCall(nil), d.calls...)
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	query := "BEGIN"
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		query += " " + sql.IsolationLevel(opts.Isolation).String()
+	}
+	if opts.ReadOnly {
+		query += " READ ONLY"
+	}
+	c.driver.record(query, nil)
+	return &fakeTx{driver: c.driver}, nil
+}
+
+// CheckNamedValue 允许sql.Out，其他值使用默认的转化
+func (c *fakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(sql.Out); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.record(query, args)
+	if c.driver.exec != nil {
+		return c.driver.exec(query, args)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.record(query, args)
+	if c.driver.query != nil {
+		return c.driver.query(query, args)
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeTx struct {
+	driver *fakeDriver
+}
+
+func (t *fakeTx) Commit() error {
+	t.driver.record("COMMIT", nil)
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.driver.record("ROLLBACK", nil)
+	return nil
+}
+
+// fakeRows 按行返回values
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	pos     int
+	closed  bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.pos])
+	r.pos++
+	return nil
+}
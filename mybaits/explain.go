@@ -0,0 +1,281 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ExplainOption 执行计划的选项
+type ExplainOption func(e *Explainer)
+
+// WithExplainProduct 设置数据库产品名，PostgreSQL使用EXPLAIN (FORMAT JSON)，其他数据库使用EXPLAIN，
+// 默认通过db的驱动推断
+func WithExplainProduct(product string) ExplainOption {
+	return func(e *Explainer) {
+		e.product = product
+	}
+}
+
+// WithCostThreshold 设置高代价节点的阈值，默认为1000，
+// PostgreSQL比较节点的Total Cost，其他数据库比较节点估计扫描的行数
+func WithCostThreshold(threshold float64) ExplainOption {
+	return func(e *Explainer) {
+		e.threshold = threshold
+	}
+}
+
+// Explainer 使用类型正确的模拟值渲染语句，并在数据库上执行EXPLAIN获取执行计划
+type Explainer struct {
+	db        *sql.DB
+	product   string
+	threshold float64
+}
+
+// NewExplainer 生成在db上获取执行计划的Explainer
+func NewExplainer(db *sql.DB, opts ...ExplainOption) *Explainer {
+	e := &Explainer{
+		db:        db,
+		product:   productName(db),
+		threshold: 1000,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ExplainReport 执行计划报告
+type ExplainReport []ExplainEntry
+
+// ExplainEntry 一条语句的执行计划
+type ExplainEntry struct {
+	Namespace string     `json:"namespace"`
+	ID        string     `json:"id"`
+	SQL       string     `json:"sql"`
+	Nodes     []PlanNode `json:"nodes"`
+	File      string     `json:"file"`
+	Line      int        `json:"line"`
+	Error     string     `json:"error,omitempty"` // EXPLAIN失败时的错误
+}
+
+// PlanNode 执行计划中的节点，按照先序排列
+type PlanNode struct {
+	Depth    int     `json:"depth"`
+	Type     string  `json:"type"` // PostgreSQL的Node Type，其他数据库的访问类型
+	Table    string  `json:"table,omitempty"`
+	Cost     float64 `json:"cost"`
+	Rows     float64 `json:"rows"`
+	SeqScan  bool    `json:"seqScan"`  // 是否全表扫描
+	HighCost bool    `json:"highCost"` // 代价超过阈值，并且子节点的代价都没有超过阈值
+}
+
+// Flagged 判断执行计划中是否有全表扫描或者高代价的节点
+func (e ExplainEntry) Flagged() bool {
+	for _, n := range e.Nodes {
+		if n.SeqScan || n.HighCost {
+			return true
+		}
+	}
+	return false
+}
+
+// Explain 按文档顺序获取映射文件中所有语句(不包括sql片段和存储过程)的执行计划，
+// 渲染后的SQL只合并空白字符，不会改写为MySQL的语法，使得PostgreSQL特有的语法也可以获取执行计划，
+// EXPLAIN失败时不会返回错误，而是记录在执行计划的Error中
+func (e *Explainer) Explain(ctx context.Context, m *Mapper) (report ExplainReport, err error) {
+	for _, id := range m.ids {
+		child := m.root[id]
+		if child.Tag == "sql" {
+			continue
+		}
+
+		var info *StatementInfo
+		if info, err = m.Statement(id); err != nil {
+			return nil, err
+		}
+		if info.StatementType == "CALLABLE" {
+			continue
+		}
+		entry := ExplainEntry{
+			Namespace: m.namespace,
+			ID:        id,
+			Nodes:     []PlanNode{},
+			File:      m.path,
+			Line:      m.Line(id),
+		}
+
+		cm := m.newChildMapper(child)
+		cm.mocker = &mocker{mode: MockTyped}
+		if m.mocker != nil {
+			cm.mocker.samples = m.mocker.samples
+		}
+		entry.SQL = strings.TrimSpace(whitespaceRegex.ReplaceAllString(cm.getRawStatement(), " "))
+		var serr error
+		if entry.Nodes, serr = e.explain(ctx, entry.SQL); serr != nil {
+			entry.Error = serr.Error()
+		}
+		report = append(report, entry)
+	}
+	return
+}
+
+func (e *Explainer) explain(ctx context.Context, query string) ([]PlanNode, error) {
+	if e.product == "PostgreSQL" {
+		return e.explainPostgreSQL(ctx, query)
+	}
+	return e.explainTable(ctx, query)
+}
+
+// pgPlan PostgreSQL的EXPLAIN (FORMAT JSON)中的节点
+type pgPlan struct {
+	NodeType     string   `json:"Node Type"`
+	RelationName string   `json:"Relation Name"`
+	TotalCost    float64  `json:"Total Cost"`
+	PlanRows     float64  `json:"Plan Rows"`
+	Plans        []pgPlan `json:"Plans"`
+}
+
+func (e *Explainer) explainPostgreSQL(ctx context.Context, query string) (nodes []PlanNode, err error) {
+	var data []byte
+	if err = e.db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&data); err != nil {
+		return nil, fmt.Errorf("explain fail. err: %v", err)
+	}
+	var plans []struct {
+		Plan pgPlan `json:"Plan"`
+	}
+	if err = json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("Unmarshal plan fail. err: %v", err)
+	}
+	nodes = []PlanNode{}
+	for _, p := range plans {
+		e.addPlan(&nodes, p.Plan, 0)
+	}
+	return
+}
+
+// addPlan 按照先序添加节点，返回子树中是否有超过阈值的节点
+func (e *Explainer) addPlan(nodes *[]PlanNode, p pgPlan, depth int) bool {
+	i := len(*nodes)
+	*nodes = append(*nodes, PlanNode{
+		Depth:   depth,
+		Type:    p.NodeType,
+		Table:   p.RelationName,
+		Cost:    p.TotalCost,
+		Rows:    p.PlanRows,
+		SeqScan: p.NodeType == "Seq Scan",
+	})
+	exceeded := false
+	for _, c := range p.Plans {
+		if e.addPlan(nodes, c, depth+1) {
+			exceeded = true
+		}
+	}
+	if !exceeded && p.TotalCost > e.threshold {
+		(*nodes)[i].HighCost = true
+		exceeded = true
+	}
+	return exceeded
+}
+
+// explainTable 获取MySQL等数据库表格形式的执行计划，每一行是一个节点，type为ALL时是全表扫描
+func (e *Explainer) explainTable(ctx context.Context, query string) (nodes []PlanNode, err error) {
+	var rows *sql.Rows
+	if rows, err = e.db.QueryContext(ctx, "EXPLAIN "+query); err != nil {
+		return nil, fmt.Errorf("explain fail. err: %v", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	if columns, err = rows.Columns(); err != nil {
+		return nil, fmt.Errorf("Columns fail. err: %v", err)
+	}
+	nodes = []PlanNode{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("Scan fail. err: %v", err)
+		}
+
+		row := make(map[string]string)
+		for i, c := range columns {
+			row[strings.ToLower(c)] = values[i].String
+		}
+		node := PlanNode{
+			Type:  row["type"],
+			Table: row["table"],
+		}
+		node.Rows, _ = strconv.ParseFloat(row["rows"], 64)
+		node.Cost = node.Rows
+		node.SeqScan = strings.EqualFold(node.Type, "ALL")
+		node.HighCost = node.Cost > e.threshold
+		nodes = append(nodes, node)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Next fail. err: %v", err)
+	}
+	return
+}
+
+// WriteText 以文本格式输出执行计划，有全表扫描或者高代价节点的语句会被标记
+func (r ExplainReport) WriteText(w io.Writer) error {
+	b := &strings.Builder{}
+	for _, e := range r {
+		status := "ok"
+		switch {
+		case e.Error != "":
+			status = "error"
+		case e.Flagged():
+			status = "flagged"
+		}
+		fmt.Fprintf(b, "%v:%v: [%v] %v.%v\n", e.File, e.Line, status, e.Namespace, e.ID)
+		if e.Error != "" {
+			fmt.Fprintf(b, "\t%v\n", e.Error)
+		}
+		for _, n := range e.Nodes {
+			fmt.Fprintf(b, "\t%v%v", strings.Repeat("  ", n.Depth), n.Type)
+			if n.Table != "" {
+				fmt.Fprintf(b, " on %v", n.Table)
+			}
+			fmt.Fprintf(b, " (cost=%v rows=%v)", n.Cost, n.Rows)
+			if n.SeqScan {
+				b.WriteString(" SEQ SCAN")
+			}
+			if n.HighCost {
+				b.WriteString(" HIGH COST")
+			}
+			b.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON 以JSON格式输出执行计划
+func (r ExplainReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if r == nil {
+		r = ExplainReport{}
+	}
+	return encoder.Encode(r)
+}
+
+// Write 以format(text, json)格式输出执行计划
+func (r ExplainReport) Write(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "text":
+		return r.WriteText(w)
+	case "json":
+		return r.WriteJSON(w)
+	}
+	return fmt.Errorf("explain format(%v) is not supported", format)
+}
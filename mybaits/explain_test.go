@@ -0,0 +1,199 @@
+package mybaits
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPgPlan = `[{"Plan": {"Node Type": "Hash Join", "Total Cost": 2400.5, "Plan Rows": 10,
+	"Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 1800, "Plan Rows": 50000},
+		{"Node Type": "Index Scan", "Relation Name": "customers", "Total Cost": 8.3, "Plan Rows": 1}
+	]}}]`
+
+func TestExplainer_Explain(t *testing.T) {
+	m, err := NewMapper("testdata/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      []ExplainOption
+		query     func(query string, args []driver.NamedValue) (driver.Rows, error)
+		wantCalls []string
+		want      map[string][]PlanNode
+	}{
+		{
+			name: "mysql",
+			query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+				if strings.HasPrefix(query, "EXPLAIN INSERT") {
+					return nil, fmt.Errorf("explain denied")
+				}
+				if strings.HasPrefix(query, "EXPLAIN UPDATE") {
+					return nil, fmt.Errorf("syntax error")
+				}
+				return &fakeRows{
+					columns: []string{"id", "select_type", "table", "type", "key", "rows", "Extra"},
+					values: [][]driver.Value{
+						{int64(1), "SIMPLE", "o", "const", "PRIMARY", int64(1), nil},
+						{int64(1), "SIMPLE", "c", "ALL", nil, int64(5000), "Using where"},
+					},
+				}, nil
+			},
+			wantCalls: []string{
+				"EXPLAIN SELECT o.id, o.name FROM sales.orders o JOIN customers c ON o.customer_id = c.id WHERE o.id = 1",
+				"EXPLAIN INSERT INTO orders (id, name) VALUES ('1', 'mock')",
+				"EXPLAIN UPDATE orders SET WHERE",
+			},
+			want: map[string][]PlanNode{
+				"selectOrders": {
+					{Type: "const", Table: "o", Cost: 1, Rows: 1},
+					{Type: "ALL", Table: "c", Cost: 5000, Rows: 5000, SeqScan: true, HighCost: true},
+				},
+			},
+		},
+		{
+			name: "postgresql",
+			opts: []ExplainOption{WithExplainProduct("PostgreSQL"), WithCostThreshold(1000)},
+			query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+				if strings.Contains(query, "SET WHERE") {
+					return nil, fmt.Errorf("syntax error")
+				}
+				return &fakeRows{columns: []string{"QUERY PLAN"}, values: [][]driver.Value{{testPgPlan}}}, nil
+			},
+			want: map[string][]PlanNode{
+				"selectOrders": {
+					{Depth: 0, Type: "Hash Join", Cost: 2400.5, Rows: 10},
+					{Depth: 1, Type: "Seq Scan", Table: "orders", Cost: 1800, Rows: 50000, SeqScan: true, HighCost: true},
+					{Depth: 1, Type: "Index Scan", Table: "customers", Cost: 8.3, Rows: 1},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDriver{query: tt.query}
+			report, err := NewExplainer(newFakeDB(d), tt.opts...).Explain(context.Background(), m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for _, e := range report {
+				ids = append(ids, e.ID)
+			}
+			if want := []string{"selectOrders", "insertOrder", "broken"}; !reflect.DeepEqual(ids, want) {
+				t.Fatalf("ids = %v, want %v", ids, want)
+			}
+			if got := report[0].Nodes; !reflect.DeepEqual(got, tt.want["selectOrders"]) {
+				t.Errorf("Nodes = %+v, want %+v", got, tt.want["selectOrders"])
+			}
+			if !report[0].Flagged() || report[2].Error == "" {
+				t.Errorf("report = %+v", report)
+			}
+
+			if tt.wantCalls != nil {
+				if report[1].Error == "" {
+					t.Errorf("insertOrder error is empty")
+				}
+				var calls []string
+				for _, c := range d.recorded() {
+					calls = append(calls, c.Query)
+				}
+				if !reflect.DeepEqual(calls, tt.wantCalls) {
+					t.Errorf("calls = %q, want %q", calls, tt.wantCalls)
+				}
+			} else if c := d.recorded()[0].Query; !strings.HasPrefix(c, "EXPLAIN (FORMAT JSON) SELECT") {
+				t.Errorf("call = %v", c)
+			}
+		})
+	}
+}
+
+func TestExplainer_Explain_postgresSyntax(t *testing.T) {
+	m, err := NewMapperFromBytes("explain.xml", []byte(`<mapper namespace="Explain">
+    <select id="selectNow">
+        select now()
+    </select>
+    <select id="searchOrders">
+        SELECT id::text FROM orders
+        WHERE name ILIKE #{name,jdbcType=VARCHAR}
+        LIMIT 10 OFFSET 5
+    </select>
+</mapper>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDriver{query: func(query string, args []driver.NamedValue) (driver.Rows, error) {
+		return &fakeRows{columns: []string{"QUERY PLAN"}, values: [][]driver.Value{{testPgPlan}}}, nil
+	}}
+	report, err := NewExplainer(newFakeDB(d), WithExplainProduct("PostgreSQL")).Explain(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range report {
+		if e.Error != "" {
+			t.Errorf("statement(%v) error = %v", e.ID, e.Error)
+		}
+	}
+
+	var calls []string
+	for _, c := range d.recorded() {
+		calls = append(calls, c.Query)
+	}
+	want := []string{
+		"EXPLAIN (FORMAT JSON) select now()",
+		"EXPLAIN (FORMAT JSON) SELECT id::text FROM orders WHERE name ILIKE 'mock' LIMIT 10 OFFSET 5",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestExplainReport_Write(t *testing.T) {
+	report := ExplainReport{{
+		Namespace: "Catalog",
+		ID:        "selectOrders",
+		Nodes: []PlanNode{
+			{Type: "Hash Join", Cost: 2400.5, Rows: 10},
+			{Depth: 1, Type: "Seq Scan", Table: "orders", Cost: 1800, Rows: 50000, SeqScan: true, HighCost: true},
+		},
+		File: "catalog.xml",
+		Line: 7,
+	}}
+	buf := &bytes.Buffer{}
+	if err := report.Write(buf, "text"); err != nil {
+		t.Fatal(err)
+	}
+	want := "catalog.xml:7: [flagged] Catalog.selectOrders\n" +
+		"\tHash Join (cost=2400.5 rows=10)\n" +
+		"\t  Seq Scan on orders (cost=1800 rows=50000) SEQ SCAN HIGH COST\n"
+	if buf.String() != want {
+		t.Errorf("WriteText() = %q, want %q", buf.String(), want)
+	}
+	if err := report.Write(buf, "csv"); err == nil {
+		t.Errorf("Write() error = nil, want unsupported format")
+	}
+}
+
+func TestExplainer_Explain_callable(t *testing.T) {
+	m, err := NewMapper("testdata/bind.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := NewExplainer(newFakeDB(&fakeDriver{})).Explain(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range report {
+		if e.ID == "countFruits" || e.ID == "adjustPrice" {
+			t.Errorf("callable statement %v is explained", e.ID)
+		}
+	}
+}
@@ -0,0 +1,785 @@
+package mybaits
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// scope 绑定参数时表达式求值的作用域
+type scope struct {
+	param      interface{}
+	databaseID string
+	locals     map[string]interface{}
+	// databaseIDOnly 加载映射文件时只能访问_databaseId，访问其他变量返回错误
+	databaseIDOnly bool
+}
+
+// lookup 获取变量name的值，依次查找局部变量、_parameter、_databaseId和参数的属性，
+// 参数不是map和结构体时任意变量名都表示参数本身，和mybatis只有一个参数时的行为一致
+func (s *scope) lookup(name string) (interface{}, error) {
+	if v, ok := s.locals[name]; ok {
+		return v, nil
+	}
+	switch name {
+	case "_parameter":
+		return s.param, nil
+	case "_databaseId":
+		return s.databaseID, nil
+	}
+	if s.databaseIDOnly {
+		return nil, fmt.Errorf("variable(%v) is not available before binding", name)
+	}
+	return member(s.param, name, true)
+}
+
+// member 获取v的属性name，和mybatis一致，map中没有的键为nil，结构体中没有的字段返回错误，
+// v不是map和结构体时，self为true返回v本身，否则返回nil
+func member(v interface{}, name string, self bool) (interface{}, error) {
+	if pv, ok := property(v, name); ok {
+		return pv, nil
+	}
+	switch rv := indirect(v); {
+	case rv.Kind() == reflect.Struct:
+		return nil, fmt.Errorf("property(%v) not found in %v", name, rv.Type())
+	case self && !isContainer(v):
+		return v, nil
+	}
+	return nil, nil
+}
+
+// evalExpression 对OGNL表达式的常用子集求值，支持属性访问、下标、size()等方法、
+// 比较、算术、字符串拼接以及and、or、not
+func evalExpression(expr string, s *scope) (interface{}, error) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return nil, fmt.Errorf("expression(%v) is not valid. err: %v", expr, err)
+	}
+	p := &exprParser{tokens: tokens, scope: s}
+	v, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %v", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("expression(%v) is not valid. err: %v", expr, err)
+	}
+	return v, nil
+}
+
+// evalBool 和mybatis的ExpressionEvaluator一致，数值不为0或者值不为null时为真
+func evalBool(expr string, s *scope) (bool, error) {
+	v, err := evalExpression(expr, s)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return !isNil(v)
+}
+
+type exprTokenKind int
+
+const (
+	tokenIdent exprTokenKind = iota
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenStatic // 静态引用，如@java.lang.Math@max
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+}
+
+var exprOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ".", ","}
+
+func tokenizeExpression(expr string) (tokens []exprToken, err error) {
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			b := &strings.Builder{}
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				b.WriteByte(expr[j])
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: b.String()})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: expr[i:j]})
+			i = j
+		case c == '_' || c == '$' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(expr) && (expr[j] == '_' || expr[j] == '$' || unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: expr[i:j]})
+			i = j
+		case c == '@':
+			j := strings.IndexByte(expr[i+1:], '@')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated static reference")
+			}
+			k := i + j + 2
+			for k < len(expr) && (expr[k] == '_' || expr[k] == '$' || unicode.IsLetter(rune(expr[k])) || unicode.IsDigit(rune(expr[k]))) {
+				k++
+			}
+			tokens = append(tokens, exprToken{kind: tokenStatic, text: expr[i:k]})
+			i = k
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, exprToken{kind: tokenOperator, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+		}
+	}
+	return
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	scope  *scope
+	skip   int // 大于0时表示正在解析被短路的操作数，只检查语法，忽略求值错误
+}
+
+// evalError 被短路的操作数的求值错误不返回，和OGNL不对短路的操作数求值一致
+func (p *exprParser) evalError(err error) error {
+	if p.skip > 0 {
+		return nil
+	}
+	return err
+}
+
+// accept 下一个记号是运算符或者关键字之一时前进并返回它
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	t := p.tokens[p.pos]
+	if t.kind != tokenOperator && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		return fmt.Errorf("expect %v", op)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		short := truthy(left)
+		if short {
+			p.skip++
+		}
+		right, err := p.parseAnd()
+		if short {
+			p.skip--
+		}
+		if err != nil {
+			return nil, err
+		}
+		left = short || truthy(right)
+	}
+}
+
+func (p *exprParser) parseAnd() (interface{}, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		short := !truthy(left)
+		if short {
+			p.skip++
+		}
+		right, err := p.parseNot()
+		if short {
+			p.skip--
+		}
+		if err != nil {
+			return nil, err
+		}
+		left = !short && truthy(right)
+	}
+}
+
+func (p *exprParser) parseNot() (interface{}, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		v, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return !truthy(v), nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (interface{}, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "eq", "neq", "lt", "lte", "gt", "gte")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "==", "eq":
+		return equal(left, right), nil
+	case "!=", "neq":
+		return !equal(left, right), nil
+	}
+	c, err := compare(left, right)
+	if err != nil {
+		return nil, p.evalError(err)
+	}
+	switch op {
+	case "<", "lt":
+		return c < 0, nil
+	case "<=", "lte":
+		return c <= 0, nil
+	case ">", "gt":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func (p *exprParser) parseAdd() (interface{}, error) {
+	left, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := left.(string); ok && op == "+" {
+			left = left.(string) + toString(right)
+			continue
+		}
+		if _, ok := right.(string); ok && op == "+" {
+			left = toString(left) + right.(string)
+			continue
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, p.evalError(err)
+		}
+	}
+}
+
+func (p *exprParser) parseMul() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, p.evalError(err)
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (interface{}, error) {
+	if _, ok := p.accept("-"); ok {
+		v, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if v, err = arithmetic("-", int64(0), v); err != nil {
+			return nil, p.evalError(err)
+		}
+		return v, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (v interface{}, err error) {
+	if v, err = p.parsePrimary(); err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("."); ok {
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenIdent {
+				return nil, fmt.Errorf("expect property name")
+			}
+			name := p.tokens[p.pos].text
+			p.pos++
+			if _, ok := p.accept("("); ok {
+				if v, err = p.parseCall(v, name); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if v, err = member(v, name, false); err != nil {
+				if err = p.evalError(err); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if _, ok := p.accept("["); ok {
+			var index interface{}
+			if index, err = p.parseOr(); err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+			v = element(v, index)
+			continue
+		}
+		return v, nil
+	}
+}
+
+func (p *exprParser) parsePrimary() (interface{}, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokenString:
+		return t.text, nil
+	case tokenNumber:
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("number(%v) is not valid", t.text)
+		}
+		return f, nil
+	case tokenIdent:
+		switch t.text {
+		case "null":
+			return nil, nil
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		v, err := p.scope.lookup(t.text)
+		return v, p.evalError(err)
+	case tokenStatic:
+		return nil, fmt.Errorf("static reference(%v) is not supported", t.text)
+	}
+	if t.text == "(" {
+		v, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return v, p.expect(")")
+	}
+	return nil, fmt.Errorf("unexpected %v", t.text)
+}
+
+// parseCall 对v调用方法name，支持java中集合和字符串的常用方法
+func (p *exprParser) parseCall(v interface{}, name string) (interface{}, error) {
+	var args []interface{}
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	switch name {
+	case "size", "length":
+		return int64(length(v)), nil
+	case "isEmpty":
+		return length(v) == 0, nil
+	case "toString":
+		return toString(v), nil
+	case "trim":
+		return strings.TrimSpace(toString(v)), nil
+	case "toUpperCase":
+		return strings.ToUpper(toString(v)), nil
+	case "toLowerCase":
+		return strings.ToLower(toString(v)), nil
+	case "equals":
+		if len(args) == 1 {
+			return equal(v, args[0]), nil
+		}
+	case "contains", "containsKey":
+		if len(args) == 1 {
+			return contains(v, args[0]), nil
+		}
+	}
+	return nil, p.evalError(fmt.Errorf("method %v with %v arguments is not supported", name, len(args)))
+}
+
+func indirect(v interface{}) reflect.Value {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+func isNil(v interface{}) bool {
+	return !indirect(v).IsValid()
+}
+
+func isContainer(v interface{}) bool {
+	switch indirect(v).Kind() {
+	case reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// property 获取map中键为name的值，或者结构体中名字和name忽略首字母大小写相同的字段
+func property(v interface{}, name string) (interface{}, bool) {
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		e := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !e.IsValid() {
+			return nil, true
+		}
+		return e.Interface(), true
+	case reflect.Struct:
+		f, ok := fieldByName(rv.Type(), name)
+		if !ok {
+			return nil, false
+		}
+		// 字段在为nil的匿名结构体指针中时值为nil
+		if fv, ok := fieldByIndex(rv, f.Index, false); ok {
+			return fv.Interface(), true
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+// fieldByName 获取结构体类型t中名字为name的导出字段，优先匹配首字母大写后的name，
+// 其次不区分大小写匹配，如id可以匹配ID
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	if name == "" {
+		return reflect.StructField{}, false
+	}
+	exported := strings.ToUpper(name[:1]) + name[1:]
+	f, ok := t.FieldByName(exported)
+	if !ok {
+		f, ok = t.FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+	}
+	return f, ok && f.IsExported()
+}
+
+// structField 获取结构体rv中名字为name的导出字段，字段所在的匿名结构体指针为nil时，
+// 指针可以设置则分配，否则返回无效的值
+func structField(rv reflect.Value, name string) reflect.Value {
+	f, ok := fieldByName(rv.Type(), name)
+	if !ok {
+		return reflect.Value{}
+	}
+	fv, _ := fieldByIndex(rv, f.Index, true)
+	return fv
+}
+
+// fieldByIndex 和reflect.Value.FieldByIndex一致，但是不会因为为nil的匿名结构体指针panic，
+// alloc为true并且指针可以设置时分配指针，否则返回false
+func fieldByIndex(rv reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				if !alloc || !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+func element(v, index interface{}) interface{} {
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		i, ok := toFloat(index)
+		if !ok || int(i) < 0 || int(i) >= rv.Len() {
+			return nil
+		}
+		return rv.Index(int(i)).Interface()
+	case reflect.Map:
+		key := reflect.ValueOf(index)
+		if !key.IsValid() || !key.Type().ConvertibleTo(rv.Type().Key()) {
+			return nil
+		}
+		if e := rv.MapIndex(key.Convert(rv.Type().Key())); e.IsValid() {
+			return e.Interface()
+		}
+		return nil
+	}
+	v, _ = property(v, toString(index))
+	return v
+}
+
+func length(v interface{}) int {
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String, reflect.Chan:
+		return rv.Len()
+	}
+	return 0
+}
+
+func contains(v, x interface{}) bool {
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return strings.Contains(rv.String(), toString(x))
+	case reflect.Map:
+		return element(v, x) != nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if equal(rv.Index(i).Interface(), x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// iterate 按顺序遍历集合v，map按键排序，保证生成的SQL稳定
+func iterate(v interface{}, fn func(index, item interface{}) error) error {
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := fn(i, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return toString(keys[i].Interface()) < toString(keys[j].Interface())
+		})
+		for _, k := range keys {
+			if err := fn(k.Interface(), rv.MapIndex(k).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%T is not iterable", v)
+}
+
+func toString(v interface{}) string {
+	rv := indirect(v)
+	if !rv.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(rv.Interface())
+}
+
+// toFloat 将数值转化为float64，和ognl一致，字符串会被解析为数值，空字符串为0
+func toFloat(v interface{}) (float64, bool) {
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func isNumber(v interface{}) bool {
+	_, ok := toFloat(v)
+	return ok
+}
+
+func stringNumber(v interface{}) (float64, bool) {
+	s, ok := indirect(v).Interface().(string)
+	if !ok {
+		return 0, false
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+func equal(a, b interface{}) bool {
+	if isNil(a) || isNil(b) {
+		return isNil(a) && isNil(b)
+	}
+	if isNumber(a) || isNumber(b) {
+		c, err := compare(a, b)
+		return err == nil && c == 0
+	}
+	return reflect.DeepEqual(indirect(a).Interface(), indirect(b).Interface())
+}
+
+func compare(a, b interface{}) (int, error) {
+	if isNil(a) || isNil(b) {
+		return 0, fmt.Errorf("can not compare %v with %v", a, b)
+	}
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && !okB {
+		fb, okB = stringNumber(b)
+	}
+	if okB && !okA {
+		fa, okA = stringNumber(a)
+	}
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1, nil
+		case fa > fb:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	sa, okA := indirect(a).Interface().(string)
+	sb, okB := indirect(b).Interface().(string)
+	if okA && okB {
+		return strings.Compare(sa, sb), nil
+	}
+	return 0, fmt.Errorf("can not compare %T with %T", a, b)
+}
+
+func arithmetic(op string, a, b interface{}) (interface{}, error) {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if !okA || !okB {
+		return nil, fmt.Errorf("can not apply %v to %T and %T", op, a, b)
+	}
+
+	ra, rb := indirect(a).Kind(), indirect(b).Kind()
+	if ra != reflect.Float32 && ra != reflect.Float64 && rb != reflect.Float32 && rb != reflect.Float64 {
+		ia, ib := int64(fa), int64(fb)
+		switch op {
+		case "+":
+			return ia + ib, nil
+		case "-":
+			return ia - ib, nil
+		case "*":
+			return ia * ib, nil
+		}
+		if ib == 0 {
+			return nil, fmt.Errorf("divide by zero")
+		}
+		if op == "/" {
+			return ia / ib, nil
+		}
+		return ia % ib, nil
+	}
+
+	switch op {
+	case "+":
+		return fa + fb, nil
+	case "-":
+		return fa - fb, nil
+	case "*":
+		return fa * fb, nil
+	case "/":
+		return fa / fb, nil
+	}
+	return nil, fmt.Errorf("can not apply %v to float", op)
+}
@@ -0,0 +1,94 @@
+package mybaits
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testEmbeddedFruit struct {
+	*testFruit
+	Color string
+}
+
+func Test_evalExpression(t *testing.T) {
+	s := &scope{
+		param: map[string]interface{}{
+			"name":  "apple",
+			"price": 0,
+			"ids":   []int{1, 2, 3},
+			"user":  &testFruit{ID: 5},
+			"attrs": map[string]string{"color": "red"},
+			"fruit": testEmbeddedFruit{Color: "red"},
+		},
+		databaseID: "mysql",
+		locals:     map[string]interface{}{"item": "local"},
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "notNull", expr: "name != null", want: true},
+		{name: "missingIsNull", expr: "missing == null", want: true},
+		{name: "andOr", expr: "name == 'apple' and (price > 1 or ids.size() == 3)", want: true},
+		{name: "symbols", expr: "name eq \"apple\" && !(price gt 0) || false", want: true},
+		{name: "ognlEmptyString", expr: "price == ''", want: true},
+		{name: "index", expr: "ids[1] + 1", want: int64(3)},
+		{name: "mapIndex", expr: "attrs['color']", want: "red"},
+		{name: "structField", expr: "user.id", want: int64(5)},
+		{name: "concat", expr: "'%' + name + '%'", want: "%apple%"},
+		{name: "methods", expr: "name.toUpperCase().length()", want: int64(5)},
+		{name: "local", expr: "item", want: "local"},
+		{name: "databaseID", expr: "_databaseId == 'mysql'", want: true},
+		{name: "arithmetic", expr: "-ids[0] * 2 + 10 % 4", want: int64(0)},
+		{name: "float", expr: "1.5 * 2", want: float64(3)},
+		{name: "static", expr: "@java.lang.Math@max(1, 2)", wantErr: true},
+		{name: "unterminated", expr: "name == 'apple", wantErr: true},
+		{name: "trailing", expr: "name name", wantErr: true},
+		{name: "compareNull", expr: "missing > 1", wantErr: true},
+		{name: "andShortCircuit", expr: "missing != null and missing > 100", want: false},
+		{name: "orShortCircuit", expr: "missing == null or missing.foo() > 1", want: true},
+		{name: "nestedShortCircuit", expr: "missing != null and (missing > 1 or missing * 2 > 1)", want: false},
+		{name: "shortCircuitSyntax", expr: "missing != null and missing >", wantErr: true},
+		{name: "notShortCircuit", expr: "name != null and missing > 100", wantErr: true},
+		{name: "unknownField", expr: "user.nmae", wantErr: true},
+		{name: "unknownFieldShortCircuit", expr: "user == null and user.nmae == 1", want: false},
+		{name: "nilEmbedded", expr: "fruit.name == null and fruit.color == 'red'", want: true},
+		{name: "nilEmbeddedUnknown", expr: "fruit.nmae", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evalExpression(tt.expr, s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evalExpression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evalExpression() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_truthy(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want bool
+	}{
+		{name: "nil", v: nil, want: false},
+		{name: "nilPointer", v: (*int)(nil), want: false},
+		{name: "zero", v: 0, want: false},
+		{name: "number", v: 2.5, want: true},
+		{name: "emptyString", v: "", want: true},
+		{name: "false", v: false, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truthy(tt.v); got != tt.want {
+				t.Errorf("truthy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package mybaits
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// ErrNotMapper Format的内容不是mybatis的映射文件，根元素不是mapper，
+// NewMapper不检查根元素
+var ErrNotMapper = errors.New("not mybatis mapper")
+
+// KeywordCase 格式化时SQL关键字的大小写
+type KeywordCase int
+
+// 关键字的大小写
+const (
+	KeywordUpper    KeywordCase = iota // 大写
+	KeywordLower                       // 小写
+	KeywordPreserve                    // 保持不变
+)
+
+// FormatOption 格式化的选项
+type FormatOption func(f *formatter)
+
+// WithIndent 设置每一层的缩进，默认为4个空格
+func WithIndent(indent string) FormatOption {
+	return func(f *formatter) {
+		f.indent = indent
+	}
+}
+
+// WithKeywordCase 设置SQL关键字的大小写，默认为大写
+func WithKeywordCase(c KeywordCase) FormatOption {
+	return func(f *formatter) {
+		f.keywordCase = c
+	}
+}
+
+type formatter struct {
+	indent      string
+	keywordCase KeywordCase
+}
+
+// Format 格式化映射文件的内容data，统一元素的缩进，重排语句中的SQL文本，
+// #{}、${}、字符串、注释和CDATA的内容保持不变，格式化后的结果再次格式化不会变化
+func Format(data []byte, opts ...FormatOption) ([]byte, error) {
+	f := &formatter{indent: "    "}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	doc := etree.NewDocument()
+	doc.ReadSettings.PreserveCData = true
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("ReadFromBytes fail. err: %v", err)
+	}
+	if root := doc.Root(); root == nil || root.Tag != "mapper" {
+		return nil, ErrNotMapper
+	}
+
+	out := etree.NewDocument()
+	out.WriteSettings.CanonicalText = true
+	out.WriteSettings.CanonicalAttrVal = true
+	for _, token := range doc.Child {
+		switch t := token.(type) {
+		case *etree.ProcInst:
+			out.CreateProcInst(t.Target, t.Inst)
+		case *etree.Directive:
+			out.CreateDirective(t.Data)
+		case *etree.Comment:
+			out.CreateComment(t.Data)
+		case *etree.Element:
+			out.AddChild(f.mapper(t))
+		default:
+			continue
+		}
+		out.CreateText("\n")
+	}
+	return out.WriteToBytes()
+}
+
+// mapper 格式化mapper元素，顶层元素之间空一行，注释紧跟后面的元素
+func (f *formatter) mapper(root *etree.Element) *etree.Element {
+	e := copyElement(root)
+	first, afterComment := true, false
+	for _, token := range root.Child {
+		switch t := token.(type) {
+		case *etree.Element:
+			f.newline(e, 1, !first && !afterComment)
+			e.AddChild(f.element(t, 1, t.Tag == "sql"))
+			afterComment = false
+		case *etree.Comment:
+			f.newline(e, 1, !first && !afterComment)
+			e.CreateComment(t.Data)
+			afterComment = true
+		default:
+			continue
+		}
+		first = false
+	}
+	if len(e.Child) > 0 {
+		f.newline(e, 0, false)
+	}
+	return e
+}
+
+// element 格式化深度为depth的元素，语句和动态标签中的文本按照SQL格式化，其他元素只调整缩进。
+// 文本和相邻的标签之间没有空白字符时保持相连，如sales.<include refid="tbl"/>，否则改变了语句；
+// strict为true时元素内部的首尾也是如此，用于sql片段和两边紧贴文本的元素
+func (f *formatter) element(src *etree.Element, depth int, strict bool) *etree.Element {
+	e := copyElement(src)
+	glued := false // 上一个token是否是紧贴当前位置的文本
+	space := false // 原文中当前位置是否有空白字符，strict为true时只在有空白字符的位置换行
+	for i, token := range src.Child {
+		br := !glued && (!strict || space)
+		switch t := token.(type) {
+		case *etree.Element:
+			f.lineBreak(e, depth+1, br)
+			e.AddChild(f.element(t, depth+1, strict || t.Tag == "sql" || glued || touchesText(src.Child, i+1)))
+		case *etree.Comment:
+			f.lineBreak(e, depth+1, br)
+			e.CreateComment(t.Data)
+		case *etree.CharData:
+			if t.IsCData() {
+				f.lineBreak(e, depth+1, br)
+				e.CreateCData(t.Data)
+				break
+			}
+			lines := f.sqlLines(t.Data)
+			if len(lines) == 0 {
+				glued, space = false, t.Data != ""
+				continue
+			}
+			touchStart := !isSpace(t.Data[0]) && (i > 0 || strict)
+			for j, line := range lines {
+				f.lineBreak(e, depth+1, j > 0 || !touchStart)
+				e.CreateText(line)
+			}
+			glued = !isSpace(t.Data[len(t.Data)-1]) && (i < len(src.Child)-1 || strict)
+			space = !glued
+			continue
+		}
+		glued, space = false, false
+	}
+	if len(e.Child) > 0 {
+		f.lineBreak(e, depth, !glued && (!strict || space))
+	}
+	return e
+}
+
+// touchesText 判断tokens[i]是否是开头没有空白字符的文本
+func touchesText(tokens []etree.Token, i int) bool {
+	if i >= len(tokens) {
+		return false
+	}
+	t, ok := tokens[i].(*etree.CharData)
+	return ok && !t.IsCData() && t.Data != "" && !isSpace(t.Data[0])
+}
+
+// lineBreak br为true时换行并缩进，否则和上一个token相连
+func (f *formatter) lineBreak(e *etree.Element, depth int, br bool) {
+	if br {
+		f.newline(e, depth, false)
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func (f *formatter) newline(e *etree.Element, depth int, blank bool) {
+	s := "\n"
+	if blank {
+		s = "\n\n"
+	}
+	e.CreateText(s + strings.Repeat(f.indent, depth))
+}
+
+func copyElement(src *etree.Element) *etree.Element {
+	e := etree.NewElement(src.Tag)
+	e.Space = src.Space
+	for _, a := range src.Attr {
+		e.CreateAttr(a.FullKey(), a.Value)
+	}
+	return e
+}
+
+// clauseKeywords 另起一行的子句关键字
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"LIMIT": true, "OFFSET": true, "UNION": true, "INSERT": true, "VALUES": true, "UPDATE": true,
+	"SET": true, "DELETE": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true,
+	"OUTER": true, "CROSS": true, "FULL": true, "RETURNING": true,
+}
+
+// joinedKeywords 后面的子句关键字不另起一行，如LEFT JOIN、DELETE FROM和FOR UPDATE
+var joinedKeywords = map[string]bool{
+	"LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true, "CROSS": true, "FULL": true,
+	"NATURAL": true, "DELETE": true, "FOR": true, "KEY": true, "CHARACTER": true, "INSERT": true,
+}
+
+// sqlKeywords 需要统一大小写的关键字
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "BY": true, "HAVING": true,
+	"LIMIT": true, "OFFSET": true, "UNION": true, "ALL": true, "DISTINCT": true, "INSERT": true,
+	"INTO": true, "VALUES": true, "UPDATE": true, "SET": true, "DELETE": true, "JOIN": true,
+	"LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true, "CROSS": true, "FULL": true,
+	"NATURAL": true, "ON": true, "USING": true, "AS": true, "AND": true, "OR": true, "NOT": true,
+	"IN": true, "IS": true, "NULL": true, "LIKE": true, "BETWEEN": true, "EXISTS": true,
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true, "ASC": true, "DESC": true,
+	"FOR": true, "KEY": true, "DUPLICATE": true, "RETURNING": true, "WITH": true, "CALL": true,
+	"TRUE": true, "FALSE": true, "COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+}
+
+// FormatSQL 按照映射文件格式化时的规则格式化SQL文本，子句关键字另起一行，各行之间使用换行符分隔
+func FormatSQL(text string, opts ...FormatOption) string {
+	f := &formatter{indent: "    "}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return strings.Join(f.sqlLines(text), "\n")
+}
+
+// sqlLines 格式化SQL文本，连续的空白字符合并为一个空格，子句关键字另起一行，括号内的子句不换行
+func (f *formatter) sqlLines(text string) (lines []string) {
+	var line []string
+	flush := func() {
+		if len(line) > 0 {
+			lines = append(lines, strings.Join(line, " "))
+			line = nil
+		}
+	}
+
+	depth, prev := 0, ""
+	for _, t := range sqlTokens(text) {
+		token, upper := t.text, strings.ToUpper(t.text)
+		if isWord(token) {
+			if depth == 0 && clauseKeywords[upper] && !joinedKeywords[prev] && t.space {
+				flush()
+			}
+			if sqlKeywords[upper] {
+				token = f.keyword(token)
+			}
+			prev = upper
+		} else {
+			prev = ""
+		}
+
+		switch {
+		case token == "(":
+			depth++
+		case token == ")" && depth > 0:
+			depth--
+		}
+		// 原文中没有空白字符分隔的token保持相连
+		if n := len(line); n > 0 && !t.space {
+			line[n-1] += token
+		} else {
+			line = append(line, token)
+		}
+		if strings.HasPrefix(token, "--") {
+			flush()
+		}
+	}
+	flush()
+	return
+}
+
+func (f *formatter) keyword(word string) string {
+	switch f.keywordCase {
+	case KeywordUpper:
+		return strings.ToUpper(word)
+	case KeywordLower:
+		return strings.ToLower(word)
+	}
+	return word
+}
+
+func isWord(token string) bool {
+	c := token[0]
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// sqlToken SQL文本中的token，space表示原文中前面是否有空白字符
+type sqlToken struct {
+	text  string
+	space bool
+}
+
+// sqlTokens 将SQL文本拆分为不含空白字符的token，#{}、${}、字符串、带引号的标识符和注释作为一个整体
+func sqlTokens(text string) (tokens []sqlToken) {
+	space := false
+	for i := 0; i < len(text); {
+		c := text[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			space = true
+			continue
+		case (c == '#' || c == '$') && i+1 < len(text) && text[i+1] == '{':
+			i = indexFrom(text, i, "}") + 1
+		case c == '\'' || c == '"' || c == '`':
+			i = closingQuote(text, i)
+		case strings.HasPrefix(text[i:], "--"):
+			i = indexFrom(text, i, "\n")
+		case strings.HasPrefix(text[i:], "/*"):
+			i = indexFrom(text, i+2, "*/") + 2
+		case c == '_' || isAlnum(c) || c >= 0x80:
+			for i < len(text) && (text[i] == '_' || isAlnum(text[i]) || text[i] >= 0x80) {
+				i++
+			}
+		default:
+			i++
+		}
+		if i > len(text) {
+			i = len(text)
+		}
+		tokens = append(tokens, sqlToken{text: text[start:i], space: space})
+		space = false
+	}
+	return
+}
+
+// indexFrom 获取从from开始sub的下标，不存在时返回文本长度
+func indexFrom(text string, from int, sub string) int {
+	if j := strings.Index(text[from:], sub); j >= 0 {
+		return from + j
+	}
+	return len(text)
+}
+
+// closingQuote 获取从i开始的引号结束后的下标，两个连续的引号表示转义
+func closingQuote(text string, i int) int {
+	quote := text[i]
+	for j := i + 1; j < len(text); j++ {
+		if text[j] == '\\' && quote != '`' {
+			j++
+			continue
+		}
+		if text[j] == quote {
+			if j+1 < len(text) && text[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(text)
+}
+
+func isAlnum(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
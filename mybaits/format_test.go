@@ -0,0 +1,144 @@
+package mybaits
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	data, err := os.ReadFile("testdata/format.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/format_expected.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Format(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Format() = \n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormat_notMapper(t *testing.T) {
+	if _, err := Format([]byte(`<configuration/>`)); err != ErrNotMapper {
+		t.Errorf("Format() error = %v, want %v", err, ErrNotMapper)
+	}
+}
+
+func TestFormatSQL(t *testing.T) {
+	got := FormatSQL("select id,  name from fruits  where name = ? order by id", WithKeywordCase(KeywordUpper))
+	want := "SELECT id, name\nFROM fruits\nWHERE name = ?\nORDER BY id"
+	if got != want {
+		t.Errorf("FormatSQL() = %q, want %q", got, want)
+	}
+}
+
+// TestFormat_roundTrip 格式化所有测试用的映射文件，格式化是幂等的，并且语句不会改变
+func TestFormat_roundTrip(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formatted, err := Format(data, WithKeywordCase(KeywordPreserve))
+			if err == ErrNotMapper {
+				t.Skip("not mapper")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := Format(formatted, WithKeywordCase(KeywordPreserve))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(formatted) {
+				t.Errorf("Format() is not idempotent:\n%s\n%s", formatted, again)
+			}
+
+			old, err := NewMapperFromBytes(file, data)
+			if err != nil {
+				t.Skip(err)
+			}
+			m, err := NewMapperFromBytes(file, formatted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range old.IDs() {
+				want, err := old.GetStatement(id)
+				if err != nil {
+					continue
+				}
+				got, err := m.GetStatement(id)
+				if err != nil {
+					t.Fatalf("GetStatement(%v) error = %v", id, err)
+				}
+				if strings.Join(strings.Fields(got), " ") != strings.Join(strings.Fields(want), " ") {
+					t.Errorf("GetStatement(%v) = %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestFormat_adjacent 文本和标签之间没有空白字符时格式化不能改变绑定后的SQL
+func TestFormat_adjacent(t *testing.T) {
+	data := []byte(`<mapper namespace="Adjacent">
+  <sql id="tbl">orders</sql>
+  <sql id="col">status</sql>
+  <select id="selectOrders">
+    select id from sales.<include refid="tbl"/> where log_<include refid="col"/> = #{status}
+    and name like '%'||<if test="name != null">#{name}</if>||'%' order by o.<choose><when test="sort != null">${sort}</when><otherwise>id</otherwise></choose>
+  </select>
+</mapper>`)
+	formatted, err := Format(data, WithKeywordCase(KeywordPreserve))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Format(formatted, WithKeywordCase(KeywordPreserve))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(formatted) {
+		t.Errorf("Format() is not idempotent:\n%s\n%s", formatted, again)
+	}
+
+	normalize := func(s string) string {
+		return strings.TrimSpace(whitespaceRegex.ReplaceAllString(s, " "))
+	}
+	old, err := NewMapperFromBytes("adjacent.xml", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMapperFromBytes("adjacent.xml", formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, param := range []map[string]interface{}{
+		{"status": 1, "name": "apple", "sort": "name"},
+		{"status": 1},
+	} {
+		want, err := old.Bind("selectOrders", param)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.Bind("selectOrders", param)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if normalize(got.SQL) != normalize(want.SQL) || !strings.Contains(got.SQL, "sales.orders") {
+			t.Errorf("Bind() SQL = %v, want %v", normalize(got.SQL), normalize(want.SQL))
+		}
+	}
+}
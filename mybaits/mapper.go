@@ -1,7 +1,10 @@
 package mybaits
 
 import (
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
@@ -10,7 +13,29 @@ import (
 )
 
 type Mapper struct {
-	root map[string]*etree.Element
+	root       map[string]*etree.Element
+	ids        []string
+	lines      map[string]int
+	path       string
+	namespace  string
+	databaseID string
+
+	databaseIDProvider DatabaseIDProvider
+	properties         *Properties
+	mocker             *mocker
+	placeholder        PlaceholderStyle
+	resultMaps         map[string]*ResultMap
+}
+
+// MapperOption 映射文件的加载选项
+type MapperOption func(m *Mapper)
+
+// WithDatabaseIDProvider 设置数据库标识提供者，加载时会选择databaseId和当前数据库标识一致的语句，
+// 没有的情况下选择未设置databaseId的语句
+func WithDatabaseIDProvider(provider DatabaseIDProvider) MapperOption {
+	return func(m *Mapper) {
+		m.databaseIDProvider = provider
+	}
 }
 
 var queryTypes = map[string]struct{}{
@@ -21,12 +46,21 @@ var queryTypes = map[string]struct{}{
 	"delete": struct{}{},
 }
 
-func NewMapper(xmlPath string) (mapper *Mapper, err error) {
+func NewMapper(xmlPath string, opts ...MapperOption) (mapper *Mapper, err error) {
 	var data []byte
 	if data, err = os.ReadFile(xmlPath); err != nil {
 		err = fmt.Errorf("ReadFile fail. err: %v", err)
 		return
 	}
+	return newMapper(xmlPath, data, opts...)
+}
+
+// NewMapperFromBytes 从映射文件的内容data中加载映射文件，name用于标识映射文件的来源
+func NewMapperFromBytes(name string, data []byte, opts ...MapperOption) (*Mapper, error) {
+	return newMapper(name, data, opts...)
+}
+
+func newMapper(xmlPath string, data []byte, opts ...MapperOption) (mapper *Mapper, err error) {
 	rawText := replaceCDATA(string(data))
 
 	doc := etree.NewDocument()
@@ -35,17 +69,66 @@ func NewMapper(xmlPath string) (mapper *Mapper, err error) {
 		return
 	}
 
+	root := doc.Root()
+	if root == nil {
+		err = fmt.Errorf("%v has no root element", xmlPath)
+		return
+	}
+
+	var lines []int
+	if lines, err = childLines(rawText); err != nil {
+		return
+	}
+
 	mapper = &Mapper{
-		root: make(map[string]*etree.Element),
+		root:       make(map[string]*etree.Element),
+		lines:      make(map[string]int),
+		path:       xmlPath,
+		resultMaps: make(map[string]*ResultMap),
+	}
+	for _, opt := range opts {
+		opt(mapper)
 	}
 
-	root := doc.Root()
+	if mapper.mocker != nil {
+		if err = validateSamples(mapper.mocker.samples); err != nil {
+			return nil, err
+		}
+	}
+
+	if mapper.databaseIDProvider != nil {
+		if mapper.databaseID, err = mapper.databaseIDProvider.DatabaseID(); err != nil {
+			return nil, fmt.Errorf("DatabaseID fail. err: %v", err)
+		}
+	}
+
+	if mapper.properties != nil {
+		if err = mapper.properties.apply(root); err != nil {
+			return nil, err
+		}
+	}
+	mapper.namespace = root.SelectAttrValue("namespace", "")
 
-	for _, child := range root.ChildElements() {
+	for i, child := range root.ChildElements() {
+		if child.Tag == "resultMap" {
+			var rm *ResultMap
+			if rm, err = parseResultMap(child); err != nil {
+				return nil, err
+			}
+			mapper.resultMaps[rm.ID] = rm
+			continue
+		}
 		if _, ok := queryTypes[child.Tag]; ok {
 			id := child.SelectAttrValue("id", "")
-			if id != "" {
+			if id != "" && mapper.matchDatabaseID(id, child) {
+				if _, ok := mapper.root[id]; !ok {
+					mapper.ids = append(mapper.ids, id)
+				}
+				if err = validateParams(child); err != nil {
+					return nil, fmt.Errorf("statement(%v) at line %v is invalid. err: %v", id, lines[i], err)
+				}
 				mapper.root[id] = child
+				mapper.lines[id] = lines[i]
 			}
 		}
 	}
@@ -53,6 +136,89 @@ func NewMapper(xmlPath string) (mapper *Mapper, err error) {
 	return
 }
 
+// childLines 按顺序获取根元素下所有子元素所在的行号
+func childLines(rawText string) (lines []int, err error) {
+	decoder := xml.NewDecoder(strings.NewReader(rawText))
+	depth := 0
+	for {
+		offset := decoder.InputOffset()
+		var token xml.Token
+		if token, err = decoder.Token(); err != nil {
+			break
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			if depth == 1 {
+				lines = append(lines, strings.Count(rawText[:offset], "\n")+1)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return
+}
+
+// newChildMapper 生成用于转换语句child的childMapper
+func (m *Mapper) newChildMapper(child *etree.Element) *childMapper {
+	return &childMapper{
+		child:      child,
+		root:       m.root,
+		databaseID: m.databaseID,
+		mocker:     m.mocker,
+	}
+}
+
+// GetStatement 获取id对应的规范化后的语句
+func (m *Mapper) GetStatement(id string) (string, error) {
+	child, ok := m.root[id]
+	if !ok {
+		return "", fmt.Errorf("statement(%v) not found", id)
+	}
+	return m.newChildMapper(child).getStatement()
+}
+
+// Path 获取映射文件的路径
+func (m *Mapper) Path() string {
+	return m.path
+}
+
+// Line 获取id对应的语句在映射文件中的行号，找不到时返回0
+func (m *Mapper) Line(id string) int {
+	return m.lines[id]
+}
+
+// matchDatabaseID 判断child是否可以作为id对应的语句，和mybatis一样，
+// databaseId和当前数据库标识一致的语句优先于未设置databaseId的语句
+func (m *Mapper) matchDatabaseID(id string, child *etree.Element) bool {
+	databaseID := child.SelectAttrValue("databaseId", "")
+	if databaseID != "" {
+		return databaseID == m.databaseID
+	}
+	if exist, ok := m.root[id]; ok {
+		return exist.SelectAttrValue("databaseId", "") == ""
+	}
+	return true
+}
+
+// DatabaseID 获取加载时使用的数据库标识
+func (m *Mapper) DatabaseID() string {
+	return m.databaseID
+}
+
+// Namespace 获取映射文件的命名空间
+func (m *Mapper) Namespace() string {
+	return m.namespace
+}
+
+// IDs 按文档顺序获取所有语句以及sql片段的ID
+func (m *Mapper) IDs() []string {
+	return append([]string(nil), m.ids...)
+}
+
 type MapperStmt struct {
 	ID   string
 	Stmt string
@@ -76,12 +242,10 @@ func (m *Mapper) GetRawStatement() (fullStmt string, err error) {
 }
 
 func (m *Mapper) GetStatements() (mstmts []MapperStmt, err error) {
-	for id, child := range m.root {
+	for _, id := range m.ids {
+		child := m.root[id]
 		if child.Tag != "sql" {
-			cm := &childMapper{
-				child: child,
-				root:  m.root,
-			}
+			cm := m.newChildMapper(child)
 			stmt, err := cm.getStatement()
 			if err != nil {
 				return nil, err
@@ -51,3 +51,19 @@ func Test_Mapper_GetRawStatement(t *testing.T) {
 	}
 	t.Log(stmt)
 }
+
+func TestNewMapperFromBytes_anyRoot(t *testing.T) {
+	m, err := NewMapperFromBytes("sqls.xml", []byte(`<sqls>
+    <select id="selectOne">select 1</select>
+</sqls>`))
+	if err != nil {
+		t.Fatalf("NewMapperFromBytes() error = %v", err)
+	}
+	if _, err = m.GetStatement("selectOne"); err != nil {
+		t.Errorf("GetStatement() error = %v", err)
+	}
+
+	if _, err = NewMapperFromBytes("empty.xml", []byte(`<?xml version="1.0"?>`)); err == nil {
+		t.Errorf("NewMapperFromBytes() error = nil, want no root element")
+	}
+}
@@ -0,0 +1,235 @@
+package mybaits
+
+import (
+	"encoding/json"
+	"expvar"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultLatencyBuckets 默认的耗时直方图的上界
+var defaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+}
+
+// Metrics 按照语句ID(命名空间.ID)统计会话执行语句的次数、出错次数、行数和耗时直方图，
+// 实现了expvar.Var和http.Handler，可以通过Publish发布到expvar，也可以作为HTTP处理器展示统计表格
+type Metrics struct {
+	buckets []time.Duration
+
+	mu    sync.Mutex
+	stats map[string]*StatementStats
+}
+
+// StatementStats 一个语句的执行统计
+type StatementStats struct {
+	ID      string          `json:"id"`
+	Calls   int64           `json:"calls"`
+	Errors  int64           `json:"errors"`
+	Rows    int64           `json:"rows"`  // 查询返回或者影响的行数之和
+	Total   time.Duration   `json:"total"` // 单位纳秒
+	Max     time.Duration   `json:"max"`
+	Latency []LatencyBucket `json:"latency"`
+}
+
+// LatencyBucket 耗时直方图的区间，统计耗时不大于Le并且大于上一个区间Le的次数，Le为0时表示没有上界
+type LatencyBucket struct {
+	Le    time.Duration `json:"le"`
+	Count int64         `json:"count"`
+}
+
+// Mean 获取平均耗时
+func (s StatementStats) Mean() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// NewMetrics 生成语句的执行统计，buckets为耗时直方图各个区间的上界，需要从小到大排列，
+// 为空时使用1ms, 5ms, 10ms, 50ms, 100ms, 500ms, 1s, 5s，超过最大上界的耗时统计在没有上界的区间
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = defaultLatencyBuckets
+	}
+	return &Metrics{
+		buckets: buckets,
+		stats:   make(map[string]*StatementStats),
+	}
+}
+
+// WithMetrics 设置会话的执行统计，多个会话可以共用同一个统计
+func WithMetrics(m *Metrics) SessionOption {
+	return func(s *Session) {
+		s.metrics = m
+	}
+}
+
+// record 记录语句id的一次执行，rows小于0时表示行数未知
+func (m *Metrics) record(id string, elapsed time.Duration, rows int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.stats[id]
+	if !ok {
+		s = &StatementStats{ID: id, Latency: make([]LatencyBucket, len(m.buckets)+1)}
+		for i, b := range m.buckets {
+			s.Latency[i].Le = b
+		}
+		m.stats[id] = s
+	}
+
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	if rows > 0 {
+		s.Rows += rows
+	}
+	s.Total += elapsed
+	if elapsed > s.Max {
+		s.Max = elapsed
+	}
+	i := sort.Search(len(m.buckets), func(i int) bool { return elapsed <= m.buckets[i] })
+	s.Latency[i].Count++
+}
+
+// Get 获取语句id的执行统计，id是命名空间.ID，没有执行过时返回false
+func (m *Metrics) Get(id string) (StatementStats, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.stats[id]
+	if !ok {
+		return StatementStats{}, false
+	}
+	return s.copy(), true
+}
+
+// Snapshot 获取所有语句的执行统计，按照语句ID排序
+func (m *Metrics) Snapshot() []StatementStats {
+	m.mu.Lock()
+	snapshot := make([]StatementStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		snapshot = append(snapshot, s.copy())
+	}
+	m.mu.Unlock()
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].ID < snapshot[j].ID
+	})
+	return snapshot
+}
+
+// Reset 清空所有统计
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = make(map[string]*StatementStats)
+}
+
+func (s *StatementStats) copy() StatementStats {
+	c := *s
+	c.Latency = append([]LatencyBucket(nil), s.Latency...)
+	return c
+}
+
+// String 以JSON格式输出所有语句的执行统计，键是语句ID，用于实现expvar.Var
+func (m *Metrics) String() string {
+	stats := make(map[string]StatementStats)
+	for _, s := range m.Snapshot() {
+		stats[s.ID] = s
+	}
+	data, _ := json.Marshal(stats)
+	return string(data)
+}
+
+// Publish 以名字name发布到expvar，和expvar.Publish一样，name重复时会panic
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, m)
+}
+
+// metricsColumns 表格中可以排序的列以及比较函数
+var metricsColumns = []struct {
+	key   string
+	title string
+	less  func(a, b StatementStats) bool
+}{
+	{"id", "Statement", func(a, b StatementStats) bool { return a.ID < b.ID }},
+	{"calls", "Calls", func(a, b StatementStats) bool { return a.Calls < b.Calls }},
+	{"errors", "Errors", func(a, b StatementStats) bool { return a.Errors < b.Errors }},
+	{"rows", "Rows", func(a, b StatementStats) bool { return a.Rows < b.Rows }},
+	{"total", "Total", func(a, b StatementStats) bool { return a.Total < b.Total }},
+	{"mean", "Mean", func(a, b StatementStats) bool { return a.Mean() < b.Mean() }},
+	{"max", "Max", func(a, b StatementStats) bool { return a.Max < b.Max }},
+}
+
+var metricsTemplate = template.Must(template.New("metrics").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>mybaits statements</title></head>
+<body>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr>{{range .Columns}}<th><a href="?sort={{.Key}}&amp;order={{.Order}}">{{.Title}}</a></th>{{end}}{{range .Buckets}}<th>{{.}}</th>{{end}}</tr>
+{{range .Stats}}<tr><td>{{.ID}}</td><td>{{.Calls}}</td><td>{{.Errors}}</td><td>{{.Rows}}</td><td>{{.Total}}</td><td>{{.Mean}}</td><td>{{.Max}}</td>{{range .Latency}}<td>{{.Count}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// ServeHTTP 以HTML表格展示所有语句的执行统计，参数sort为排序的列(id, calls, errors, rows, total, mean, max)，
+// 默认为calls，参数order为asc或者desc，默认为desc，参数format为json时输出和String相同的JSON
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(m.String()))
+		return
+	}
+
+	key, order := query.Get("sort"), query.Get("order")
+	if key == "" {
+		key = "calls"
+	}
+	if order != "asc" {
+		order = "desc"
+	}
+
+	stats := m.Snapshot()
+	type column struct{ Key, Title, Order string }
+	var columns []column
+	for _, c := range metricsColumns {
+		next := "desc"
+		if c.key == key {
+			less := c.less
+			sort.SliceStable(stats, func(i, j int) bool {
+				if order == "asc" {
+					return less(stats[i], stats[j])
+				}
+				return less(stats[j], stats[i])
+			})
+			if order == "desc" {
+				next = "asc"
+			}
+		}
+		columns = append(columns, column{Key: c.key, Title: c.title, Order: next})
+	}
+
+	var buckets []string
+	for _, b := range m.buckets {
+		buckets = append(buckets, "≤"+b.String())
+	}
+	if len(m.buckets) > 0 {
+		buckets = append(buckets, ">"+m.buckets[len(m.buckets)-1].String())
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := metricsTemplate.Execute(w, map[string]interface{}{
+		"Columns": columns,
+		"Buckets": buckets,
+		"Stats":   stats,
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
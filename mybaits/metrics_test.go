@@ -0,0 +1,130 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession_metrics(t *testing.T) {
+	metrics := NewMetrics()
+	s, d := newTestSession(t, WithMetrics(metrics))
+	calls := 0
+	d.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		if calls++; calls == 2 {
+			return nil, errors.New("connection reset")
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		var titles []string
+		if err := s.SelectList(ctx, "selectTitles", nil, &titles); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		s.Exec(ctx, "updateTitle", map[string]interface{}{"id": 1, "title": "go"})
+	}
+
+	var got []StatementStats
+	for _, st := range metrics.Snapshot() {
+		var count int64
+		for _, b := range st.Latency {
+			count += b.Count
+		}
+		if count != st.Calls || len(st.Latency) != len(defaultLatencyBuckets)+1 {
+			t.Errorf("%v latency = %+v, calls %v", st.ID, st.Latency, st.Calls)
+		}
+		got = append(got, StatementStats{ID: st.ID, Calls: st.Calls, Errors: st.Errors, Rows: st.Rows})
+	}
+	want := []StatementStats{
+		{ID: "Session.selectTitles", Calls: 3, Rows: 6},
+		{ID: "Session.updateTitle", Calls: 3, Errors: 1, Rows: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+
+	if st, ok := metrics.Get("Session.selectTitles"); !ok || st.Calls != 3 {
+		t.Errorf("Get() = %+v, %v", st, ok)
+	}
+	if _, ok := metrics.Get("selectTitles"); ok {
+		t.Errorf("Get() found statement without namespace")
+	}
+	metrics.Reset()
+	if got := metrics.Snapshot(); len(got) != 0 {
+		t.Errorf("Snapshot() after Reset() = %+v", got)
+	}
+}
+
+func TestMetrics_record(t *testing.T) {
+	m := NewMetrics(time.Millisecond, 10*time.Millisecond)
+	for _, d := range []time.Duration{time.Microsecond, time.Millisecond, 2 * time.Millisecond, time.Second} {
+		m.record("fruits.select", d, -1, nil)
+	}
+	st, _ := m.Get("fruits.select")
+	want := []LatencyBucket{{Le: time.Millisecond, Count: 2}, {Le: 10 * time.Millisecond, Count: 1}, {Count: 1}}
+	if !reflect.DeepEqual(st.Latency, want) {
+		t.Errorf("Latency = %+v, want %+v", st.Latency, want)
+	}
+	if st.Rows != 0 || st.Max != time.Second || st.Mean() != (time.Second+3*time.Millisecond+time.Microsecond)/4 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestMetrics_Publish(t *testing.T) {
+	m := NewMetrics()
+	m.record("fruits.select", time.Millisecond, 2, nil)
+	m.Publish("mybaits_test_metrics")
+
+	var got map[string]StatementStats
+	if err := json.Unmarshal([]byte(expvar.Get("mybaits_test_metrics").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if st := got["fruits.select"]; st.Calls != 1 || st.Rows != 2 || st.Total != time.Millisecond {
+		t.Errorf("expvar = %+v", got)
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := NewMetrics()
+	m.record("fruits.hot", time.Millisecond, 1, nil)
+	m.record("fruits.hot", time.Millisecond, 1, nil)
+	m.record("fruits.cold", time.Second, 1, nil)
+
+	tests := []struct {
+		query string
+		order []string
+		want  []string
+	}{
+		{query: "", order: []string{"fruits.hot", "fruits.cold"}, want: []string{`href="?sort=calls&amp;order=asc"`, "<th>≤1ms</th>", "<th>&gt;5s</th>"}},
+		{query: "?sort=calls&order=asc", order: []string{"fruits.cold", "fruits.hot"}, want: []string{`href="?sort=calls&amp;order=desc"`}},
+		{query: "?sort=mean", order: []string{"fruits.cold", "fruits.hot"}},
+		{query: "?sort=id&order=asc", order: []string{"fruits.cold", "fruits.hot"}},
+		{query: "?format=json", order: []string{`"fruits.cold"`, `"fruits.hot"`}, want: []string{`"calls":2`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/mybaits"+tt.query, nil))
+			body := rec.Body.String()
+			if first, second := strings.Index(body, tt.order[0]), strings.Index(body, tt.order[1]); first < 0 || second < first {
+				t.Errorf("body = %v, want %v before %v", body, tt.order[0], tt.order[1])
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(body, w) {
+					t.Errorf("body = %v, want contains %v", body, w)
+				}
+			}
+		})
+	}
+}
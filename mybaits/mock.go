@@ -0,0 +1,204 @@
+package mybaits
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// MockMode 生成语句时参数模拟值的模式
+type MockMode int
+
+// 参数模拟值的模式
+const (
+	MockPlaceholder MockMode = iota // 所有参数都使用?
+	MockTyped                       // 按照jdbcType使用类型正确的字面量，使生成的语句可以直接执行
+)
+
+// mocker 生成参数的模拟值，为nil时使用参数的MockValue
+type mocker struct {
+	mode    MockMode
+	samples map[string]interface{}
+	raw     map[string]string // ${}全文到替换文本的映射，优先于样例值，用于审计时定位${}
+}
+
+// WithMockMode 设置生成语句时参数模拟值的模式
+func WithMockMode(mode MockMode) MapperOption {
+	return func(m *Mapper) {
+		if m.mocker == nil {
+			m.mocker = &mocker{}
+		}
+		m.mocker.mode = mode
+	}
+}
+
+// WithMockSamples 设置参数的样例值，参数名在samples中的参数优先使用样例值，
+// #{}中的字符串会作为字符串字面量，${}中的字符串会原样替换，
+// 样例值只支持nil、字符串、数字、布尔值以及它们组成的列表，其他类型在加载映射文件时报错
+func WithMockSamples(samples map[string]interface{}) MapperOption {
+	return func(m *Mapper) {
+		if m.mocker == nil {
+			m.mocker = &mocker{}
+		}
+		m.mocker.samples = samples
+	}
+}
+
+// LoadMockSamples 从JSON文件filename中读取参数的样例值，文件内容为参数名到样例值的映射
+func LoadMockSamples(filename string) (samples map[string]interface{}, err error) {
+	var data []byte
+	if data, err = os.ReadFile(filename); err != nil {
+		return nil, fmt.Errorf("ReadFile fail. err: %v", err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err = decoder.Decode(&samples); err != nil {
+		return nil, fmt.Errorf("Decode %v fail. err: %v", filename, err)
+	}
+	if err = validateSamples(samples); err != nil {
+		return nil, fmt.Errorf("%v is invalid. err: %v", filename, err)
+	}
+	return
+}
+
+// validateSamples 校验样例值都可以转化为字面量
+func validateSamples(samples map[string]interface{}) error {
+	for name, v := range samples {
+		if err := validateSample(v); err != nil {
+			return fmt.Errorf("sample(%v) is not supported. err: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func validateSample(v interface{}) error {
+	switch t := v.(type) {
+	case nil, string, bool, json.Number:
+		return nil
+	case []interface{}:
+		for _, e := range t {
+			if err := validateSample(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	}
+	return fmt.Errorf("%T has no SQL literal", v)
+}
+
+func (mk *mocker) value(p Param) string {
+	if mk == nil {
+		return p.MockValue
+	}
+	if v, ok := mk.raw[p.FullName]; ok && strings.HasPrefix(p.FullName, "$") {
+		return v
+	}
+	if v, ok := mk.samples[p.Name]; ok {
+		return sampleLiteral(v, strings.HasPrefix(p.FullName, "$"))
+	}
+	return mockValue(p, mk.mode)
+}
+
+// mockValue 获取参数p在模式mode下的模拟值，MockTyped模式下#{}使用类型正确的字面量，
+// 没有类型的${}通常是表名、列名或者排序子句，使用mock_加属性名的标识符，如${sort.column}为mock_column
+func mockValue(p Param, mode MockMode) string {
+	if mode != MockTyped {
+		return "?"
+	}
+	if strings.HasPrefix(p.FullName, "$") && p.JdbcType == "" && p.JavaType == "" {
+		return mockIdent(p.Name)
+	}
+	return typedMockValue(p.JdbcType, p.JavaType)
+}
+
+// mockIdent 生成属性name对应的标识符，只保留属性路径最后一段中的字母、数字和下划线
+func mockIdent(name string) string {
+	name = strings.TrimSpace(name)
+	b := &strings.Builder{}
+	b.WriteString("mock_")
+	for _, c := range name[strings.LastIndex(name, ".")+1:] {
+		if c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// typedMockValue 获取jdbcType对应类型的字面量，没有jdbcType时根据javaType推断
+func typedMockValue(jdbcType, javaType string) string {
+	switch jdbcType {
+	case "DATE":
+		return "'2000-01-01'"
+	case "TIME":
+		return "'00:00:00'"
+	case "TIMESTAMP":
+		return "'2000-01-01 00:00:00'"
+	case "REAL":
+		return "1.0"
+	case "CLOB", "NCLOB":
+		return "'mock'"
+	}
+
+	for group, types := range jdbcTypes {
+		for _, t := range types {
+			if t != jdbcType {
+				continue
+			}
+			switch group {
+			case "NUM":
+				return "1"
+			case "BOOLEAN":
+				return "true"
+			case "STRING":
+				return "'mock'"
+			case "BINARY":
+				return "X'00'"
+			}
+			return "NULL"
+		}
+	}
+
+	switch strings.TrimPrefix(javaType, "java.lang.") {
+	case "int", "Integer", "long", "Long", "short", "Short", "byte", "Byte",
+		"double", "Double", "float", "Float", "BigDecimal", "java.math.BigDecimal", "BigInteger", "java.math.BigInteger":
+		return "1"
+	case "boolean", "Boolean":
+		return "true"
+	case "String":
+		return "'mock'"
+	case "Date", "java.util.Date", "java.sql.Date", "LocalDate", "java.time.LocalDate":
+		return "'2000-01-01'"
+	case "LocalDateTime", "java.time.LocalDateTime", "Timestamp", "java.sql.Timestamp":
+		return "'2000-01-01 00:00:00'"
+	}
+	// 字符串字面量可以和大多数类型比较
+	return "'1'"
+}
+
+// sampleLiteral 将样例值v转化为字面量，raw为true时字符串不加引号，v需要通过validateSample的校验
+func sampleLiteral(v interface{}, raw bool) string {
+	switch t := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		if raw {
+			return t
+		}
+		return "'" + strings.ReplaceAll(t, "'", "''") + "'"
+	case []interface{}:
+		var values []string
+		for _, e := range t {
+			values = append(values, sampleLiteral(e, raw))
+		}
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprint(v)
+}
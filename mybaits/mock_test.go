@@ -0,0 +1,174 @@
+package mybaits
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMapper_GetStatement_mock(t *testing.T) {
+	samples, err := LoadMockSamples("testdata/mock_samples.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []MapperOption
+		id       string
+		wantStmt string
+	}{
+		{
+			name:     "placeholder",
+			opts:     []MapperOption{WithMockMode(MockPlaceholder)},
+			id:       "testParameters",
+			wantStmt: "select name, category, price from fruits where category = :v1 and price > :v2 and type = :v3 and content = :v4",
+		},
+		{
+			name:     "typed",
+			opts:     []MapperOption{WithMockMode(MockTyped)},
+			id:       "testParameters",
+			wantStmt: "select name, category, price from fruits where category = 'mock' and price > 1 and type = true and content = X'00'",
+		},
+		{
+			name:     "typedWithoutJdbcType",
+			opts:     []MapperOption{WithMockMode(MockTyped)},
+			id:       "testIf",
+			wantStmt: "select name, category, price from fruits where 1 = 1 and category = '1' and price = mock_price and name = 'Fuji'",
+		},
+		{
+			name:     "samples",
+			opts:     []MapperOption{WithMockMode(MockTyped), WithMockSamples(samples)},
+			id:       "testParameters",
+			wantStmt: "select name, category, price from fruits where category = 'app\\'le' and price > 10 and type = 10 and content = 10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMapper("testdata/test.xml", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotStmt, err := m.GetStatement(tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotStmt != tt.wantStmt {
+				t.Errorf("Mapper.GetStatement() = %v, want %v", gotStmt, tt.wantStmt)
+			}
+		})
+	}
+}
+
+func Test_typedMockValue(t *testing.T) {
+	tests := []struct {
+		jdbcType string
+		javaType string
+		want     string
+	}{
+		{jdbcType: "INTEGER", want: "1"},
+		{jdbcType: "DECIMAL", want: "1"},
+		{jdbcType: "BOOLEAN", want: "true"},
+		{jdbcType: "DATE", want: "'2000-01-01'"},
+		{jdbcType: "TIME", want: "'00:00:00'"},
+		{jdbcType: "TIMESTAMP", want: "'2000-01-01 00:00:00'"},
+		{jdbcType: "VARCHAR", want: "'mock'"},
+		{jdbcType: "VARBINARY", want: "X'00'"},
+		{jdbcType: "REAL", want: "1.0"},
+		{jdbcType: "CLOB", want: "'mock'"},
+		{jdbcType: "NCLOB", want: "'mock'"},
+		{jdbcType: "OTHER", want: "NULL"},
+		{javaType: "java.lang.Long", want: "1"},
+		{javaType: "String", want: "'mock'"},
+		{javaType: "com.example.Fruit", want: "'1'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.jdbcType+tt.javaType, func(t *testing.T) {
+			if got := typedMockValue(tt.jdbcType, tt.javaType); got != tt.want {
+				t.Errorf("typedMockValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mockValue(t *testing.T) {
+	tests := []struct {
+		token string
+		mode  MockMode
+		want  string
+	}{
+		{token: "#{price,jdbcType=INTEGER}", mode: MockPlaceholder, want: "?"},
+		{token: "${table}", mode: MockPlaceholder, want: "?"},
+		{token: "#{price,jdbcType=INTEGER}", mode: MockTyped, want: "1"},
+		{token: "#{name}", mode: MockTyped, want: "'1'"},
+		{token: "${table}", mode: MockTyped, want: "mock_table"},
+		{token: "${ sort.column }", mode: MockTyped, want: "mock_column"},
+		{token: "${limit,javaType=int}", mode: MockTyped, want: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			p, err := ParseParam(tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := mockValue(p, tt.mode); got != tt.want {
+				t.Errorf("mockValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sampleLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		raw  bool
+		want string
+	}{
+		{name: "nil", v: nil, want: "NULL"},
+		{name: "string", v: "it's", want: "'it''s'"},
+		{name: "raw", v: "name desc", raw: true, want: "name desc"},
+		{name: "bool", v: true, want: "true"},
+		{name: "list", v: []interface{}{"Fuji", 2}, want: "'Fuji', 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sampleLiteral(tt.v, tt.raw); got != tt.want {
+				t.Errorf("sampleLiteral() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithMockSamples_unsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples map[string]interface{}
+		wantErr bool
+	}{
+		{name: "scalars", samples: map[string]interface{}{"name": "Fuji", "price": 1.5, "id": int64(1), "ok": true, "none": nil}},
+		{name: "list", samples: map[string]interface{}{"name": []interface{}{"Fuji", json.Number("2")}}},
+		{name: "map", samples: map[string]interface{}{"name": map[string]interface{}{"a": 1}}, wantErr: true},
+		{name: "mapInList", samples: map[string]interface{}{"name": []interface{}{map[string]interface{}{"a": 1}}}, wantErr: true},
+		{name: "struct", samples: map[string]interface{}{"name": struct{ A int }{1}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMapper("testdata/test.xml", WithMockMode(MockTyped), WithMockSamples(tt.samples))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewMapper() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMockSamples_unsupported(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "samples.json")
+	if err := os.WriteFile(filename, []byte(`{"name": {"a": 1}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMockSamples(filename); err == nil {
+		t.Errorf("LoadMockSamples() error = nil, want map sample to be unsupported")
+	}
+}
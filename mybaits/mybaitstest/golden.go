@@ -0,0 +1,251 @@
+// Package mybaitstest 提供映射文件渲染结果的golden文件测试，
+// 使用go test -mybaitstest.update重新生成golden文件，测试包自己定义了-update时也可以使用-update
+package mybaitstest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Breeze0806/go/mybaits"
+)
+
+// update 带有包名前缀，避免和测试包自己定义的-update冲突
+var update = flag.Bool("mybaitstest.update", false, "regenerate golden files of mybaitstest")
+
+// updating 判断是否重新生成golden文件，测试包定义的bool类型的-update也会生效
+func updating() bool {
+	if *update {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		if getter, ok := f.Value.(flag.Getter); ok {
+			b, _ := getter.Get().(bool)
+			return b
+		}
+	}
+	return false
+}
+
+// Case 一组渲染语句的参数
+type Case struct {
+	Name  string      // 用例名，在golden文件中唯一，为空时使用ID
+	ID    string      // 语句ID
+	Param interface{} // 语句的参数
+}
+
+// Rendered 语句渲染的结果
+type Rendered struct {
+	Name string
+	SQL  string // 格式化后的SQL
+	Args string // JSON格式的参数值
+}
+
+const (
+	casePrefix = "-- case: "
+	argsPrefix = "-- args: "
+)
+
+// Render 使用cases中的参数渲染m中的语句
+func Render(m *mybaits.Mapper, cases []Case) ([]Rendered, error) {
+	var rendered []Rendered
+	seen := make(map[string]bool)
+	for _, c := range cases {
+		name := c.Name
+		if name == "" {
+			name = c.ID
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("case(%v) is duplicated", name)
+		}
+		seen[name] = true
+
+		bound, err := m.Bind(c.ID, c.Param)
+		if err != nil {
+			return nil, fmt.Errorf("case(%v) bind fail. err: %v", name, err)
+		}
+		args := bound.Args
+		if args == nil {
+			args = []interface{}{}
+		}
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("case(%v) marshal args fail. err: %v", name, err)
+		}
+		rendered = append(rendered, Rendered{
+			Name: name,
+			SQL:  mybaits.FormatSQL(bound.SQL, mybaits.WithKeywordCase(mybaits.KeywordPreserve)),
+			Args: string(data),
+		})
+	}
+	return rendered, nil
+}
+
+// AssertGolden 使用cases渲染m中的语句并和golden文件比较，比较时忽略字符串以外的空白字符，
+// 不一致时输出SQL的差异，使用-mybaitstest.update时重新生成golden文件
+func AssertGolden(t testing.TB, m *mybaits.Mapper, golden string, cases []Case) {
+	t.Helper()
+	got, err := Render(m, cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updating() {
+		if err = os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(golden, []byte(Encode(got)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("golden file %v is updated", golden)
+		return
+	}
+
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file fail, run go test -mybaitstest.update to create it. err: %v", err)
+	}
+	want := make(map[string]Rendered)
+	for _, r := range Decode(string(data)) {
+		want[r.Name] = r
+	}
+
+	for _, g := range got {
+		w, ok := want[g.Name]
+		if !ok {
+			t.Errorf("case(%v) is not in golden file %v, run go test -mybaitstest.update to add it", g.Name, golden)
+			continue
+		}
+		delete(want, g.Name)
+		if normalize(w.SQL) != normalize(g.SQL) {
+			t.Errorf("case(%v) sql differs from golden file %v (-want +got):\n%v", g.Name, golden, Diff(w.SQL, g.SQL))
+		}
+		if w.Args != g.Args {
+			t.Errorf("case(%v) args differ from golden file %v:\n- %v\n+ %v", g.Name, golden, w.Args, g.Args)
+		}
+	}
+	for name := range want {
+		t.Errorf("case(%v) in golden file %v is stale, run go test -mybaitstest.update to remove it", name, golden)
+	}
+}
+
+// Encode 将渲染结果编码为golden文件的内容，每个用例以"-- case: 用例名"开始
+func Encode(rendered []Rendered) string {
+	b := &strings.Builder{}
+	for i, r := range rendered {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(casePrefix + r.Name + "\n")
+		b.WriteString(argsPrefix + r.Args + "\n")
+		b.WriteString(r.SQL + "\n")
+	}
+	return b.String()
+}
+
+// Decode 解析golden文件的内容
+func Decode(content string) (rendered []Rendered) {
+	var current *Rendered
+	var sql []string
+	flush := func() {
+		if current != nil {
+			current.SQL = strings.TrimSpace(strings.Join(sql, "\n"))
+			rendered = append(rendered, *current)
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, casePrefix):
+			flush()
+			current = &Rendered{Name: strings.TrimSpace(strings.TrimPrefix(line, casePrefix))}
+			sql = nil
+		case current != nil && current.Args == "" && strings.HasPrefix(line, argsPrefix):
+			current.Args = strings.TrimSpace(strings.TrimPrefix(line, argsPrefix))
+		case current != nil:
+			sql = append(sql, line)
+		}
+	}
+	flush()
+	return
+}
+
+// normalize 合并字符串和带引号的标识符以外的空白字符
+func normalize(sql string) string {
+	b := &strings.Builder{}
+	space := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			space = true
+			continue
+		case '\'', '"', '`':
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			j := i + 1
+			for j < len(sql) && sql[j] != c {
+				if sql[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(sql) {
+				j = len(sql) - 1
+			}
+			b.WriteString(sql[i : j+1])
+			i = j
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Diff 按行比较want和got，相同的行以两个空格开头，删除的行以-开头，新增的行以+开头
+func Diff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+	// lcs[i][j]为a[i:]和b[j:]的最长公共子序列的长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	out := &strings.Builder{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,163 @@
+package mybaitstest
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Breeze0806/go/mybaits"
+)
+
+var fruitCases = []Case{
+	{Name: "byName", ID: "selectFruits", Param: map[string]interface{}{"name": "apple", "orderBy": "name"}},
+	{Name: "byIDs", ID: "selectFruits", Param: map[string]interface{}{"ids": []int{1, 2}, "orderBy": "price"}},
+	{ID: "selectByName", Param: map[string]interface{}{"name": "app"}},
+}
+
+func newFruitMapper(t *testing.T) *mybaits.Mapper {
+	m, err := mybaits.NewMapper("../testdata/bind.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestAssertGolden(t *testing.T) {
+	AssertGolden(t, newFruitMapper(t), "testdata/fruits.golden", fruitCases)
+}
+
+// recorder 记录AssertGolden报告的错误
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// assertGolden 在新的goroutine中执行AssertGolden，Fatalf只结束该goroutine
+func assertGolden(t *testing.T, golden string, cases []Case) []string {
+	r := &recorder{TB: t}
+	m := newFruitMapper(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		AssertGolden(r, m, golden, cases)
+	}()
+	<-done
+	return r.errors
+}
+
+func TestAssertGolden_mismatch(t *testing.T) {
+	cases := []Case{
+		{Name: "byName", ID: "selectFruits", Param: map[string]interface{}{"name": "pear", "minPrice": 3, "orderBy": "name"}},
+		{ID: "selectByName", Param: map[string]interface{}{"name": "app"}},
+		{Name: "new", ID: "selectByID", Param: map[string]interface{}{"anything": 1}},
+	}
+	errors := assertGolden(t, "testdata/fruits.golden", cases)
+
+	want := []string{
+		"case(byName) sql differs",
+		"+ WHERE name = ? AND price >= ?",
+		"case(byName) args differ",
+		"case(new) is not in golden file",
+		"case(byIDs) in golden file testdata/fruits.golden is stale",
+	}
+	all := strings.Join(errors, "\n")
+	for _, w := range want {
+		if !strings.Contains(all, w) {
+			t.Errorf("errors = %v, want %v", all, w)
+		}
+	}
+	if len(errors) != 4 {
+		t.Errorf("errors = %q, want 4 errors", errors)
+	}
+}
+
+func TestAssertGolden_missing(t *testing.T) {
+	errors := assertGolden(t, "testdata/missing.golden", fruitCases)
+	if len(errors) != 1 || !strings.Contains(errors[0], "go test -mybaitstest.update") {
+		t.Errorf("errors = %q", errors)
+	}
+}
+
+func Test_updating(t *testing.T) {
+	commandLine := flag.CommandLine
+	defer func() { flag.CommandLine = commandLine }()
+
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{"none", nil, false},
+		{"update", []string{"-update"}, true},
+		{"updateFalse", []string{"-update=false"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(tt.name, flag.ContinueOnError)
+			flag.Bool("update", false, "")
+			if err := flag.CommandLine.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := updating(); got != tt.want {
+				t.Errorf("updating() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_normalize(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select  a,\n\tb from t", "select a, b from t"},
+		{"select 'a  b'  from \"my  t\"", "select 'a  b' from \"my  t\""},
+		{"  where a = 'it\\'s  x'\n", "where a = 'it\\'s  x'"},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.sql); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := Diff("SELECT a\nFROM t\nWHERE a = ?", "SELECT a, b\nFROM t\nWHERE a = ?\nLIMIT 1")
+	want := "- SELECT a\n+ SELECT a, b\n  FROM t\n  WHERE a = ?\n+ LIMIT 1\n"
+	if got != want {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+}
+
+func TestEncode_Decode(t *testing.T) {
+	rendered, err := Render(newFruitMapper(t), fruitCases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := Decode(Encode(rendered))
+	if len(decoded) != len(rendered) {
+		t.Fatalf("Decode() = %+v", decoded)
+	}
+	for i := range rendered {
+		if decoded[i] != rendered[i] {
+			t.Errorf("Decode()[%v] = %+v, want %+v", i, decoded[i], rendered[i])
+		}
+	}
+
+	if _, err = Render(newFruitMapper(t), append(fruitCases, fruitCases[0])); err == nil {
+		t.Errorf("Render() error = nil, want duplicated case")
+	}
+}
@@ -0,0 +1,445 @@
+package mybaits
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+)
+
+// maxBatchKeys 一条IN查询中键的最大数量，键更多时分成多条查询，使占位符的数量远小于数据库65535的上限
+var maxBatchKeys = 1000
+
+// nestedLoad 一个待执行的嵌套查询，结果赋值给field
+type nestedLoad struct {
+	mapper  *Mapper // 嵌套查询所在resultMap的映射文件
+	mapping NestedMapping
+	field   reflect.Value
+	param   interface{}   // 嵌套查询的参数，组合键时为map[string]interface{}
+	key     []interface{} // 键的列值，和ColumnParams一一对应
+}
+
+// nestedGroup 同一个association或者collection的嵌套查询
+type nestedGroup struct {
+	mapper *Mapper
+	id     string
+	typ    reflect.Type
+	loads  []*nestedLoad
+}
+
+type nestedGroupKey struct {
+	mapper   *Mapper
+	selectID string
+	column   string
+	typ      reflect.Type
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// loadNested 执行嵌套查询并赋值，字段为func() (T, error)或者func(context.Context) (T, error)时延迟加载，
+// 不带context.Context的访问函数使用原查询的ctx，调用时原查询所在的事务已经结束则在事务外查询，
+// 会话直接使用*sql.Tx时事务结束后访问函数会返回错误
+func (s *Session) loadNested(ctx context.Context, pending []*nestedLoad, depth int) error {
+	var groups []*nestedGroup
+	index := make(map[nestedGroupKey]*nestedGroup)
+	for _, load := range pending {
+		if load.field.Kind() == reflect.Func {
+			if err := s.lazyLoad(ctx, load, depth); err != nil {
+				return err
+			}
+			continue
+		}
+
+		m, id, err := s.lookup(load.mapping.Select, load.mapper)
+		if err != nil {
+			return fmt.Errorf("%v(%v) %v", nestedTag(load.mapping), load.mapping.Property, err)
+		}
+		key := nestedGroupKey{mapper: m, selectID: id, column: load.mapping.Column, typ: load.field.Type()}
+		g, ok := index[key]
+		if !ok {
+			g = &nestedGroup{mapper: m, id: id, typ: load.field.Type()}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.loads = append(g.loads, load)
+	}
+
+	for _, g := range groups {
+		if s.batch && len(g.loads) > 1 {
+			ok, err := s.batchLoad(ctx, g, depth)
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+		}
+		if err := s.eachLoad(ctx, g, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lazyLoad 将访问函数设置到字段，fetchType为eager时立即执行嵌套查询
+func (s *Session) lazyLoad(ctx context.Context, load *nestedLoad, depth int) error {
+	ft := load.field.Type()
+	if ft.NumOut() != 2 || ft.Out(1) != errorType || ft.IsVariadic() ||
+		ft.NumIn() > 1 || (ft.NumIn() == 1 && ft.In(0) != contextType) {
+		return fmt.Errorf("%v(%v) accessor must be func() (T, error) or func(context.Context) (T, error)",
+			nestedTag(load.mapping), load.mapping.Property)
+	}
+	m, id, err := s.lookup(load.mapping.Select, load.mapper)
+	if err != nil {
+		return fmt.Errorf("%v(%v) %v", nestedTag(load.mapping), load.mapping.Property, err)
+	}
+
+	var once sync.Once
+	var value reflect.Value
+	var loadErr error
+	fetch := func(ctx context.Context) {
+		once.Do(func() {
+			value, loadErr = s.fetch(ctx, m, id, load.param, ft.Out(0), depth)
+		})
+	}
+	if load.mapping.FetchType == "eager" {
+		if fetch(ctx); loadErr != nil {
+			return loadErr
+		}
+	}
+
+	load.field.Set(reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		fetchCtx := ctx
+		if len(args) == 1 && !args[0].IsNil() {
+			fetchCtx = args[0].Interface().(context.Context)
+		}
+		fetch(fetchCtx)
+		if loadErr != nil {
+			return []reflect.Value{reflect.Zero(ft.Out(0)), reflect.ValueOf(&loadErr).Elem()}
+		}
+		return []reflect.Value{value, reflect.Zero(errorType)}
+	}))
+	return nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// eachLoad 按键逐个执行嵌套查询，相同的键只查询一次
+func (s *Session) eachLoad(ctx context.Context, g *nestedGroup, depth int) error {
+	cache := make(map[string]reflect.Value)
+	for _, load := range g.loads {
+		k := keyString(load.key)
+		v, ok := cache[k]
+		if !ok {
+			var err error
+			if v, err = s.fetch(ctx, g.mapper, g.id, load.param, g.typ, depth); err != nil {
+				return err
+			}
+			cache[k] = v
+		}
+		load.field.Set(v)
+	}
+	return nil
+}
+
+// fetch 执行嵌套查询，typ为切片时是collection，否则是association
+func (s *Session) fetch(ctx context.Context, m *Mapper, id string, param interface{}, typ reflect.Type, depth int) (reflect.Value, error) {
+	if typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8 {
+		values, err := s.query(ctx, m, id, param, typ.Elem(), depth)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return makeSlice(typ, values), nil
+	}
+
+	values, err := s.query(ctx, m, id, param, typ, depth)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	switch len(values) {
+	case 0:
+		return reflect.Zero(typ), nil
+	case 1:
+		return values[0], nil
+	}
+	return reflect.Value{}, fmt.Errorf("nested select(%v) expected one result but found %v", id, len(values))
+}
+
+// batchLoad 将同一组的嵌套查询改写为一条IN查询，无法改写时返回false
+func (s *Session) batchLoad(ctx context.Context, g *nestedGroup, depth int) (bool, error) {
+	if depth > maxNestedDepth {
+		return false, fmt.Errorf("nested select(%v) is deeper than %v", g.id, maxNestedDepth)
+	}
+
+	var keys [][]interface{}
+	seen := make(map[string]bool)
+	for _, load := range g.loads {
+		if k := keyString(load.key); !seen[k] {
+			seen[k] = true
+			keys = append(keys, load.key)
+		}
+	}
+	batches := make([]*batchSQL, 0, (len(keys)+maxBatchKeys-1)/maxBatchKeys)
+	for i := 0; i < len(keys); i += maxBatchKeys {
+		end := i + maxBatchKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch, ok := g.mapper.batchQuery(g.id, g.loads[0].mapping, keys[i:end])
+		if !ok {
+			return false, nil
+		}
+		batches = append(batches, batch)
+	}
+
+	elemType, collection := g.typ, g.typ.Kind() == reflect.Slice && g.typ.Elem().Kind() != reflect.Uint8
+	if collection {
+		elemType = g.typ.Elem()
+	}
+	rmMapper, rm, elemType, err := s.resultOf(g.mapper, g.id, elemType)
+	if err != nil {
+		return false, err
+	}
+
+	results := make(map[string][]reflect.Value)
+	for _, batch := range batches {
+		if err = s.batchFetch(ctx, g, batch, rmMapper, rm, elemType, depth, results); err != nil {
+			return false, err
+		}
+	}
+
+	for _, load := range g.loads {
+		values := results[keyString(load.key)]
+		switch {
+		case collection:
+			load.field.Set(makeSlice(g.typ, values))
+		case len(values) == 0:
+			load.field.Set(reflect.Zero(g.typ))
+		case len(values) == 1:
+			load.field.Set(values[0])
+		default:
+			return false, fmt.Errorf("nested select(%v) expected one result but found %v", g.id, len(values))
+		}
+	}
+	return true, nil
+}
+
+// batchFetch 执行一条改写后的IN查询，将结果按照键加入results
+func (s *Session) batchFetch(ctx context.Context, g *nestedGroup, batch *batchSQL, rmMapper *Mapper, rm *ResultMap,
+	elemType reflect.Type, depth int, results map[string][]reflect.Value) error {
+	bound := &BoundSQL{ID: g.id, SQL: batch.sql, Args: batch.args, Params: batch.params}
+	start := time.Now()
+	rows, err := executor(ctx, s.exec).QueryContext(ctx, batch.sql, batch.args...)
+	if err != nil {
+		s.observe(g.mapper, bound, start, -1, err)
+		return fmt.Errorf("query statement(%v) fail. err: %v", g.id, err)
+	}
+	defer rows.Close()
+
+	skip := make(map[string]bool)
+	for _, c := range batch.keyColumns {
+		skip[c] = true
+	}
+	mapped, pending, err := s.mapRows(rows, rmMapper, rm, elemType, skip)
+	if err != nil {
+		s.observe(g.mapper, bound, start, -1, err)
+		return fmt.Errorf("map statement(%v) fail. err: %v", g.id, err)
+	}
+	s.observe(g.mapper, bound, start, int64(len(mapped)), nil)
+	if err = s.loadNested(ctx, pending, depth+1); err != nil {
+		return err
+	}
+
+	for _, r := range mapped {
+		key := make([]interface{}, len(batch.keyColumns))
+		for i, c := range batch.keyColumns {
+			key[i], _ = r.row.get(c)
+		}
+		k := keyString(key)
+		results[k] = append(results[k], r.value)
+	}
+	return nil
+}
+
+// batchKey 改写IN查询时代替键绑定到语句中的值，值为键的下标，用于找到键对应的条件
+type batchKey int
+
+// batchSQL 改写后的IN查询
+type batchSQL struct {
+	sql        string
+	args       []interface{}
+	params     []Param  // 和args一一对应的参数，键的值使用键对应的参数，用于日志中屏蔽参数值
+	keyColumns []string // 查询结果中键的列名，和键一一对应
+}
+
+// batchQuery 将嵌套查询id改写为查询所有keys的IN查询，只支持where中通过and连接的键等值条件，并且每个键只出现一次，
+// 语句中有group by、having、limit或者distinct时无法改写
+func (m *Mapper) batchQuery(id string, mapping NestedMapping, keys [][]interface{}) (*batchSQL, bool) {
+	var param interface{} = batchKey(0)
+	if len(mapping.ColumnParams) > 0 {
+		params := make(map[string]interface{})
+		for i, cp := range mapping.ColumnParams {
+			params[cp.Param] = batchKey(i)
+		}
+		param = params
+	}
+	bound, err := m.bind(id, param, PlaceholderQuestion)
+	if err != nil {
+		return nil, false
+	}
+	stmt, err := sqlparser.Parse(bound.SQL)
+	if err != nil {
+		return nil, false
+	}
+	sel, ok := stmt.(*sqlparser.Select)
+	if !ok || sel.Where == nil || sel.GroupBy != nil || sel.Having != nil || sel.Limit != nil || sel.Distinct != "" {
+		return nil, false
+	}
+
+	keyCount := len(mapping.ColumnParams)
+	if keyCount == 0 {
+		keyCount = 1
+	}
+	args := make(map[string]interface{})
+	params := make(map[string]Param)
+	keyParams := make([]Param, keyCount)
+	for i, arg := range bound.Args {
+		name := ":v" + strconv.Itoa(i+1)
+		args[name] = arg
+		if i < len(bound.Params) {
+			params[name] = bound.Params[i]
+			if key, ok := arg.(batchKey); ok && int(key) < keyCount && keyParams[key].Name == "" {
+				keyParams[key] = bound.Params[i]
+			}
+		}
+	}
+	columns := make([]*sqlparser.ColName, keyCount)
+	var first *sqlparser.ComparisonExpr
+	sel.Where.Expr = replaceKeys(sel.Where.Expr, args, columns, &first)
+	if first == nil {
+		return nil, false
+	}
+	for _, c := range columns {
+		if c == nil {
+			return nil, false
+		}
+	}
+
+	// 第一个键条件改写为IN，其余的键条件已经替换为true
+	batch := &batchSQL{}
+	left, tuples := sqlparser.ValTuple{}, sqlparser.ValTuple{}
+	for i, c := range columns {
+		left = append(left, c)
+		alias := "mybaits_key_" + strconv.Itoa(i)
+		sel.SelectExprs = append(sel.SelectExprs, &sqlparser.AliasedExpr{Expr: c, As: sqlparser.NewColIdent(alias)})
+		batch.keyColumns = append(batch.keyColumns, alias)
+	}
+	for i, key := range keys {
+		tuple := sqlparser.ValTuple{}
+		for j, v := range key {
+			name := fmt.Sprintf(":mybaits_key_%v_%v", i, j)
+			args[name] = v
+			params[name] = keyParams[j]
+			tuple = append(tuple, sqlparser.NewValArg([]byte(name)))
+		}
+		if len(tuple) == 1 {
+			tuples = append(tuples, tuple[0])
+		} else {
+			tuples = append(tuples, tuple)
+		}
+	}
+	first.Operator = sqlparser.InStr
+	first.Left, first.Right = left, tuples
+	if len(left) == 1 {
+		first.Left = left[0]
+	}
+
+	buf := sqlparser.NewTrackedBuffer(func(buf *sqlparser.TrackedBuffer, node sqlparser.SQLNode) {
+		v, ok := node.(*sqlparser.SQLVal)
+		if !ok || v.Type != sqlparser.ValArg {
+			node.Format(buf)
+			return
+		}
+		batch.args = append(batch.args, args[string(v.Val)])
+		batch.params = append(batch.params, params[string(v.Val)])
+		if m.placeholder == PlaceholderDollar {
+			buf.WriteString("$" + strconv.Itoa(len(batch.args)))
+			return
+		}
+		buf.WriteString("?")
+	})
+	batch.sql = buf.WriteNode(sel).String()
+	// 键出现多次或者不在列 = 键的条件中时无法改写，剩下的键会被驱动当作0
+	for _, arg := range batch.args {
+		if _, ok := arg.(batchKey); ok {
+			return nil, false
+		}
+	}
+	return batch, true
+}
+
+// replaceKeys 查找通过and连接的列 = 键条件，记录键对应的列，第一个条件记录到first，其余的去掉
+func replaceKeys(expr sqlparser.Expr, args map[string]interface{}, columns []*sqlparser.ColName, first **sqlparser.ComparisonExpr) sqlparser.Expr {
+	switch e := expr.(type) {
+	case *sqlparser.AndExpr:
+		e.Left = replaceKeys(e.Left, args, columns, first)
+		e.Right = replaceKeys(e.Right, args, columns, first)
+		// 去掉替换后的true条件
+		if b, ok := e.Right.(sqlparser.BoolVal); ok && bool(b) {
+			return e.Left
+		}
+		if b, ok := e.Left.(sqlparser.BoolVal); ok && bool(b) {
+			return e.Right
+		}
+	case *sqlparser.ParenExpr:
+		e.Expr = replaceKeys(e.Expr, args, columns, first)
+	case *sqlparser.ComparisonExpr:
+		if e.Operator != sqlparser.EqualStr {
+			return e
+		}
+		col, val := e.Left, e.Right
+		if _, ok := col.(*sqlparser.ColName); !ok {
+			col, val = val, col
+		}
+		c, ok := col.(*sqlparser.ColName)
+		v, isVal := val.(*sqlparser.SQLVal)
+		if !ok || !isVal || v.Type != sqlparser.ValArg {
+			return e
+		}
+		key, ok := args[string(v.Val)].(batchKey)
+		if !ok || int(key) >= len(columns) || columns[key] != nil {
+			return e
+		}
+		columns[key] = c
+		if *first == nil {
+			*first = e
+			return e
+		}
+		return sqlparser.BoolVal(true)
+	}
+	return expr
+}
+
+// keyString 将键转换为字符串用于比较，不同驱动返回的[]byte和字符串、数字视为相同
+func keyString(key []interface{}) string {
+	s := make([]string, len(key))
+	for i, v := range key {
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		s[i] = fmt.Sprint(v)
+	}
+	return strings.Join(s, "\x00")
+}
+
+func nestedTag(n NestedMapping) string {
+	if n.Collection {
+		return "collection"
+	}
+	return "association"
+}
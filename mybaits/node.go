@@ -0,0 +1,371 @@
+package mybaits
+
+import (
+	"fmt"
+
+	"github.com/beevik/etree"
+)
+
+// Node 语句树中的节点
+type Node interface {
+	node()
+}
+
+// Attr 元素的属性
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// StatementNode 语句树的根节点，对应select、insert、update、delete和sql元素
+type StatementNode struct {
+	ID       string
+	Kind     string // sql, select, insert, update, delete
+	Attrs    []Attr // 除id以外的属性，保持文档顺序
+	Children []Node
+}
+
+// TextNode 文本，包括其中的#{}和${}
+type TextNode struct {
+	Text string
+}
+
+// IfNode if元素
+type IfNode struct {
+	Test     string
+	Children []Node
+}
+
+// ChooseNode choose元素
+type ChooseNode struct {
+	Whens     []*WhenNode
+	Otherwise *OtherwiseNode // 没有otherwise时为nil
+}
+
+// WhenNode choose中的when元素
+type WhenNode struct {
+	Test     string
+	Children []Node
+}
+
+// OtherwiseNode choose中的otherwise元素
+type OtherwiseNode struct {
+	Children []Node
+}
+
+// TrimNode trim、where和set元素，where和set的前后缀规则由Tag决定，不使用其他字段
+type TrimNode struct {
+	Tag             string // trim, where, set
+	Prefix          string
+	Suffix          string
+	PrefixOverrides string
+	SuffixOverrides string
+	Children        []Node
+}
+
+// ForeachNode foreach元素
+type ForeachNode struct {
+	Collection string
+	Item       string
+	Index      string
+	Open       string
+	Close      string
+	Separator  string
+	Attrs      []Attr // 其他属性，如nullable
+	Children   []Node
+}
+
+// IncludeNode include元素
+type IncludeNode struct {
+	RefID      string
+	Properties []Attr // property子元素的name和value
+}
+
+// BindNode bind元素
+type BindNode struct {
+	Name  string
+	Value string
+}
+
+// ElementNode 其他元素，如selectKey
+type ElementNode struct {
+	Tag      string
+	Attrs    []Attr
+	Children []Node
+}
+
+func (*StatementNode) node() {}
+func (*TextNode) node()      {}
+func (*IfNode) node()        {}
+func (*ChooseNode) node()    {}
+func (*WhenNode) node()      {}
+func (*OtherwiseNode) node() {}
+func (*TrimNode) node()      {}
+func (*ForeachNode) node()   {}
+func (*IncludeNode) node()   {}
+func (*BindNode) node()      {}
+func (*ElementNode) node()   {}
+
+// Visitor 遍历语句树的访问者，Visit返回的访问者用于访问node的子节点，返回nil时不访问子节点
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk 按文档顺序深度优先遍历语句树，和go/ast.Walk一致，访问完node的子节点后会调用w.Visit(nil)
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *StatementNode:
+		walkNodes(v, n.Children)
+	case *IfNode:
+		walkNodes(v, n.Children)
+	case *ChooseNode:
+		for _, when := range n.Whens {
+			Walk(v, when)
+		}
+		if n.Otherwise != nil {
+			Walk(v, n.Otherwise)
+		}
+	case *WhenNode:
+		walkNodes(v, n.Children)
+	case *OtherwiseNode:
+		walkNodes(v, n.Children)
+	case *TrimNode:
+		walkNodes(v, n.Children)
+	case *ForeachNode:
+		walkNodes(v, n.Children)
+	case *ElementNode:
+		walkNodes(v, n.Children)
+	}
+	v.Visit(nil)
+}
+
+func walkNodes(v Visitor, nodes []Node) {
+	for _, n := range nodes {
+		Walk(v, n)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect 按文档顺序深度优先遍历语句树，f返回false时不访问node的子节点
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Node 获取id对应的语句或者sql片段的语句树，修改语句树不会影响映射文件，需要通过SetNode生效
+func (m *Mapper) Node(id string) (*StatementNode, error) {
+	child, ok := m.root[id]
+	if !ok {
+		return nil, fmt.Errorf("statement(%v) not found", id)
+	}
+
+	stmt := &StatementNode{
+		ID:       id,
+		Kind:     child.Tag,
+		Attrs:    elementAttrs(child, "id"),
+		Children: elementNodes(child),
+	}
+	return stmt, nil
+}
+
+// SetNode 使用语句树替换映射文件中同ID的语句，ID不存在时添加到最后，
+// 之后生成和绑定语句时都会使用新的语句树，和NewMapper一样校验#{}参数的选项
+func (m *Mapper) SetNode(stmt *StatementNode) error {
+	if stmt.ID == "" {
+		return fmt.Errorf("statement id is empty")
+	}
+	if _, ok := queryTypes[stmt.Kind]; !ok {
+		return fmt.Errorf("statement kind(%v) is not supported", stmt.Kind)
+	}
+
+	e := etree.NewElement(stmt.Kind)
+	e.CreateAttr("id", stmt.ID)
+	setAttrs(e, stmt.Attrs)
+	addNodes(e, stmt.Children)
+	if err := validateParams(e); err != nil {
+		return fmt.Errorf("statement(%v) is invalid. err: %v", stmt.ID, err)
+	}
+
+	old, ok := m.root[stmt.ID]
+	if !ok {
+		m.ids = append(m.ids, stmt.ID)
+	} else if parent := old.Parent(); parent != nil {
+		// 保持在文档中的位置，语句后的空白字符也是生成的语句的一部分
+		parent.InsertChildAt(old.Index(), e)
+		parent.RemoveChild(old)
+	}
+	m.root[stmt.ID] = e
+	return nil
+}
+
+// elementAttrs 获取元素e中除skip以外的属性
+func elementAttrs(e *etree.Element, skip ...string) (attrs []Attr) {
+	for _, a := range e.Attr {
+		skipped := false
+		for _, s := range skip {
+			if a.Key == s {
+				skipped = true
+			}
+		}
+		if !skipped {
+			attrs = append(attrs, Attr{Key: a.Key, Value: a.Value})
+		}
+	}
+	return
+}
+
+func elementNodes(e *etree.Element) (nodes []Node) {
+	for _, token := range e.Child {
+		switch t := token.(type) {
+		case *etree.CharData:
+			nodes = append(nodes, &TextNode{Text: t.Data})
+		case *etree.Element:
+			nodes = append(nodes, elementNode(t))
+		}
+	}
+	return
+}
+
+func elementNode(e *etree.Element) Node {
+	attr := func(key string) string {
+		return e.SelectAttrValue(key, "")
+	}
+
+	switch e.Tag {
+	case "if":
+		return &IfNode{Test: attr("test"), Children: elementNodes(e)}
+	case "choose":
+		choose := &ChooseNode{}
+		for _, c := range e.ChildElements() {
+			switch c.Tag {
+			case "when":
+				choose.Whens = append(choose.Whens, &WhenNode{Test: c.SelectAttrValue("test", ""), Children: elementNodes(c)})
+			case "otherwise":
+				choose.Otherwise = &OtherwiseNode{Children: elementNodes(c)}
+			}
+		}
+		return choose
+	case "trim", "where", "set":
+		return &TrimNode{
+			Tag:             e.Tag,
+			Prefix:          attr("prefix"),
+			Suffix:          attr("suffix"),
+			PrefixOverrides: attr("prefixOverrides"),
+			SuffixOverrides: attr("suffixOverrides"),
+			Children:        elementNodes(e),
+		}
+	case "foreach":
+		return &ForeachNode{
+			Collection: attr("collection"),
+			Item:       attr("item"),
+			Index:      attr("index"),
+			Open:       attr("open"),
+			Close:      attr("close"),
+			Separator:  attr("separator"),
+			Attrs:      elementAttrs(e, "collection", "item", "index", "open", "close", "separator"),
+			Children:   elementNodes(e),
+		}
+	case "include":
+		include := &IncludeNode{RefID: attr("refid")}
+		for _, p := range e.SelectElements("property") {
+			include.Properties = append(include.Properties, Attr{
+				Key:   p.SelectAttrValue("name", ""),
+				Value: p.SelectAttrValue("value", ""),
+			})
+		}
+		return include
+	case "bind":
+		return &BindNode{Name: attr("name"), Value: attr("value")}
+	}
+	return &ElementNode{Tag: e.Tag, Attrs: elementAttrs(e), Children: elementNodes(e)}
+}
+
+func setAttrs(e *etree.Element, attrs []Attr) {
+	for _, a := range attrs {
+		e.CreateAttr(a.Key, a.Value)
+	}
+}
+
+// setAttrIf 值不为空时设置属性
+func setAttrIf(e *etree.Element, key, value string) {
+	if value != "" {
+		e.CreateAttr(key, value)
+	}
+}
+
+func addNodes(parent *etree.Element, nodes []Node) {
+	for _, n := range nodes {
+		addNode(parent, n)
+	}
+}
+
+func addNode(parent *etree.Element, node Node) {
+	switch n := node.(type) {
+	case *TextNode:
+		parent.CreateText(n.Text)
+	case *IfNode:
+		e := parent.CreateElement("if")
+		e.CreateAttr("test", n.Test)
+		addNodes(e, n.Children)
+	case *ChooseNode:
+		e := parent.CreateElement("choose")
+		for _, when := range n.Whens {
+			addNode(e, when)
+		}
+		if n.Otherwise != nil {
+			addNode(e, n.Otherwise)
+		}
+	case *WhenNode:
+		e := parent.CreateElement("when")
+		e.CreateAttr("test", n.Test)
+		addNodes(e, n.Children)
+	case *OtherwiseNode:
+		addNodes(parent.CreateElement("otherwise"), n.Children)
+	case *TrimNode:
+		e := parent.CreateElement(n.Tag)
+		if n.Tag == "trim" {
+			setAttrIf(e, "prefix", n.Prefix)
+			setAttrIf(e, "suffix", n.Suffix)
+			setAttrIf(e, "prefixOverrides", n.PrefixOverrides)
+			setAttrIf(e, "suffixOverrides", n.SuffixOverrides)
+		}
+		addNodes(e, n.Children)
+	case *ForeachNode:
+		e := parent.CreateElement("foreach")
+		e.CreateAttr("collection", n.Collection)
+		setAttrIf(e, "item", n.Item)
+		setAttrIf(e, "index", n.Index)
+		setAttrIf(e, "open", n.Open)
+		setAttrIf(e, "close", n.Close)
+		setAttrIf(e, "separator", n.Separator)
+		setAttrs(e, n.Attrs)
+		addNodes(e, n.Children)
+	case *IncludeNode:
+		e := parent.CreateElement("include")
+		e.CreateAttr("refid", n.RefID)
+		for _, p := range n.Properties {
+			pe := e.CreateElement("property")
+			pe.CreateAttr("name", p.Key)
+			pe.CreateAttr("value", p.Value)
+		}
+	case *BindNode:
+		e := parent.CreateElement("bind")
+		e.CreateAttr("name", n.Name)
+		e.CreateAttr("value", n.Value)
+	case *ElementNode:
+		e := parent.CreateElement(n.Tag)
+		setAttrs(e, n.Attrs)
+		addNodes(e, n.Children)
+	}
+}
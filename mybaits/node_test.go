@@ -0,0 +1,192 @@
+package mybaits
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMapper_SetNode_roundTrip(t *testing.T) {
+	for _, filename := range []string{"testdata/test.xml", "testdata/bind.xml", "testdata/conformance/trim.xml"} {
+		m, err := NewMapper(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied, err := NewMapper(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range m.IDs() {
+			node, err := m.Node(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = copied.SetNode(node); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, id := range m.IDs() {
+			t.Run(filename+"/"+id, func(t *testing.T) {
+				// choose和include中只有空白字符的文本不在语句树中，比较时忽略空白字符的差异
+				want := whitespaceRegex.ReplaceAllString(m.newChildMapper(m.root[id]).getRawStatement(), " ")
+				got := whitespaceRegex.ReplaceAllString(copied.newChildMapper(copied.root[id]).getRawStatement(), " ")
+				if got != want {
+					t.Errorf("getRawStatement() = %v, want %v", got, want)
+				}
+				wantNode, _ := m.Node(id)
+				gotNode, _ := copied.Node(id)
+				if !reflect.DeepEqual(gotNode, wantNode) {
+					t.Errorf("Node() = %+v, want %+v", gotNode, wantNode)
+				}
+			})
+		}
+	}
+}
+
+type kindVisitor struct {
+	kinds []string
+}
+
+func (v *kindVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		return nil
+	}
+	kind := reflect.TypeOf(node).Elem().Name()
+	if _, ok := node.(*TextNode); ok {
+		return v
+	}
+	v.kinds = append(v.kinds, kind)
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	m, err := NewMapper("testdata/bind.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := m.Node("selectFruits")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &kindVisitor{}
+	Walk(v, node)
+	want := []string{
+		"StatementNode", "IncludeNode", "TrimNode", "IfNode", "IfNode", "IfNode", "ForeachNode",
+		"ChooseNode", "WhenNode", "OtherwiseNode",
+	}
+	if !reflect.DeepEqual(v.kinds, want) {
+		t.Errorf("Walk() = %v, want %v", v.kinds, want)
+	}
+}
+
+func TestInspect_tenantPredicate(t *testing.T) {
+	m, err := NewMapper("testdata/bind.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"selectFruits", "updateFruit"} {
+		node, err := m.Node(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		Inspect(node, func(n Node) bool {
+			if where, ok := n.(*TrimNode); ok && where.Tag == "where" {
+				where.Children = append([]Node{&TextNode{Text: " AND tenant_id = #{tenantID} "}}, where.Children...)
+				found = true
+				return false
+			}
+			return true
+		})
+		if !found {
+			node.Children = append(node.Children, &TextNode{Text: " AND tenant_id = #{tenantID}"})
+		}
+		if err = m.SetNode(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		id    string
+		param map[string]interface{}
+		want  string
+	}{
+		{
+			id:    "selectFruits",
+			param: map[string]interface{}{"tenantID": 1, "orderBy": "price"},
+			want:  "SELECT id, name, price FROM fruits WHERE tenant_id = ? ORDER BY price [1]",
+		},
+		{
+			id:    "updateFruit",
+			param: map[string]interface{}{"tenantID": 1, "fruit": map[string]interface{}{"id": 2, "price": 3}},
+			want:  "UPDATE fruits SET price = ? WHERE id = ? AND tenant_id = ? [3 2 1]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			bound, err := m.Bind(tt.id, tt.param)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := whitespaceRegex.ReplaceAllString(bound.SQL, " ") + " " + fmt.Sprint(bound.Args)
+			if got != tt.want {
+				t.Errorf("Bind() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapper_SetNode(t *testing.T) {
+	m, err := NewMapper("testdata/bind.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := m.Node("selectFruits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		node    *StatementNode
+		wantErr bool
+	}{
+		{
+			name: "add",
+			node: &StatementNode{ID: "added", Kind: "select", Children: []Node{&TextNode{Text: "SELECT 1"}}},
+		},
+		{
+			name:    "emptyID",
+			node:    &StatementNode{Kind: "select"},
+			wantErr: true,
+		},
+		{
+			name:    "kind",
+			node:    &StatementNode{ID: "bad", Kind: "resultMap"},
+			wantErr: true,
+		},
+		{
+			name: "invalidParam",
+			node: &StatementNode{ID: "selectFruits", Kind: "select", Children: []Node{
+				&TextNode{Text: "SELECT * FROM fruits WHERE "},
+				&IfNode{Test: "name != null", Children: []Node{&TextNode{Text: "name = #{name,jdbcTyp=VARCHAR}"}}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.SetNode(tt.node); (err != nil) != tt.wantErr {
+				t.Errorf("SetNode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if stmt, err := m.GetStatement("added"); err != nil || stmt != "select 1 from dual" {
+		t.Errorf("GetStatement() = %v, %v", stmt, err)
+	}
+	if after, err := m.Node("selectFruits"); err != nil || !reflect.DeepEqual(after, before) {
+		t.Errorf("Node() = %+v, %v, want the invalid statement not to replace %+v", after, err, before)
+	}
+}
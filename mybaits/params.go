@@ -1,8 +1,11 @@
 package mybaits
 
 import (
-	"regexp"
+	"fmt"
+	"strconv"
 	"strings"
+
+	"github.com/beevik/etree"
 )
 
 var jdbcTypes = map[string][]string{
@@ -14,73 +17,256 @@ var jdbcTypes = map[string][]string{
 	"OTHER":   {"ARRAY", "CLOB", "CURSOR", "DATALINK", "DATETIMEOFFSET", "DISTINCT", "JAVA_OBJECT", "NCLOB", "NULL", "OTHER", "REAL", "REF", "ROWID", "SQLXML", "STRUCT", "UNDEFINED"},
 }
 
+// Param #{}或者${}参数，选项和mybatis的#{}一致
 type Param struct {
-	FullName  string
-	Name      string
-	JdbcType  string
-	JavaType  string
-	MockValue string
+	FullName     string
+	Name         string // 属性名，使用(expression)形式时为括号中的表达式
+	JdbcType     string
+	JavaType     string
+	Mode         string // IN, OUT, INOUT，未设置时为空，和IN相同
+	NumericScale int    // 小数位数，0表示未设置
+	TypeHandler  string
+	ResultMap    string // mode为OUT并且jdbcType为CURSOR时映射结果集的resultMap
+	JdbcTypeName string // 用户自定义类型的类型名
+	MockValue    string // MockPlaceholder模式下的模拟值
 }
 
-func GetParams(childText, childTail string) map[string][]Param {
-	params := map[string][]Param{
-		"#": []Param{},
-		"$": []Param{},
+// paramOptions #{}支持的选项
+var paramOptions = []string{"javaType", "jdbcType", "mode", "numericScale", "resultMap", "typeHandler", "jdbcTypeName"}
+
+// ParseParam 解析#{}或者${}参数token，语法和mybatis一致:
+//
+//	#{property,jdbcType=VARCHAR,javaType=java.lang.String,mode=IN,numericScale=2}
+//	#{property:VARCHAR,typeHandler=com.example.Handler}
+//	#{(expression):VARCHAR}
+//
+// 选项名不支持、缺少等号、mode不是IN、OUT、INOUT或者numericScale不是非负整数时返回错误
+func ParseParam(token string) (param Param, err error) {
+	param.FullName = token
+	if len(token) < 3 || (token[0] != '#' && token[0] != '$') || token[1] != '{' || token[len(token)-1] != '}' {
+		return param, fmt.Errorf("%v is not a parameter", token)
+	}
+	p := &paramParser{text: token[2 : len(token)-1], param: &param}
+	if err = p.parse(); err != nil {
+		return param, fmt.Errorf("parse parameter %v fail. err: %v", token, err)
+	}
+	param.MockValue = mockValue(param, MockPlaceholder)
+	return param, nil
+}
+
+// paramParser #{}内容的解析器，对应mybatis的ParameterExpression
+type paramParser struct {
+	text  string
+	param *Param
+}
+
+func (p *paramParser) parse() error {
+	i := p.skipSpace(0)
+	if i < len(p.text) && p.text[i] == '(' {
+		return p.expression(i + 1)
 	}
+	return p.property(i)
+}
 
-	p := regexp.MustCompile(`\S`)
-	if !p.MatchString(childText) {
-		childText = ""
+// expression 解析(expression)形式的属性，括号可以嵌套
+func (p *paramParser) expression(start int) error {
+	depth := 1
+	i := start
+	for ; i < len(p.text) && depth > 0; i++ {
+		switch p.text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
 	}
-	if !p.MatchString(childTail) {
-		childTail = ""
+	if depth > 0 {
+		return fmt.Errorf("unclosed expression at position %v", start-1)
 	}
-	convertString := childText + childTail
+	p.param.Name = strings.TrimSpace(p.text[start : i-1])
+	return p.jdbcTypeOpt(i)
+}
 
-	for _, char := range []string{"#", "$"} {
-		pattern := regexp.MustCompile(`\` + char + `\{.+?\}`)
-		matches := pattern.FindAllString(convertString, -1)
+func (p *paramParser) property(start int) error {
+	end := p.skipUntil(start, ",:")
+	p.param.Name = strings.TrimSpace(p.text[start:end])
+	if p.param.Name == "" {
+		return fmt.Errorf("property is empty")
+	}
+	return p.jdbcTypeOpt(end)
+}
 
-		seen := make(map[string]bool)
-		uniqueMatches := []string{}
-		for _, m := range matches {
-			if !seen[m] {
-				seen[m] = true
-				uniqueMatches = append(uniqueMatches, m)
-			}
+// jdbcTypeOpt 解析属性后的:jdbcType简写以及选项
+func (p *paramParser) jdbcTypeOpt(i int) error {
+	i = p.skipSpace(i)
+	if i >= len(p.text) {
+		return nil
+	}
+	switch p.text[i] {
+	case ':':
+		end := p.skipUntil(i+1, ",")
+		p.param.JdbcType = strings.TrimSpace(p.text[i+1 : end])
+		return p.options(end)
+	case ',':
+		return p.options(i)
+	}
+	return fmt.Errorf("unexpected %q at position %v", p.text[i], i)
+}
+
+// options 从i处的逗号开始解析name=value形式的选项
+func (p *paramParser) options(i int) error {
+	for i < len(p.text) {
+		end := p.skipUntil(i+1, ",")
+		option := p.text[i+1 : end]
+		eq := strings.Index(option, "=")
+		if eq < 0 {
+			return fmt.Errorf("option %q has no value", strings.TrimSpace(option))
 		}
+		if err := p.set(strings.TrimSpace(option[:eq]), strings.TrimSpace(option[eq+1:])); err != nil {
+			return err
+		}
+		i = end
+	}
+	return nil
+}
 
-		for _, match := range uniqueMatches {
-			param := Param{FullName: match}
-			inner := strings.TrimPrefix(strings.TrimSuffix(match, "}"), char+"{")
-			parts := strings.Split(inner, ",")
-			param.Name = parts[0]
+func (p *paramParser) set(name, value string) (err error) {
+	switch name {
+	case "javaType":
+		p.param.JavaType = value
+	case "jdbcType":
+		p.param.JdbcType = value
+	case "mode":
+		p.param.Mode = strings.ToUpper(value)
+		if p.param.Mode != "IN" && p.param.Mode != "OUT" && p.param.Mode != "INOUT" {
+			return fmt.Errorf("mode(%v) is not IN, OUT or INOUT", value)
+		}
+	case "numericScale":
+		if p.param.NumericScale, err = strconv.Atoi(value); err != nil || p.param.NumericScale < 0 {
+			return fmt.Errorf("numericScale(%v) is not non-negative integer", value)
+		}
+	case "resultMap":
+		p.param.ResultMap = value
+	case "typeHandler":
+		p.param.TypeHandler = value
+	case "jdbcTypeName":
+		p.param.JdbcTypeName = value
+	default:
+		return fmt.Errorf("option %q is invalid. valid options are %v", name, strings.Join(paramOptions, ", "))
+	}
+	return nil
+}
 
-			jdbcRegex := regexp.MustCompile(`\s*jdbcType\s*=\s*(\w+)`)
-			if j := jdbcRegex.FindStringSubmatch(inner); len(j) > 1 {
-				param.JdbcType = j[1]
-			}
+func (p *paramParser) skipSpace(i int) int {
+	for i < len(p.text) && p.text[i] <= ' ' {
+		i++
+	}
+	return i
+}
 
-			javaRegex := regexp.MustCompile(`\s*javaType\s*=\s*(\w+)`)
-			if j := javaRegex.FindStringSubmatch(inner); len(j) > 1 {
-				param.JavaType = j[1]
-			}
+func (p *paramParser) skipUntil(i int, chars string) int {
+	for i < len(p.text) && !strings.ContainsRune(chars, rune(p.text[i])) {
+		i++
+	}
+	return i
+}
 
-			param.MockValue = getMockValue(param.JdbcType)
-			params[char] = append(params[char], param)
+// paramTokens 按出现顺序获取s中#{}和${}参数的位置，参数中的花括号可以嵌套，没有闭合的参数会被忽略
+func paramTokens(s string) (tokens [][2]int) {
+	for i := 0; i+1 < len(s); i++ {
+		if (s[i] != '#' && s[i] != '$') || s[i+1] != '{' {
+			continue
+		}
+		depth := 0
+		for j := i + 1; j < len(s); j++ {
+			if s[j] == '{' {
+				depth++
+			} else if s[j] == '}' {
+				if depth--; depth == 0 {
+					tokens = append(tokens, [2]int{i, j + 1})
+					i = j
+					break
+				}
+			}
 		}
 	}
+	return
+}
 
+// replaceParams 使用fn的返回值替换s中所有的#{}和${}参数
+func replaceParams(s string, fn func(token string) string) string {
+	tokens := paramTokens(s)
+	if len(tokens) == 0 {
+		return s
+	}
+	b := &strings.Builder{}
+	last := 0
+	for _, t := range tokens {
+		b.WriteString(s[last:t[0]])
+		b.WriteString(fn(s[t[0]:t[1]]))
+		last = t[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// GetParams 获取childText和childTail中的#{}和${}参数，重复的参数只保留第一个
+func GetParams(childText, childTail string) map[string][]Param {
+	params := map[string][]Param{
+		"#": []Param{},
+		"$": []Param{},
+	}
+	if strings.TrimSpace(childText) == "" {
+		childText = ""
+	}
+	if strings.TrimSpace(childTail) == "" {
+		childTail = ""
+	}
+	for _, p := range findParams(childText + childTail) {
+		params[p.FullName[:1]] = append(params[p.FullName[:1]], p)
+	}
 	return params
 }
 
-func getMockValue(jdbcType string) string {
-	for _, types := range jdbcTypes {
-		for _, t := range types {
-			if t == jdbcType {
-				return "?"
+// findParams 按出现顺序获取s中的所有#{}和${}参数，重复的参数只保留第一个
+func findParams(s string) (params []Param) {
+	seen := make(map[string]bool)
+	for _, t := range paramTokens(s) {
+		match := s[t[0]:t[1]]
+		if !seen[match] {
+			seen[match] = true
+			params = append(params, newParam(match))
+		}
+	}
+	return
+}
+
+// newParam 解析参数，忽略解析错误，加载映射文件时已经校验过#{}参数
+func newParam(match string) Param {
+	param, _ := ParseParam(match)
+	param.MockValue = mockValue(param, MockPlaceholder)
+	return param
+}
+
+// validateParams 校验e中所有#{}参数的选项
+func validateParams(e *etree.Element) error {
+	for _, token := range e.Child {
+		switch t := token.(type) {
+		case *etree.CharData:
+			s := convertCDATA(t.Data, false)
+			for _, pos := range paramTokens(s) {
+				if s[pos[0]] != '#' {
+					continue
+				}
+				if _, err := ParseParam(s[pos[0]:pos[1]]); err != nil {
+					return err
+				}
+			}
+		case *etree.Element:
+			if err := validateParams(t); err != nil {
+				return err
 			}
 		}
 	}
-	return "?"
+	return nil
 }
@@ -0,0 +1,142 @@
+package mybaits
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseParam(t *testing.T) {
+	tests := []struct {
+		token   string
+		want    Param
+		wantErr string
+	}{
+		{
+			token: "#{name}",
+			want:  Param{Name: "name"},
+		},
+		{
+			token: "#{ id , jdbcType = BIGINT , javaType = java.lang.Long }",
+			want:  Param{Name: "id", JdbcType: "BIGINT", JavaType: "java.lang.Long"},
+		},
+		{
+			token: "#{price, mode=inout, jdbcType=NUMERIC, numericScale=2}",
+			want:  Param{Name: "price", JdbcType: "NUMERIC", Mode: "INOUT", NumericScale: 2},
+		},
+		{
+			token: "#{rows, mode=OUT, jdbcType=CURSOR, javaType=ResultSet, resultMap=fruitResult}",
+			want:  Param{Name: "rows", JdbcType: "CURSOR", JavaType: "ResultSet", Mode: "OUT", ResultMap: "fruitResult"},
+		},
+		{
+			token: "#{tags,typeHandler=com.example.ArrayTypeHandler,jdbcTypeName=text_array}",
+			want:  Param{Name: "tags", TypeHandler: "com.example.ArrayTypeHandler", JdbcTypeName: "text_array"},
+		},
+		{
+			token: "#{name:VARCHAR, javaType=String}",
+			want:  Param{Name: "name", JdbcType: "VARCHAR", JavaType: "String"},
+		},
+		{
+			token: "#{(id + (1)):INTEGER}",
+			want:  Param{Name: "id + (1)", JdbcType: "INTEGER"},
+		},
+		{
+			token: "${orderBy}",
+			want:  Param{Name: "orderBy"},
+		},
+		{
+			token:   "#{name, size=10}",
+			wantErr: `option "size" is invalid`,
+		},
+		{
+			token:   "#{name, jdbcType}",
+			wantErr: `option "jdbcType" has no value`,
+		},
+		{
+			token:   "#{total, mode=BOTH}",
+			wantErr: "mode(BOTH) is not IN, OUT or INOUT",
+		},
+		{
+			token:   "#{price, numericScale=two}",
+			wantErr: "numericScale(two) is not non-negative integer",
+		},
+		{
+			token:   "#{(id, jdbcType=INTEGER}",
+			wantErr: "unclosed expression",
+		},
+		{
+			token:   "#{ , jdbcType=INTEGER}",
+			wantErr: "property is empty",
+		},
+		{
+			token:   "#{(id) name}",
+			wantErr: "unexpected 'n'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			got, err := ParseParam(tt.token)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseParam() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseParam() error = %v", err)
+			}
+			tt.want.FullName = tt.token
+			tt.want.MockValue = "?"
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseParam() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findParams(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{
+			s:    "where a = #{a} and b = ${b} and c = #{a}",
+			want: []string{"#{a}", "${b}"},
+		},
+		{
+			s:    "where a = #{map{a}, jdbcType=VARCHAR} and b = #{ b }",
+			want: []string{"#{map{a}, jdbcType=VARCHAR}", "#{ b }"},
+		},
+		{
+			s:    "where a = #{a",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			var got []string
+			for _, p := range findParams(tt.s) {
+				got = append(got, p.FullName)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMapper_invalidParam(t *testing.T) {
+	data := []byte(`<mapper namespace="fruits">
+    <select id="selectByName">
+        select id from fruits
+        <where>
+            name = #{name, jdbcType=VARCHAR, length=10}
+        </where>
+    </select>
+</mapper>`)
+	_, err := NewMapperFromBytes("fruits.xml", data)
+	if err == nil || !strings.Contains(err.Error(), "statement(selectByName) at line 2") ||
+		!strings.Contains(err.Error(), `option "length" is invalid`) {
+		t.Fatalf("NewMapperFromBytes() error = %v", err)
+	}
+}
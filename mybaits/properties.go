@@ -0,0 +1,252 @@
+package mybaits
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// Properties 加载映射文件时用于替换${}占位符的属性，支持${name:default}形式的默认值，
+// 后加入的属性会覆盖先加入的属性，没有定义属性也没有默认值的${}是运行时参数，留到绑定参数时替换，
+// 开启Strict时这样的${}会使加载映射文件失败
+type Properties struct {
+	values map[string]string
+	strict bool
+}
+
+// NewProperties 通过属性集合sources生成Properties
+func NewProperties(sources ...map[string]string) *Properties {
+	p := &Properties{
+		values: make(map[string]string),
+	}
+	for _, s := range sources {
+		p.SetMap(s)
+	}
+	return p
+}
+
+// SetMap 加入属性集合m
+func (p *Properties) SetMap(m map[string]string) {
+	for k, v := range m {
+		p.values[k] = v
+	}
+}
+
+// Set 设置属性key的值为value
+func (p *Properties) Set(key, value string) {
+	p.values[key] = value
+}
+
+// LoadFile 从java的properties文件filename中加入属性
+func (p *Properties) LoadFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("ReadFile fail. err: %v", err)
+	}
+	p.SetMap(parseJavaProperties(data))
+	return nil
+}
+
+// LoadEnv 加入以prefix为前缀的环境变量，属性名为去掉前缀后的环境变量名以及它的小写点分形式，
+// 如前缀为APP_时，APP_SCHEMA可以通过${SCHEMA}或者${schema}访问, APP_DB_SCHEMA可以通过${DB_SCHEMA}或者${db.schema}访问，
+// 只有通过LoadEnv加入的环境变量才可以这样访问
+func (p *Properties) LoadEnv(prefix string) {
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if i < 0 || !strings.HasPrefix(kv[:i], prefix) {
+			continue
+		}
+		if key := kv[len(prefix):i]; key != "" {
+			p.values[key] = kv[i+1:]
+			p.values[strings.ReplaceAll(strings.ToLower(key), "_", ".")] = kv[i+1:]
+		}
+	}
+}
+
+// Strict 设置是否严格检查占位符，开启后映射文件中没有定义属性也没有默认值的${}会返回错误，
+// 只适用于不使用运行时${}参数的映射文件，include中property定义的属性不受影响
+func (p *Properties) Strict(strict bool) {
+	p.strict = strict
+}
+
+// Get 获取属性key的值
+func (p *Properties) Get(key string) (string, bool) {
+	v, ok := p.values[key]
+	return v, ok
+}
+
+// Resolve 替换s中定义了属性或者默认值的${}占位符，其余的占位符保持原样，unresolved为这些占位符的属性名
+func (p *Properties) Resolve(s string) (resolved string, unresolved []string) {
+	return p.resolve(s, nil)
+}
+
+// resolve 替换s中所有的${}占位符，带有选项的${}以及skip中的属性名视为运行时参数不做替换
+func (p *Properties) resolve(s string, skip map[string]bool) (resolved string, unresolved []string) {
+	b := &strings.Builder{}
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(s[:start])
+		placeholder := s[start : end+1]
+		inner := s[start+2 : end]
+		s = s[end+1:]
+
+		name, dflt, hasDefault := inner, "", false
+		if i := strings.Index(inner, ":"); i >= 0 {
+			name, dflt, hasDefault = inner[:i], inner[i+1:], true
+		}
+		name = strings.TrimSpace(name)
+
+		if strings.Contains(inner, ",") || skip[name] {
+			b.WriteString(placeholder)
+			continue
+		}
+		if v, ok := p.Get(name); ok {
+			b.WriteString(v)
+			continue
+		}
+		if hasDefault {
+			b.WriteString(dflt)
+			continue
+		}
+		b.WriteString(placeholder)
+		unresolved = append(unresolved, name)
+	}
+	b.WriteString(s)
+	return b.String(), unresolved
+}
+
+// apply 替换root下所有文本以及属性值中定义了属性或者默认值的${}占位符，
+// sql片段中由引用它的include的property定义的属性不做替换，其余的${}是运行时参数，保持原样，
+// 开启Strict时有运行时参数会返回错误
+func (p *Properties) apply(root *etree.Element) error {
+	scopes := includeScopes(root)
+	var unresolved []string
+	seen := make(map[string]bool)
+	resolve := func(s string, skip map[string]bool) string {
+		resolved, names := p.resolve(s, skip)
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				unresolved = append(unresolved, name)
+			}
+		}
+		return resolved
+	}
+
+	var walk func(e *etree.Element, skip map[string]bool)
+	walk = func(e *etree.Element, skip map[string]bool) {
+		if e.Tag == "sql" && e.Parent() == root {
+			skip = scopes[e.SelectAttrValue("id", "")]
+		}
+		attrSkip := skip
+		if e.Tag == "include" {
+			// include的refid可以使用自身property定义的属性
+			attrSkip = union(skip, propertyNames(e))
+		}
+		for i := range e.Attr {
+			e.Attr[i].Value = resolve(e.Attr[i].Value, attrSkip)
+		}
+		for _, token := range e.Child {
+			switch t := token.(type) {
+			case *etree.CharData:
+				t.Data = resolve(t.Data, skip)
+			case *etree.Element:
+				walk(t, skip)
+			}
+		}
+	}
+	walk(root, nil)
+
+	if p.strict && len(unresolved) > 0 {
+		return fmt.Errorf("properties(%v) are not defined", strings.Join(unresolved, ", "))
+	}
+	return nil
+}
+
+// includeScopes 获取每个sql片段中由include的property定义的属性名，键为片段的id，
+// 和mybatis一致，片段中的include会把片段可以使用的属性传给它引用的片段
+func includeScopes(root *etree.Element) map[string]map[string]bool {
+	namespace := root.SelectAttrValue("namespace", "")
+	fragmentID := func(refid string) string {
+		return strings.TrimPrefix(refid, namespace+".")
+	}
+	// enclosing 获取include所在的sql片段的id，不在片段中时为空
+	enclosing := func(e *etree.Element) string {
+		for ; e != nil && e.Parent() != root; e = e.Parent() {
+		}
+		if e == nil || e.Tag != "sql" {
+			return ""
+		}
+		return e.SelectAttrValue("id", "")
+	}
+
+	scopes := make(map[string]map[string]bool)
+	includes := root.FindElements("//include")
+	for changed := true; changed; {
+		changed = false
+		for _, include := range includes {
+			target := fragmentID(includeRefID(include))
+			if scopes[target] == nil {
+				scopes[target] = make(map[string]bool)
+			}
+			names := propertyNames(include)
+			if id := enclosing(include); id != "" {
+				names = union(names, scopes[id])
+			}
+			for name := range names {
+				if !scopes[target][name] {
+					scopes[target][name] = true
+					changed = true
+				}
+			}
+		}
+	}
+	return scopes
+}
+
+// includeRefID 获取include引用的片段，refid中可以使用include自身property定义的属性
+func includeRefID(include *etree.Element) string {
+	refid := include.SelectAttrValue("refid", "")
+	for _, property := range include.SelectElements("property") {
+		refid = strings.ReplaceAll(refid, "${"+property.SelectAttrValue("name", "")+"}", property.SelectAttrValue("value", ""))
+	}
+	return refid
+}
+
+// propertyNames 获取include中property定义的属性名
+func propertyNames(include *etree.Element) map[string]bool {
+	names := make(map[string]bool)
+	for _, property := range include.SelectElements("property") {
+		names[property.SelectAttrValue("name", "")] = true
+	}
+	return names
+}
+
+func union(a, b map[string]bool) map[string]bool {
+	u := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		u[k] = true
+	}
+	for k := range b {
+		u[k] = true
+	}
+	return u
+}
+
+// WithProperties 设置加载映射文件时用于替换${}占位符的属性
+func WithProperties(p *Properties) MapperOption {
+	return func(m *Mapper) {
+		m.properties = p
+	}
+}
@@ -0,0 +1,179 @@
+package mybaits
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProperties_Resolve(t *testing.T) {
+	t.Setenv("MYBAITS_TEST_DB_SCHEMA", "sales")
+	t.Setenv("REGION", "eu")
+	p := NewProperties(map[string]string{
+		"schema": "public",
+		"table":  "orders",
+	})
+	p.LoadEnv("MYBAITS_TEST_")
+
+	tests := []struct {
+		name           string
+		s              string
+		wantResolved   string
+		wantUnresolved []string
+	}{
+		{
+			name:         "basic",
+			s:            "${schema}.${table}",
+			wantResolved: "public.orders",
+		},
+		{
+			name:         "default",
+			s:            "${region:cn}.${schema:other}",
+			wantResolved: "cn.public",
+		},
+		{
+			name:         "env",
+			s:            "${db.schema}.orders",
+			wantResolved: "sales.orders",
+		},
+		{
+			name:         "envUpper",
+			s:            "${DB_SCHEMA}.orders",
+			wantResolved: "sales.orders",
+		},
+		{
+			name:           "envNotLoaded",
+			s:              "${region}.orders",
+			wantResolved:   "${region}.orders",
+			wantUnresolved: []string{"region"},
+		},
+		{
+			name:         "options",
+			s:            "price > ${price,jdbcType=BIGINT}",
+			wantResolved: "price > ${price,jdbcType=BIGINT}",
+		},
+		{
+			name:           "unresolved",
+			s:              "${region}.${schema}",
+			wantResolved:   "${region}.public",
+			wantUnresolved: []string{"region"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotResolved, gotUnresolved := p.Resolve(tt.s)
+			if gotResolved != tt.wantResolved {
+				t.Errorf("Properties.Resolve() resolved = %v, want %v", gotResolved, tt.wantResolved)
+			}
+			if !reflect.DeepEqual(gotUnresolved, tt.wantUnresolved) {
+				t.Errorf("Properties.Resolve() unresolved = %v, want %v", gotUnresolved, tt.wantUnresolved)
+			}
+		})
+	}
+}
+
+func TestNewMapper_properties(t *testing.T) {
+	tests := []struct {
+		name          string
+		p             *Properties
+		wantNamespace string
+		wantSQL       string
+	}{
+		{
+			name:          "resolved",
+			p:             NewProperties(map[string]string{"schema": "sales"}),
+			wantNamespace: "Properties",
+			wantSQL:       "SELECT id FROM sales.orders WHERE status = ? AND region = 'eu' AND price > 10",
+		},
+		{
+			name:          "default",
+			p:             NewProperties(map[string]string{"namespace": "Orders"}),
+			wantNamespace: "Orders",
+			wantSQL:       "SELECT id FROM public.orders WHERE status = ? AND region = 'eu' AND price > 10",
+		},
+		{
+			// 属性只替换定义过并且没有选项的占位符，运行时参数${region}和${price,jdbcType=BIGINT}使用绑定时的参数值
+			name:          "runtimeParam",
+			p:             NewProperties(map[string]string{"schema": "sales", "price": "99"}),
+			wantNamespace: "Properties",
+			wantSQL:       "SELECT id FROM sales.orders WHERE status = ? AND region = 'eu' AND price > 10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMapper("testdata/properties.xml", WithProperties(tt.p))
+			if err != nil {
+				t.Fatalf("NewMapper() error = %v", err)
+			}
+			if m.Namespace() != tt.wantNamespace {
+				t.Errorf("Namespace() = %v, want %v", m.Namespace(), tt.wantNamespace)
+			}
+			bound, err := m.Bind("testSchema", map[string]interface{}{"status": 1, "region": "eu", "price": 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(strings.Fields(bound.SQL), " "); got != tt.wantSQL {
+				t.Errorf("Bind() = %v, want %v", got, tt.wantSQL)
+			}
+		})
+	}
+}
+
+func TestNewMapper_strictProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		strict  bool
+		data    string
+		wantErr string
+	}{
+		{
+			name:   "lenient",
+			strict: false,
+			data:   `<select id="s">SELECT id FROM ${schema}.orders ORDER BY ${orderBy}</select>`,
+		},
+		{
+			name:    "undefined",
+			strict:  true,
+			data:    `<select id="s">SELECT id FROM ${schema}.orders ORDER BY ${orderBy} ${orderBy}</select>`,
+			wantErr: "properties(schema, orderBy) are not defined",
+		},
+		{
+			name:   "defined",
+			strict: true,
+			data: `<sql id="columns">${alias}.id</sql>
+    <sql id="from">${table} <include refid="columns"/></sql>
+    <select id="s">SELECT id FROM ${db:main}.<include refid="${name}">
+        <property name="name" value="from"/>
+        <property name="table" value="orders"/>
+        <property name="alias" value="o"/>
+    </include></select>`,
+		},
+		{
+			// 属性只在include引用的片段中生效，在其他片段中使用时视为没有定义
+			name:   "otherFragment",
+			strict: true,
+			data: `<sql id="a">${table}</sql>
+    <sql id="b">${tabel}</sql>
+    <select id="s1">SELECT id FROM <include refid="a"><property name="table" value="t"/></include></select>
+    <select id="s2">SELECT id FROM <include refid="b"><property name="table" value="t"/></include>
+        JOIN <include refid="a"><property name="tabel" value="t"/></include></select>`,
+			wantErr: "properties(tabel) are not defined",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProperties()
+			p.Strict(tt.strict)
+			_, err := NewMapperFromBytes("strict.xml", []byte(`<mapper namespace="Strict">`+tt.data+`</mapper>`), WithProperties(p))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewMapperFromBytes() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewMapperFromBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package mybaits
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Registry 按命名空间管理配置中的所有映射文件
+type Registry struct {
+	config     *Config
+	mappers    map[string]*Mapper
+	namespaces []string
+}
+
+// NewRegistry 加载config中的所有映射文件，opts会作用于每个映射文件，
+// 配置中的properties和databaseIdProvider会在opts之前生效，
+// 没有配置environment或者无法通过url推断数据库产品名时不选择databaseId
+func NewRegistry(config *Config, opts ...MapperOption) (r *Registry, err error) {
+	r = &Registry{
+		config:  config,
+		mappers: make(map[string]*Mapper),
+	}
+
+	var configOpts []MapperOption
+	if len(config.Properties) > 0 {
+		configOpts = append(configOpts, WithProperties(NewProperties(config.Properties)))
+	}
+	if config.DatabaseIDProvider != nil && config.DatabaseIDProvider.ProductName != "" {
+		configOpts = append(configOpts, WithDatabaseIDProvider(config.DatabaseIDProvider))
+	}
+	opts = append(configOpts, opts...)
+
+	for _, filename := range config.Mappers {
+		var m *Mapper
+		if m, err = NewMapper(filename, opts...); err != nil {
+			return nil, fmt.Errorf("NewMapper(%v) fail. err: %v", filename, err)
+		}
+		if _, ok := r.mappers[m.Namespace()]; ok {
+			return nil, fmt.Errorf("namespace(%v) in %v is duplicate", m.Namespace(), filename)
+		}
+		r.mappers[m.Namespace()] = m
+		r.namespaces = append(r.namespaces, m.Namespace())
+	}
+	return
+}
+
+// Config 获取配置
+func (r *Registry) Config() *Config {
+	return r.config
+}
+
+// Namespaces 按配置顺序获取所有映射文件的命名空间
+func (r *Registry) Namespaces() []string {
+	return append([]string(nil), r.namespaces...)
+}
+
+// Mapper 获取命名空间namespace对应的映射文件
+func (r *Registry) Mapper(namespace string) (*Mapper, error) {
+	m, ok := r.mappers[namespace]
+	if !ok {
+		return nil, fmt.Errorf("mapper(%v) not found", namespace)
+	}
+	return m, nil
+}
+
+// Statement 获取完整ID(命名空间.ID)对应语句的元数据
+func (r *Registry) Statement(fullID string) (*StatementInfo, error) {
+	i := strings.LastIndex(fullID, ".")
+	if i < 0 {
+		return nil, fmt.Errorf("statement(%v) is not full id", fullID)
+	}
+	m, err := r.Mapper(fullID[:i])
+	if err != nil {
+		return nil, err
+	}
+	return m.Statement(fullID[i+1:])
+}
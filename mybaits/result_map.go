@@ -0,0 +1,242 @@
+package mybaits
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// ResultMap 映射文件中的resultMap
+type ResultMap struct {
+	ID            string
+	Type          string
+	Extends       string
+	AutoMapping   string          // true, false，未设置时为空
+	Results       []ResultMapping // id和result
+	Nested        []NestedMapping // association和collection
+	Discriminator *Discriminator  // 没有discriminator时为nil，不会通过extends继承
+}
+
+// ResultMapping resultMap中的id和result
+type ResultMapping struct {
+	Property string
+	Column   string
+	JavaType string
+	JdbcType string
+	ID       bool
+}
+
+// NestedMapping resultMap中的association和collection
+type NestedMapping struct {
+	Property     string
+	Column       string
+	ColumnParams []ColumnParam // column为{a=col1,b=col2}形式的组合键时的参数，否则为空
+	JavaType     string
+	OfType       string
+	Select       string // 嵌套查询的语句ID，可以是命名空间.ID
+	ResultMap    string
+	FetchType    string // lazy, eager，未设置时为空
+	ColumnPrefix string
+	Collection   bool
+	Results      []ResultMapping // 内联的id和result
+}
+
+// Discriminator resultMap中的discriminator，按照列column的值选择case
+type Discriminator struct {
+	Column   string
+	JavaType string
+	JdbcType string
+	Cases    []DiscriminatorCase
+}
+
+// DiscriminatorCase discriminator中的case，ResultMap为空时使用内联的映射，内联的映射包含外层resultMap的映射
+type DiscriminatorCase struct {
+	Value      string
+	ResultMap  string
+	ResultType string
+	Results    []ResultMapping
+	Nested     []NestedMapping
+}
+
+// ColumnParam 组合键中参数名和列名的对应关系
+type ColumnParam struct {
+	Param  string
+	Column string
+}
+
+// ResultMap 获取id对应的resultMap，extends继承的映射会合并到结果中，子resultMap中的同名属性优先
+func (m *Mapper) ResultMap(id string) (*ResultMap, error) {
+	return m.resultMap(id, make(map[string]bool))
+}
+
+func (m *Mapper) resultMap(id string, seen map[string]bool) (*ResultMap, error) {
+	rm, ok := m.resultMaps[id]
+	if !ok {
+		return nil, fmt.Errorf("resultMap(%v) not found", id)
+	}
+	if rm.Extends == "" {
+		return rm, nil
+	}
+	if seen[id] {
+		return nil, fmt.Errorf("resultMap(%v) extends itself", id)
+	}
+	seen[id] = true
+
+	parent, err := m.resultMap(strings.TrimPrefix(rm.Extends, m.namespace+"."), seen)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *rm
+	merged.Results = nil
+	merged.Nested = nil
+	defined := make(map[string]bool)
+	for _, r := range rm.Results {
+		defined[r.Property] = true
+	}
+	for _, n := range rm.Nested {
+		defined[n.Property] = true
+	}
+	for _, r := range parent.Results {
+		if !defined[r.Property] {
+			merged.Results = append(merged.Results, r)
+		}
+	}
+	for _, n := range parent.Nested {
+		if !defined[n.Property] {
+			merged.Nested = append(merged.Nested, n)
+		}
+	}
+	merged.Results = append(merged.Results, rm.Results...)
+	merged.Nested = append(merged.Nested, rm.Nested...)
+	if merged.AutoMapping == "" {
+		merged.AutoMapping = parent.AutoMapping
+	}
+	return &merged, nil
+}
+
+// hasNestedResults 判断是否有不通过嵌套查询映射的association或者collection
+func (rm *ResultMap) hasNestedResults() bool {
+	for _, n := range rm.Nested {
+		if n.Select == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseResultMap(e *etree.Element) (*ResultMap, error) {
+	rm := &ResultMap{
+		ID:          e.SelectAttrValue("id", ""),
+		Type:        e.SelectAttrValue("type", ""),
+		Extends:     e.SelectAttrValue("extends", ""),
+		AutoMapping: e.SelectAttrValue("autoMapping", ""),
+	}
+	for _, c := range e.ChildElements() {
+		switch c.Tag {
+		case "id", "result":
+			rm.Results = append(rm.Results, parseResultMapping(c))
+		case "association", "collection":
+			nested, err := parseNestedMapping(c)
+			if err != nil {
+				return nil, fmt.Errorf("resultMap(%v) %v", rm.ID, err)
+			}
+			rm.Nested = append(rm.Nested, nested)
+		case "discriminator":
+			d, err := parseDiscriminator(c)
+			if err != nil {
+				return nil, fmt.Errorf("resultMap(%v) %v", rm.ID, err)
+			}
+			rm.Discriminator = d
+		}
+	}
+	return rm, nil
+}
+
+func parseDiscriminator(e *etree.Element) (*Discriminator, error) {
+	d := &Discriminator{
+		Column:   e.SelectAttrValue("column", ""),
+		JavaType: e.SelectAttrValue("javaType", ""),
+		JdbcType: e.SelectAttrValue("jdbcType", ""),
+	}
+	if d.Column == "" {
+		return nil, fmt.Errorf("discriminator column is empty")
+	}
+	for _, c := range e.SelectElements("case") {
+		dc := DiscriminatorCase{
+			Value:      strings.TrimSpace(c.SelectAttrValue("value", "")),
+			ResultMap:  c.SelectAttrValue("resultMap", ""),
+			ResultType: c.SelectAttrValue("resultType", ""),
+		}
+		for _, r := range c.ChildElements() {
+			switch r.Tag {
+			case "id", "result":
+				dc.Results = append(dc.Results, parseResultMapping(r))
+			case "association", "collection":
+				nested, err := parseNestedMapping(r)
+				if err != nil {
+					return nil, fmt.Errorf("case(%v) %v", dc.Value, err)
+				}
+				dc.Nested = append(dc.Nested, nested)
+			}
+		}
+		d.Cases = append(d.Cases, dc)
+	}
+	return d, nil
+}
+
+func parseResultMapping(e *etree.Element) ResultMapping {
+	return ResultMapping{
+		Property: e.SelectAttrValue("property", ""),
+		Column:   e.SelectAttrValue("column", ""),
+		JavaType: e.SelectAttrValue("javaType", ""),
+		JdbcType: e.SelectAttrValue("jdbcType", ""),
+		ID:       e.Tag == "id",
+	}
+}
+
+func parseNestedMapping(e *etree.Element) (nested NestedMapping, err error) {
+	nested = NestedMapping{
+		Property:     e.SelectAttrValue("property", ""),
+		Column:       e.SelectAttrValue("column", ""),
+		JavaType:     e.SelectAttrValue("javaType", ""),
+		OfType:       e.SelectAttrValue("ofType", ""),
+		Select:       e.SelectAttrValue("select", ""),
+		ResultMap:    e.SelectAttrValue("resultMap", ""),
+		FetchType:    e.SelectAttrValue("fetchType", ""),
+		ColumnPrefix: e.SelectAttrValue("columnPrefix", ""),
+		Collection:   e.Tag == "collection",
+	}
+	if nested.ColumnParams, err = parseColumnParams(nested.Column); err != nil {
+		return nested, fmt.Errorf("%v(%v) %v", e.Tag, nested.Property, err)
+	}
+	for _, c := range e.ChildElements() {
+		if c.Tag == "id" || c.Tag == "result" {
+			nested.Results = append(nested.Results, parseResultMapping(c))
+		}
+	}
+	return
+}
+
+// parseColumnParams 解析{a=col1,b=col2}形式的组合键，column不是组合键时返回nil
+func parseColumnParams(column string) (params []ColumnParam, err error) {
+	column = strings.TrimSpace(column)
+	if !strings.HasPrefix(column, "{") {
+		return nil, nil
+	}
+	if !strings.HasSuffix(column, "}") {
+		return nil, fmt.Errorf("column(%v) is not valid", column)
+	}
+	for _, pair := range strings.Split(column[1:len(column)-1], ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("column(%v) is not valid", column)
+		}
+		params = append(params, ColumnParam{
+			Param:  strings.TrimSpace(kv[0]),
+			Column: strings.TrimSpace(kv[1]),
+		})
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package mybaits
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapper_ResultMap(t *testing.T) {
+	m, err := NewMapper("testdata/session.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		want    *ResultMap
+		wantErr bool
+	}{
+		{
+			name: "extends",
+			id:   "blogResult",
+			want: &ResultMap{
+				ID:      "blogResult",
+				Type:    "Blog",
+				Extends: "baseBlog",
+				Results: []ResultMapping{
+					{Property: "id", Column: "id", ID: true},
+					{Property: "title", Column: "title"},
+				},
+				Nested: []NestedMapping{
+					{Property: "author", Column: "author_id", Select: "selectAuthor"},
+					{
+						Property:     "posts",
+						Column:       "{blogId=id,lang=lang}",
+						ColumnParams: []ColumnParam{{Param: "blogId", Column: "id"}, {Param: "lang", Column: "lang"}},
+						OfType:       "Post",
+						Select:       "Session.selectPosts",
+						Collection:   true,
+					},
+				},
+			},
+		},
+		{
+			name: "nestedResultMap",
+			id:   "blogWithAuthor",
+			want: &ResultMap{
+				ID:      "blogWithAuthor",
+				Type:    "Blog",
+				Extends: "baseBlog",
+				Results: []ResultMapping{
+					{Property: "id", Column: "id", ID: true},
+					{Property: "title", Column: "title"},
+				},
+				Nested: []NestedMapping{
+					{Property: "author", ResultMap: "authorResult", ColumnPrefix: "author_"},
+				},
+			},
+		},
+		{
+			name:    "notFound",
+			id:      "nothing",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.ResultMap(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResultMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResultMap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseColumnParams(t *testing.T) {
+	tests := []struct {
+		column  string
+		want    []ColumnParam
+		wantErr bool
+	}{
+		{column: "author_id"},
+		{column: "{id=author_id}", want: []ColumnParam{{Param: "id", Column: "author_id"}}},
+		{column: " { a = col1 , b = col2 } ", want: []ColumnParam{{Param: "a", Column: "col1"}, {Param: "b", Column: "col2"}}},
+		{column: "{a=col1", wantErr: true},
+		{column: "{a}", wantErr: true},
+		{column: "{=col1}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			got, err := parseColumnParams(tt.column)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseColumnParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseColumnParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
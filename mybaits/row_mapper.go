@@ -0,0 +1,374 @@
+package mybaits
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// row 查询结果中的一行
+type row struct {
+	columns []string
+	values  []interface{}
+	index   map[string]int // 大写的列名到下标的映射
+}
+
+func scanRow(rows *sql.Rows, columns []string) (*row, error) {
+	r := &row{
+		columns: columns,
+		values:  make([]interface{}, len(columns)),
+		index:   make(map[string]int),
+	}
+	dest := make([]interface{}, len(columns))
+	for i, c := range columns {
+		dest[i] = &r.values[i]
+		if _, ok := r.index[strings.ToUpper(c)]; !ok {
+			r.index[strings.ToUpper(c)] = i
+		}
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// get 获取列column的值，和mybatis一致，列名不区分大小写
+func (r *row) get(column string) (interface{}, bool) {
+	i, ok := r.index[strings.ToUpper(column)]
+	if !ok {
+		return nil, false
+	}
+	return r.values[i], true
+}
+
+// hasValue 判断是否有列名带有前缀prefix并且值不为NULL的列
+func (r *row) hasValue(prefix string) bool {
+	for i, c := range r.columns {
+		if r.values[i] != nil && strings.HasPrefix(strings.ToUpper(c), strings.ToUpper(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// rowMapper 将一行映射为Go的值，记录需要执行的嵌套查询
+type rowMapper struct {
+	session *Session
+	mapper  *Mapper // resultMap所在的映射文件
+	row     *row
+	skip    map[string]bool
+	pending []*nestedLoad
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// value 将行映射为typ的值，typ可以是结构体、结构体指针、map[string]interface{}或者基本类型，
+// typ是接口时使用resultMap的type注册的类型
+func (rm *rowMapper) value(typ reflect.Type, resultMap *ResultMap) (reflect.Value, error) {
+	if resultMap != nil {
+		var err error
+		if rm.mapper, resultMap, err = rm.discriminate(rm.mapper, resultMap, ""); err != nil {
+			return reflect.Value{}, err
+		}
+		if typ, err = rm.session.resultType(resultMap, typ); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	base := typ
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+	target := reflect.New(base).Elem()
+
+	switch {
+	case base.Kind() == reflect.Struct && base != reflect.TypeOf(time.Time{}) && !reflect.PtrTo(base).Implements(scannerType):
+		nested := resultMap != nil && resultMap.hasNestedResults()
+		if _, err := rm.mapStruct(target, resultMap, "", nested); err != nil {
+			return reflect.Value{}, err
+		}
+	case base.Kind() == reflect.Map && base.Key().Kind() == reflect.String:
+		target.Set(reflect.MakeMap(base))
+		for i, c := range rm.row.columns {
+			if rm.skip[c] {
+				continue
+			}
+			v := reflect.New(base.Elem()).Elem()
+			if err := assignColumn(v, rm.row.values[i]); err != nil {
+				return reflect.Value{}, fmt.Errorf("column(%v) %v", c, err)
+			}
+			target.SetMapIndex(reflect.ValueOf(c).Convert(base.Key()), v)
+		}
+	default:
+		if len(rm.row.columns) == 0 {
+			return reflect.Value{}, fmt.Errorf("no column")
+		}
+		if err := assignColumn(target, rm.row.values[0]); err != nil {
+			return reflect.Value{}, fmt.Errorf("column(%v) %v", rm.row.columns[0], err)
+		}
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		return target.Addr(), nil
+	}
+	return target, nil
+}
+
+// mapStruct 按照resultMap将列名带有前缀prefix的列映射到结构体target，resultMap为nil时只自动映射，
+// 返回是否有非NULL的列映射到了target。和mybatis的PARTIAL一致，nested为true时默认不自动映射
+func (rm *rowMapper) mapStruct(target reflect.Value, resultMap *ResultMap, prefix string, nested bool) (found bool, err error) {
+	mapped := make(map[string]bool)
+	if resultMap != nil {
+		for _, r := range resultMap.Results {
+			mapped[strings.ToUpper(prefix+r.Column)] = true
+			v, ok := rm.row.get(prefix + r.Column)
+			if !ok {
+				continue
+			}
+			field := structField(target, r.Property)
+			if !field.IsValid() {
+				return false, fmt.Errorf("property(%v) not found in %v", r.Property, target.Type())
+			}
+			if err = assignColumn(field, v); err != nil {
+				return false, fmt.Errorf("column(%v) %v", prefix+r.Column, err)
+			}
+			found = found || v != nil
+		}
+
+		for _, n := range resultMap.Nested {
+			var nestedFound bool
+			if nestedFound, err = rm.mapNested(target, n, prefix); err != nil {
+				return false, err
+			}
+			found = found || nestedFound
+		}
+	}
+
+	autoMapping := !nested
+	if resultMap != nil && resultMap.AutoMapping != "" {
+		autoMapping = resultMap.AutoMapping == "true"
+	}
+	if !autoMapping {
+		return
+	}
+	for i, c := range rm.row.columns {
+		if rm.skip[c] || mapped[strings.ToUpper(c)] || !strings.HasPrefix(strings.ToUpper(c), strings.ToUpper(prefix)) {
+			continue
+		}
+		field, err := rm.autoMappedField(target, c[len(prefix):])
+		if err != nil {
+			return false, err
+		}
+		if !field.IsValid() {
+			continue
+		}
+		if err = assignColumn(field, rm.row.values[i]); err != nil {
+			return false, fmt.Errorf("column(%v) %v", c, err)
+		}
+		found = found || rm.row.values[i] != nil
+	}
+	return
+}
+
+// mapNested 处理association和collection，嵌套查询记录到pending中，嵌套结果映射到同一行的列
+func (rm *rowMapper) mapNested(target reflect.Value, n NestedMapping, prefix string) (bool, error) {
+	field := structField(target, n.Property)
+	if !field.IsValid() {
+		return false, fmt.Errorf("property(%v) not found in %v", n.Property, target.Type())
+	}
+
+	if n.Select != "" {
+		load := &nestedLoad{mapper: rm.mapper, mapping: n, field: field}
+		if len(n.ColumnParams) == 0 {
+			v, _ := rm.row.get(prefix + n.Column)
+			load.param = v
+			load.key = []interface{}{v}
+		} else {
+			param := make(map[string]interface{})
+			for _, cp := range n.ColumnParams {
+				v, _ := rm.row.get(prefix + cp.Column)
+				param[cp.Param] = v
+				load.key = append(load.key, v)
+			}
+			load.param = param
+		}
+		// 和mybatis一致，键的所有列都为NULL时不执行嵌套查询
+		for _, k := range load.key {
+			if k != nil {
+				rm.pending = append(rm.pending, load)
+				break
+			}
+		}
+		return false, nil
+	}
+
+	if n.Collection {
+		return false, fmt.Errorf("collection(%v) without select is not supported", n.Property)
+	}
+
+	nestedMapper, nested := rm.mapper, &ResultMap{Results: n.Results}
+	if n.ResultMap != "" {
+		var err error
+		if nestedMapper, nested, err = rm.session.lookupResultMap(n.ResultMap, rm.mapper); err != nil {
+			return false, err
+		}
+	}
+
+	nestedPrefix := prefix + n.ColumnPrefix
+	nestedRowMapper := &rowMapper{session: rm.session, row: rm.row, skip: rm.skip, mapper: nestedMapper}
+	// 列都为NULL时不需要确定映射的类型
+	hasValue := rm.row.hasValue(nestedPrefix)
+	var err error
+	if hasValue {
+		if nestedRowMapper.mapper, nested, err = nestedRowMapper.discriminate(nestedMapper, nested, nestedPrefix); err != nil {
+			return false, err
+		}
+	}
+	typ := field.Type()
+	if typ.Kind() == reflect.Interface {
+		if !hasValue {
+			field.Set(reflect.Zero(field.Type()))
+			return false, nil
+		}
+		if typ, err = rm.session.resultType(nested, typ); err != nil {
+			return false, err
+		}
+	}
+
+	// 嵌套查询记录的字段需要指向最终的值，非指针字段直接映射到字段上
+	value := field
+	if typ.Kind() == reflect.Ptr {
+		value = reflect.New(typ.Elem()).Elem()
+	} else if typ != field.Type() {
+		value = reflect.New(typ).Elem()
+	}
+	found, err := nestedRowMapper.mapStruct(value, nested, nestedPrefix, true)
+	if err != nil {
+		return false, err
+	}
+	rm.pending = append(rm.pending, nestedRowMapper.pending...)
+	if !found {
+		field.Set(reflect.Zero(field.Type()))
+		rm.pending = rm.pending[:len(rm.pending)-len(nestedRowMapper.pending)]
+		return false, nil
+	}
+	switch {
+	case typ.Kind() == reflect.Ptr:
+		field.Set(value.Addr())
+	case typ != field.Type():
+		field.Set(value)
+	}
+	return true, nil
+}
+
+// assignColumn 将列的值v赋值给field，v为nil时field设置为零值
+func assignColumn(field reflect.Value, v interface{}) error {
+	if v == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.CanAddr() {
+		if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+			return scanner.Scan(v)
+		}
+	}
+	if field.Kind() == reflect.Ptr {
+		p := reflect.New(field.Type().Elem())
+		if err := assignColumn(p.Elem(), v); err != nil {
+			return err
+		}
+		field.Set(p)
+		return nil
+	}
+
+	if b, ok := v.([]byte); ok {
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+		v = string(b)
+	}
+	if s, ok := v.(string); ok && field.Kind() != reflect.String && field.Kind() != reflect.Interface {
+		return parseColumn(field, s)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Type().AssignableTo(field.Type()):
+		field.Set(rv)
+	case field.Kind() == reflect.String:
+		field.SetString(fmt.Sprint(v))
+	case field.Kind() == reflect.Bool && isNumber(v):
+		f, _ := toFloat(v)
+		field.SetBool(f != 0)
+	case isNumber(v) && isNumberKind(field.Kind()):
+		field.Set(rv.Convert(field.Type()))
+	default:
+		return fmt.Errorf("%T can not be assigned to %v", v, field.Type())
+	}
+	return nil
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+var timeLayouts = []string{"2006-01-02 15:04:05.999999999", time.RFC3339Nano, "2006-01-02", "15:04:05"}
+
+// parseColumn 将文本协议返回的字符串s解析为field的类型
+func parseColumn(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(s), 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+		return nil
+	}
+
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("%v is not time", s)
+	}
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+		field.SetBytes([]byte(s))
+		return nil
+	}
+	return fmt.Errorf("string can not be assigned to %v", field.Type())
+}
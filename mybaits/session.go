@@ -0,0 +1,380 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxNestedDepth 嵌套查询的最大深度，避免循环引用的嵌套查询无限执行
+const maxNestedDepth = 16
+
+// Session 通过Executor执行映射语句，并按照resultMap或者resultType将结果映射到Go的值，
+// Executor为*sql.DB时，上下文中有TxManager开启的事务时在事务中执行
+type Session struct {
+	exec    Executor
+	mappers []*Mapper
+	batch   bool
+	types   map[string]reflect.Type // 注册的Go类型
+	product string                  // 数据库产品名
+
+	discriminatorFallback bool // discriminator的值没有对应的case时使用当前的resultMap
+
+	sqlLogger *SQLLogger
+	metrics   *Metrics
+
+	naming        NamingStrategy
+	unknownColumn UnknownColumnBehavior
+	warned        sync.Map // 已经告警过的未知列和被忽略的fetchSize
+}
+
+// SessionOption 会话的选项
+type SessionOption func(s *Session)
+
+// WithNestedSelectBatching 设置是否合并嵌套查询，开启后同一个association或者collection的嵌套查询
+// 会改写为一条IN查询，避免N+1次查询，无法改写的嵌套查询仍然按照键逐个查询
+func WithNestedSelectBatching(enabled bool) SessionOption {
+	return func(s *Session) {
+		s.batch = enabled
+	}
+}
+
+// NewSession 生成在exec上执行mappers中语句的会话，语句ID可以是命名空间.ID，
+// 也可以是在所有映射文件中唯一的ID
+func NewSession(exec Executor, mappers []*Mapper, opts ...SessionOption) *Session {
+	s := &Session{
+		exec:    exec,
+		mappers: mappers,
+		types:   make(map[string]reflect.Type),
+	}
+	if db, ok := exec.(*sql.DB); ok {
+		s.product = productName(db)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Session 生成在exec上执行注册的所有映射文件中语句的会话，
+// 配置中的mapUnderscoreToCamelCase决定自动映射的命名策略，opts可以覆盖配置
+func (r *Registry) Session(exec Executor, opts ...SessionOption) *Session {
+	var mappers []*Mapper
+	for _, ns := range r.namespaces {
+		mappers = append(mappers, r.mappers[ns])
+	}
+	naming := NamingCaseInsensitive
+	if r.config.Settings.MapUnderscoreToCamelCase {
+		naming = NamingUnderscoreToCamelCase
+	}
+	opts = append([]SessionOption{WithNamingStrategy(naming)}, opts...)
+	return NewSession(exec, mappers, opts...)
+}
+
+// SelectList 执行查询语句id，将所有结果映射到dest，dest是切片的指针，
+// 切片的元素可以是结构体、结构体指针、map[string]interface{}或者对应单列的基本类型
+func (s *Session) SelectList(ctx context.Context, id string, param interface{}, dest interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dest(%T) is not pointer of slice", dest)
+	}
+
+	m, sid, err := s.lookup(id, nil)
+	if err != nil {
+		return err
+	}
+	values, err := s.query(ctx, m, sid, param, dv.Elem().Type().Elem(), 0)
+	if err != nil {
+		return err
+	}
+	dv.Elem().Set(makeSlice(dv.Elem().Type(), values))
+	return nil
+}
+
+// SelectOne 执行查询语句id，将唯一的结果映射到dest，没有结果时返回sql.ErrNoRows，多于一个结果时返回错误
+func (s *Session) SelectOne(ctx context.Context, id string, param interface{}, dest interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return fmt.Errorf("dest(%T) is not pointer", dest)
+	}
+
+	m, sid, err := s.lookup(id, nil)
+	if err != nil {
+		return err
+	}
+	values, err := s.query(ctx, m, sid, param, dv.Elem().Type(), 0)
+	if err != nil {
+		return err
+	}
+	switch len(values) {
+	case 0:
+		return sql.ErrNoRows
+	case 1:
+		dv.Elem().Set(values[0])
+		return nil
+	}
+	return fmt.Errorf("statement(%v) expected one result but found %v", id, len(values))
+}
+
+// Exec 执行insert、update或者delete语句id
+func (s *Session) Exec(ctx context.Context, id string, param interface{}) (sql.Result, error) {
+	m, sid, err := s.lookup(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	bound, err := m.Bind(sid, param)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result, err := executor(ctx, s.exec).ExecContext(ctx, bound.SQL, bound.Args...)
+	if err != nil {
+		s.observe(m, bound, start, -1, err)
+		return nil, fmt.Errorf("exec statement(%v) fail. err: %v", id, err)
+	}
+	rows, rerr := result.RowsAffected()
+	if rerr != nil {
+		rows = -1
+	}
+	s.observe(m, bound, start, rows, nil)
+	return result, nil
+}
+
+// observe 记录语句的执行结果，rows为查询返回或者影响的行数，未知时为-1
+func (s *Session) observe(m *Mapper, bound *BoundSQL, start time.Time, rows int64, err error) {
+	elapsed := time.Since(start)
+	if s.sqlLogger != nil {
+		s.sqlLogger.log(m, bound, elapsed, rows, err)
+	}
+	if s.metrics != nil {
+		s.metrics.record(statementKey(m, bound.ID), elapsed, rows, err)
+	}
+}
+
+// statementKey 获取语句的完整ID，映射文件有命名空间时为命名空间.ID
+func statementKey(m *Mapper, id string) string {
+	if m.namespace != "" {
+		return m.namespace + "." + id
+	}
+	return id
+}
+
+// lookup 查找语句id所在的映射文件，current不为nil时优先在current中查找
+func (s *Session) lookup(id string, current *Mapper) (*Mapper, string, error) {
+	if current != nil {
+		if _, ok := current.root[id]; ok {
+			return current, id, nil
+		}
+	}
+	if i := strings.LastIndex(id, "."); i >= 0 {
+		for _, m := range s.mappers {
+			if _, ok := m.root[id[i+1:]]; ok && m.namespace == id[:i] {
+				return m, id[i+1:], nil
+			}
+		}
+	}
+
+	var found *Mapper
+	for _, m := range s.mappers {
+		if _, ok := m.root[id]; ok {
+			if found != nil {
+				return nil, "", fmt.Errorf("statement(%v) is ambiguous in %v and %v", id, found.namespace, m.namespace)
+			}
+			found = m
+		}
+	}
+	if found == nil {
+		return nil, "", fmt.Errorf("statement(%v) not found", id)
+	}
+	return found, id, nil
+}
+
+// lookupResultMap 查找resultMap，id可以是命名空间.ID，否则在current中查找，
+// current为nil时在所有映射文件中查找唯一的resultMap
+func (s *Session) lookupResultMap(id string, current *Mapper) (*Mapper, *ResultMap, error) {
+	if current != nil {
+		if rm, err := current.ResultMap(id); err == nil {
+			return current, rm, nil
+		}
+	}
+	if i := strings.LastIndex(id, "."); i >= 0 {
+		for _, m := range s.mappers {
+			if m.namespace == id[:i] {
+				rm, err := m.ResultMap(id[i+1:])
+				return m, rm, err
+			}
+		}
+	}
+	if current != nil {
+		return nil, nil, fmt.Errorf("resultMap(%v) not found", id)
+	}
+
+	var found *Mapper
+	for _, m := range s.mappers {
+		if _, ok := m.resultMaps[id]; ok {
+			if found != nil {
+				return nil, nil, fmt.Errorf("resultMap(%v) is ambiguous in %v and %v", id, found.namespace, m.namespace)
+			}
+			found = m
+		}
+	}
+	if found == nil {
+		return nil, nil, fmt.Errorf("resultMap(%v) not found", id)
+	}
+	rm, err := found.ResultMap(id)
+	return found, rm, err
+}
+
+// query 执行查询语句并映射为elemType的值，depth为嵌套查询的深度
+func (s *Session) query(ctx context.Context, m *Mapper, id string, param interface{}, elemType reflect.Type, depth int) ([]reflect.Value, error) {
+	if depth > maxNestedDepth {
+		return nil, fmt.Errorf("nested select(%v) is deeper than %v", id, maxNestedDepth)
+	}
+
+	rmMapper, rm, elemType, err := s.resultOf(m, id, elemType)
+	if err != nil {
+		return nil, err
+	}
+
+	bound, err := m.Bind(id, param)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := executor(ctx, s.exec).QueryContext(ctx, bound.SQL, bound.Args...)
+	if err != nil {
+		s.observe(m, bound, start, -1, err)
+		return nil, fmt.Errorf("query statement(%v) fail. err: %v", id, err)
+	}
+	defer rows.Close()
+
+	mapped, pending, err := s.mapRows(rows, rmMapper, rm, elemType, nil)
+	if err != nil {
+		s.observe(m, bound, start, -1, err)
+		return nil, fmt.Errorf("map statement(%v) fail. err: %v", id, err)
+	}
+	s.observe(m, bound, start, int64(len(mapped)), nil)
+	if err = s.loadNested(ctx, pending, depth+1); err != nil {
+		return nil, err
+	}
+
+	values := make([]reflect.Value, 0, len(mapped))
+	for _, r := range mapped {
+		values = append(values, r.value)
+	}
+	return values, nil
+}
+
+// resultOf 获取语句id的resultMap以及它所在的映射文件，语句使用resultType时resultMap为nil，
+// typ是接口并且resultType注册了Go类型时返回注册的类型
+func (s *Session) resultOf(m *Mapper, id string, typ reflect.Type) (*Mapper, *ResultMap, reflect.Type, error) {
+	info, err := m.Statement(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if info.ResultMap != "" {
+		rmMapper, rm, err := s.lookupResultMap(info.ResultMap, m)
+		return rmMapper, rm, typ, err
+	}
+
+	if registered, ok := s.types[info.ResultType]; ok && typ.Kind() == reflect.Interface {
+		if !registered.AssignableTo(typ) {
+			return nil, nil, nil, fmt.Errorf("resultType(%v) of statement(%v) is %v which can not be assigned to %v",
+				info.ResultType, id, registered, typ)
+		}
+		typ = registered
+	}
+	return m, nil, typ, nil
+}
+
+// ScanRows 按照语句id的resultMap或者resultType将rows映射到dest并执行嵌套查询，
+// dest是切片的指针，rows由调用方关闭
+func (s *Session) ScanRows(ctx context.Context, rows *sql.Rows, id string, dest interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dest(%T) is not pointer of slice", dest)
+	}
+
+	m, sid, err := s.lookup(id, nil)
+	if err != nil {
+		return err
+	}
+	rmMapper, rm, elemType, err := s.resultOf(m, sid, dv.Elem().Type().Elem())
+	if err != nil {
+		return err
+	}
+	return s.scan(ctx, rows, rmMapper, rm, elemType, dv.Elem())
+}
+
+// MapRows 按照resultMap将rows映射到dest并执行嵌套查询，dest是切片的指针，rows由调用方关闭
+func (s *Session) MapRows(ctx context.Context, rows *sql.Rows, resultMap string, dest interface{}) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dest(%T) is not pointer of slice", dest)
+	}
+
+	m, rm, err := s.lookupResultMap(resultMap, nil)
+	if err != nil {
+		return err
+	}
+	return s.scan(ctx, rows, m, rm, dv.Elem().Type().Elem(), dv.Elem())
+}
+
+// scan 将rows映射为elemType的值后设置到切片dest
+func (s *Session) scan(ctx context.Context, rows *sql.Rows, m *Mapper, resultMap *ResultMap, elemType reflect.Type, dest reflect.Value) error {
+	mapped, pending, err := s.mapRows(rows, m, resultMap, elemType, nil)
+	if err != nil {
+		return fmt.Errorf("map rows fail. err: %v", err)
+	}
+	if err = s.loadNested(ctx, pending, 1); err != nil {
+		return err
+	}
+
+	values := make([]reflect.Value, 0, len(mapped))
+	for _, r := range mapped {
+		values = append(values, r.value)
+	}
+	dest.Set(makeSlice(dest.Type(), values))
+	return nil
+}
+
+// mappedRow 映射后的值以及对应的行
+type mappedRow struct {
+	value reflect.Value
+	row   *row
+}
+
+// mapRows 将rows中的每一行映射为elemType的值，skip中的列不参与映射
+func (s *Session) mapRows(rows *sql.Rows, m *Mapper, resultMap *ResultMap, elemType reflect.Type, skip map[string]bool) (mapped []mappedRow, pending []*nestedLoad, err error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for rows.Next() {
+		var r *row
+		if r, err = scanRow(rows, columns); err != nil {
+			return nil, nil, err
+		}
+		rowMapper := &rowMapper{session: s, mapper: m, row: r, skip: skip}
+		var v reflect.Value
+		if v, err = rowMapper.value(elemType, resultMap); err != nil {
+			return nil, nil, err
+		}
+		mapped = append(mapped, mappedRow{value: v, row: r})
+		pending = append(pending, rowMapper.pending...)
+	}
+	return mapped, pending, rows.Err()
+}
+
+func makeSlice(typ reflect.Type, values []reflect.Value) reflect.Value {
+	slice := reflect.MakeSlice(typ, 0, len(values))
+	for _, v := range values {
+		slice = reflect.Append(slice, v)
+	}
+	return slice
+}
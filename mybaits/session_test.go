@@ -0,0 +1,392 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testAuthor struct {
+	ID       int64
+	UserName string
+}
+
+type testPost struct {
+	ID      int64
+	Subject string
+}
+
+type testBlog struct {
+	ID     int64
+	Title  string
+	Author *testAuthor
+	Posts  []testPost
+}
+
+type testLazyBlog struct {
+	ID     int64
+	Author func() (*testAuthor, error)
+}
+
+var (
+	testAuthors = map[int64]string{10: "alice", 11: "bob"}
+	testPosts   = []struct {
+		id     int64
+		blogID int64
+		lang   string
+	}{{100, 1, "en"}, {101, 1, "en"}, {102, 1, "zh"}, {103, 3, "zh"}}
+)
+
+// querySession 模拟session.xml中的表，支持按键查询和合并后的IN查询
+func querySession(query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.HasPrefix(query, "select id, title, author_id, lang from blog"):
+		return &fakeRows{
+			columns: []string{"id", "title", "author_id", "lang"},
+			values: [][]driver.Value{
+				{int64(1), "go", int64(10), "en"},
+				{int64(2), "sql", int64(10), "en"},
+				{int64(3), "xml", int64(11), "zh"},
+				{int64(4), "draft", nil, "en"},
+			},
+		}, nil
+	case strings.HasPrefix(query, "select id, author_id from blog"):
+		return &fakeRows{
+			columns: []string{"id", "author_id"},
+			values:  [][]driver.Value{{int64(1), int64(10)}, {int64(3), int64(11)}},
+		}, nil
+	case strings.HasPrefix(query, "select b.id"):
+		return &fakeRows{
+			columns: []string{"id", "title", "author_id", "author_user_name"},
+			values: [][]driver.Value{
+				{int64(1), "go", int64(10), []byte("alice")},
+				{int64(4), "draft", nil, nil},
+			},
+		}, nil
+	case strings.HasPrefix(query, "select title from blog"):
+		return &fakeRows{
+			columns: []string{"title"},
+			values:  [][]driver.Value{{[]byte("go")}, {[]byte("sql")}},
+		}, nil
+	case strings.HasPrefix(query, "select id, user_name"):
+		rows := &fakeRows{columns: []string{"id", "user_name"}}
+		batch := strings.Contains(query, " in ")
+		if batch {
+			rows.columns = append(rows.columns, "mybaits_key_0")
+		}
+		for _, a := range args {
+			id := a.Value.(int64)
+			if name, ok := testAuthors[id]; ok {
+				row := []driver.Value{id, name}
+				if batch {
+					row = append(row, id)
+				}
+				rows.values = append(rows.values, row)
+			}
+		}
+		return rows, nil
+	case strings.HasPrefix(query, "select id, subject"):
+		rows := &fakeRows{columns: []string{"id", "subject"}}
+		batch := strings.Contains(query, " in ")
+		if batch {
+			rows.columns = append(rows.columns, "mybaits_key_0", "mybaits_key_1")
+		}
+		for i := 0; i+1 < len(args); i += 2 {
+			for _, p := range testPosts {
+				if p.blogID != args[i].Value.(int64) || p.lang != args[i+1].Value.(string) {
+					continue
+				}
+				row := []driver.Value{p.id, fmt.Sprint("post", p.id)}
+				if batch {
+					// 模拟文本协议返回的键
+					row = append(row, []byte(fmt.Sprint(p.blogID)), p.lang)
+				}
+				rows.values = append(rows.values, row)
+			}
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unexpected query %v", query)
+}
+
+const postQuery = "select id, subject from post\n         WHERE blog_id = ? and lang = ?"
+
+func newTestSession(t *testing.T, opts ...SessionOption) (*Session, *fakeDriver) {
+	m, err := NewMapper("testdata/session.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDriver{query: querySession}
+	opts = append([]SessionOption{WithNamingStrategy(NamingUnderscoreToCamelCase)}, opts...)
+	return NewSession(newFakeDB(d), []*Mapper{m}, opts...), d
+}
+
+func testBlogs() []testBlog {
+	alice, bob := &testAuthor{ID: 10, UserName: "alice"}, &testAuthor{ID: 11, UserName: "bob"}
+	return []testBlog{
+		{ID: 1, Title: "go", Author: alice, Posts: []testPost{{100, "post100"}, {101, "post101"}}},
+		{ID: 2, Title: "sql", Author: alice, Posts: []testPost{}},
+		{ID: 3, Title: "xml", Author: bob, Posts: []testPost{{103, "post103"}}},
+		{ID: 4, Title: "draft", Posts: []testPost{}},
+	}
+}
+
+func TestSession_SelectList(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []SessionOption
+		id        string
+		want      interface{}
+		wantCalls []string
+	}{
+		{
+			name: "nestedSelect",
+			id:   "selectBlogs",
+			want: testBlogs(),
+			wantCalls: []string{
+				"select id, title, author_id, lang from blog",
+				"select id, user_name from author where id = ?",
+				"select id, user_name from author where id = ?",
+				postQuery,
+				postQuery,
+				postQuery,
+				postQuery,
+			},
+		},
+		{
+			name: "batching",
+			opts: []SessionOption{WithNestedSelectBatching(true)},
+			id:   "selectBlogs",
+			want: testBlogs(),
+			wantCalls: []string{
+				"select id, title, author_id, lang from blog",
+				"select id, user_name, id as mybaits_key_0 from author where id in (?, ?)",
+				"select id, subject, blog_id as mybaits_key_0, lang as mybaits_key_1 from post where (blog_id, lang) in ((?, ?), (?, ?), (?, ?), (?, ?))",
+			},
+		},
+		{
+			name: "nestedResult",
+			id:   "selectBlogsWithAuthor",
+			want: []testBlog{
+				{ID: 1, Title: "go", Author: &testAuthor{ID: 10, UserName: "alice"}},
+				{ID: 4, Title: "draft"},
+			},
+			wantCalls: []string{
+				"select b.id, b.title, a.id as author_id, a.user_name as author_user_name\n        from blog b left join author a on b.author_id = a.id",
+			},
+		},
+		{
+			name:      "scalar",
+			id:        "Session.selectTitles",
+			want:      []string{"go", "sql"},
+			wantCalls: []string{"select title from blog"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, d := newTestSession(t, tt.opts...)
+			dest := reflect.New(reflect.TypeOf(tt.want))
+			if err := s.SelectList(context.Background(), tt.id, nil, dest.Interface()); err != nil {
+				t.Fatalf("SelectList() error = %v", err)
+			}
+			if got := dest.Elem().Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectList() = %+v, want %+v", got, tt.want)
+			}
+
+			var gotCalls []string
+			for _, c := range d.recorded() {
+				gotCalls = append(gotCalls, c.Query)
+			}
+			if !reflect.DeepEqual(gotCalls, tt.wantCalls) {
+				t.Errorf("calls = %q, want %q", gotCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSession_SelectList_batchChunks(t *testing.T) {
+	defer func(n int) { maxBatchKeys = n }(maxBatchKeys)
+	maxBatchKeys = 3
+
+	s, d := newTestSession(t, WithNestedSelectBatching(true))
+	var blogs []testBlog
+	if err := s.SelectList(context.Background(), "selectBlogs", nil, &blogs); err != nil {
+		t.Fatalf("SelectList() error = %v", err)
+	}
+	if want := testBlogs(); !reflect.DeepEqual(blogs, want) {
+		t.Errorf("SelectList() = %+v, want %+v", blogs, want)
+	}
+
+	var gotCalls []string
+	for _, c := range d.recorded() {
+		gotCalls = append(gotCalls, c.Query)
+	}
+	wantCalls := []string{
+		"select id, title, author_id, lang from blog",
+		"select id, user_name, id as mybaits_key_0 from author where id in (?, ?)",
+		"select id, subject, blog_id as mybaits_key_0, lang as mybaits_key_1 from post where (blog_id, lang) in ((?, ?), (?, ?), (?, ?))",
+		"select id, subject, blog_id as mybaits_key_0, lang as mybaits_key_1 from post where (blog_id, lang) in ((?, ?))",
+	}
+	if !reflect.DeepEqual(gotCalls, wantCalls) {
+		t.Errorf("calls = %q, want %q", gotCalls, wantCalls)
+	}
+}
+
+func TestMapper_batchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		where string
+		want  string
+	}{
+		{
+			name:  "equal",
+			where: "author_id = #{id} and lang = 'en'",
+			want:  "select id, author_id as mybaits_key_0 from blog where author_id in (?, ?) and lang = 'en'",
+		},
+		{
+			name:  "repeated",
+			where: "author_id = #{id} and editor_id = #{id}",
+		},
+		{
+			name:  "notEqual",
+			where: "author_id = #{id} or editor_id > #{id}",
+		},
+		{
+			name:  "function",
+			where: "author_id = abs(#{id})",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMapperFromBytes("batch.xml", []byte(`<mapper namespace="Batch">
+    <select id="selectBlogs">select id from blog where `+tt.where+`</select>
+</mapper>`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			batch, ok := m.batchQuery("selectBlogs", NestedMapping{}, [][]interface{}{{int64(10)}, {int64(11)}})
+			if tt.want == "" {
+				if ok {
+					t.Errorf("batchQuery() = %v %v, want false", batch.sql, batch.args)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("batchQuery() = false")
+			}
+			if batch.sql != tt.want || !reflect.DeepEqual(batch.args, []interface{}{int64(10), int64(11)}) {
+				t.Errorf("batchQuery() = %v %v, want %v", batch.sql, batch.args, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession_SelectList_lazyAfterTx(t *testing.T) {
+	s, d := newTestSession(t)
+	var blogs []testLazyBlog
+	if err := NewTxManager(s.exec.(*sql.DB)).Tx(context.Background(), func(ctx context.Context) error {
+		return s.SelectList(ctx, "selectLazyBlogs", nil, &blogs)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	author, err := blogs[1].Author()
+	if err != nil {
+		t.Fatalf("Author() error = %v", err)
+	}
+	if want := (&testAuthor{ID: 11, UserName: "bob"}); !reflect.DeepEqual(author, want) {
+		t.Errorf("Author() = %+v, want %+v", author, want)
+	}
+	if calls := d.recorded(); !strings.HasPrefix(calls[len(calls)-1].Query, "select id, user_name from author") {
+		t.Errorf("calls = %q", calls)
+	}
+}
+
+func TestSession_SelectList_lazy(t *testing.T) {
+	s, d := newTestSession(t)
+	var blogs []testLazyBlog
+	if err := s.SelectList(context.Background(), "selectLazyBlogs", nil, &blogs); err != nil {
+		t.Fatal(err)
+	}
+	if len(blogs) != 2 || len(d.recorded()) != 1 {
+		t.Fatalf("blogs = %v calls = %v", len(blogs), len(d.recorded()))
+	}
+
+	for i := 0; i < 2; i++ {
+		author, err := blogs[1].Author()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (&testAuthor{ID: 11, UserName: "bob"}); !reflect.DeepEqual(author, want) {
+			t.Errorf("Author() = %+v, want %+v", author, want)
+		}
+	}
+	if len(d.recorded()) != 2 {
+		t.Errorf("calls = %v, want 2", len(d.recorded()))
+	}
+}
+
+func TestSession_SelectOne(t *testing.T) {
+	s, _ := newTestSession(t)
+	tests := []struct {
+		name    string
+		id      string
+		param   interface{}
+		dest    interface{}
+		want    interface{}
+		wantErr error
+	}{
+		{
+			name:  "one",
+			id:    "selectAuthor",
+			param: int64(10),
+			dest:  &testAuthor{},
+			want:  &testAuthor{ID: 10, UserName: "alice"},
+		},
+		{
+			name:  "map",
+			id:    "selectAuthor",
+			param: int64(11),
+			dest:  &map[string]interface{}{},
+			want:  &map[string]interface{}{"id": int64(11), "user_name": "bob"},
+		},
+		{
+			name:    "noRows",
+			id:      "selectAuthor",
+			param:   int64(12),
+			dest:    &testAuthor{},
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name: "tooMany",
+			id:   "selectTitles",
+			dest: new(string),
+		},
+		{
+			name: "notFound",
+			id:   "selectNothing",
+			dest: new(string),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.SelectOne(context.Background(), tt.id, tt.param, tt.dest)
+			if tt.want == nil {
+				if err == nil || (tt.wantErr != nil && err != tt.wantErr) {
+					t.Errorf("SelectOne() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectOne() error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.dest, tt.want) {
+				t.Errorf("SelectOne() = %+v, want %+v", tt.dest, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package mybaits
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Breeze0806/go/log"
+)
+
+// maskedValue 屏蔽后的参数值
+const maskedValue = "******"
+
+// SQLLogOption SQL日志的选项
+type SQLLogOption func(l *SQLLogger)
+
+// WithLogger 设置打印日志的Logger，默认使用log.GetLogger()
+func WithLogger(logger log.Logger) SQLLogOption {
+	return func(l *SQLLogger) {
+		l.logger = logger
+	}
+}
+
+// WithSlowThreshold 设置慢查询的阈值，耗时不小于阈值的语句使用Warnf打印并且不参与采样，0表示不区分慢查询
+func WithSlowThreshold(threshold time.Duration) SQLLogOption {
+	return func(l *SQLLogger) {
+		l.slow = threshold
+	}
+}
+
+// WithSampleRate 设置语句ids的采样率，ids为空时设置所有语句的默认采样率，默认为1，
+// 语句ID可以是命名空间.ID，也可以是ID，采样率为0.1时每10次执行打印1次，出错和慢查询总是打印
+func WithSampleRate(rate float64, ids ...string) SQLLogOption {
+	return func(l *SQLLogger) {
+		if len(ids) == 0 {
+			l.rate = rate
+			return
+		}
+		for _, id := range ids {
+			l.rates[id] = rate
+		}
+	}
+}
+
+// WithMaskedParams 设置需要屏蔽值的参数，names是参数名或者属性路径的最后一段，如password会屏蔽#{user.password}
+func WithMaskedParams(names ...string) SQLLogOption {
+	return func(l *SQLLogger) {
+		for _, name := range names {
+			l.masked[name] = true
+		}
+	}
+}
+
+// SQLLogger 记录会话通过database/sql执行的语句，包括语句ID、SQL、参数值、行数和耗时，
+// 正常执行时使用Infof打印，慢查询使用Warnf，出错时使用Errorf
+type SQLLogger struct {
+	logger log.Logger
+	slow   time.Duration
+	rate   float64
+	rates  map[string]float64
+	masked map[string]bool
+
+	mu     sync.Mutex
+	counts map[string]int64 // 语句的执行次数，用于采样
+}
+
+// NewSQLLogger 生成SQL日志
+func NewSQLLogger(opts ...SQLLogOption) *SQLLogger {
+	l := &SQLLogger{
+		rate:   1,
+		rates:  make(map[string]float64),
+		masked: make(map[string]bool),
+		counts: make(map[string]int64),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// WithSQLLogger 设置会话的SQL日志，默认不打印
+func WithSQLLogger(l *SQLLogger) SessionOption {
+	return func(s *Session) {
+		s.sqlLogger = l
+	}
+}
+
+// log 打印语句bound的执行结果
+func (l *SQLLogger) log(m *Mapper, bound *BoundSQL, elapsed time.Duration, rows int64, err error) {
+	id := statementKey(m, bound.ID)
+	logger := l.logger
+	if logger == nil {
+		logger = log.GetLogger()
+	}
+
+	switch {
+	case err != nil:
+		logger.Errorf("statement(%v) fail. duration: %v sql: %v args: %v err: %v",
+			id, elapsed, bound.SQL, l.args(bound), err)
+	case l.slow > 0 && elapsed >= l.slow:
+		logger.Warnf("slow statement(%v) rows: %v duration: %v threshold: %v sql: %v args: %v",
+			id, rows, elapsed, l.slow, bound.SQL, l.args(bound))
+	case l.sampled(id, bound.ID):
+		logger.Infof("statement(%v) rows: %v duration: %v sql: %v args: %v",
+			id, rows, elapsed, bound.SQL, l.args(bound))
+	}
+}
+
+// sampled 按照语句的采样率判断本次执行是否打印，采样率为r时第n次执行在floor(n*r)增加时打印
+func (l *SQLLogger) sampled(id, shortID string) bool {
+	rate, ok := l.rates[id]
+	if !ok {
+		if rate, ok = l.rates[shortID]; !ok {
+			rate = l.rate
+		}
+	}
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counts[id]++
+	n := float64(l.counts[id])
+	return int64(n*rate) > int64((n-1)*rate)
+}
+
+// args 格式化参数值，有参数名时格式为name=value，屏蔽的参数值显示为******
+func (l *SQLLogger) args(bound *BoundSQL) string {
+	b := &strings.Builder{}
+	b.WriteString("[")
+	for i, arg := range bound.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		name := ""
+		if i < len(bound.Params) {
+			name = strings.TrimSpace(bound.Params[i].Name)
+			b.WriteString(name)
+			b.WriteString("=")
+		}
+		if l.isMasked(name) {
+			b.WriteString(maskedValue)
+			continue
+		}
+		b.WriteString(formatArg(arg))
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+func (l *SQLLogger) isMasked(name string) bool {
+	if name == "" {
+		return false
+	}
+	if l.masked[name] {
+		return true
+	}
+	return l.masked[name[strings.LastIndex(name, ".")+1:]]
+}
+
+func formatArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("<%v bytes>", len(v))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", arg)
+}
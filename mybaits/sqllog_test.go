@@ -0,0 +1,147 @@
+package mybaits
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Breeze0806/go/log"
+)
+
+func TestSession_sqlLogger(t *testing.T) {
+	updatePassword := func(s *Session) error {
+		_, err := s.Exec(context.Background(), "updatePassword", map[string]interface{}{
+			"user": map[string]interface{}{"id": 10, "password": "secret"},
+		})
+		return err
+	}
+	selectTitles := func(s *Session) error {
+		var titles []string
+		return s.SelectList(context.Background(), "selectTitles", nil, &titles)
+	}
+
+	tests := []struct {
+		name      string
+		opts      []SQLLogOption
+		exec      func(query string, args []driver.NamedValue) (driver.Result, error)
+		run       func(s *Session) error
+		times     int
+		want      []string
+		wantLines int
+		wantErr   bool
+	}{
+		{
+			name:      "query",
+			run:       selectTitles,
+			times:     1,
+			want:      []string{"[INFO] statement(Session.selectTitles) rows: 2 duration: "},
+			wantLines: 1,
+		},
+		{
+			name:  "masked",
+			opts:  []SQLLogOption{WithMaskedParams("password")},
+			run:   updatePassword,
+			times: 1,
+			want: []string{
+				"[INFO] statement(Session.updatePassword) rows: 1 duration: ",
+				"args: [user.password=******, user.id=10]",
+			},
+		},
+		{
+			name:  "slow",
+			opts:  []SQLLogOption{WithSlowThreshold(time.Nanosecond), WithSampleRate(0)},
+			run:   selectTitles,
+			times: 1,
+			want:  []string{"[WARN] slow statement(Session.selectTitles) rows: 2 duration: ", "threshold: 1ns"},
+		},
+		{
+			name: "error",
+			exec: func(query string, args []driver.NamedValue) (driver.Result, error) {
+				return nil, errors.New("connection reset")
+			},
+			run:     updatePassword,
+			times:   1,
+			want:    []string{"[ERROR] statement(Session.updatePassword) fail.", `args: [user.password="secret", user.id=10] err: connection reset`},
+			wantErr: true,
+		},
+		{
+			name:      "sampled",
+			opts:      []SQLLogOption{WithSampleRate(0.5, "selectTitles")},
+			run:       selectTitles,
+			times:     4,
+			want:      []string{"rows: 2"},
+			wantLines: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			opts := append([]SQLLogOption{WithLogger(log.NewDefaultLogger(buf, log.DebugLevel, ""))}, tt.opts...)
+			s, d := newTestSession(t, WithSQLLogger(NewSQLLogger(opts...)))
+			d.exec = tt.exec
+			if d.exec == nil {
+				d.exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+					return driver.RowsAffected(1), nil
+				}
+			}
+			for i := 0; i < tt.times; i++ {
+				if err := tt.run(s); (err != nil) != tt.wantErr {
+					t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+
+			if lines := strings.Count(buf.String(), "\n"); tt.wantLines > 0 && lines != tt.wantLines {
+				t.Fatalf("got %v lines, want %v: %v", lines, tt.wantLines, buf.String())
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(buf.String(), w) {
+					t.Errorf("log = %v, want contains %v", buf.String(), w)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLLogger_sampled(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want string
+	}{
+		{rate: 1, want: "111111"},
+		{rate: 0, want: "000000"},
+		{rate: 0.5, want: "010101"},
+		{rate: 1.0 / 3, want: "001001"},
+	}
+	for _, tt := range tests {
+		l := NewSQLLogger(WithSampleRate(tt.rate))
+		got := ""
+		for i := 0; i < len(tt.want); i++ {
+			if l.sampled("Session.selectTitles", "selectTitles") {
+				got += "1"
+			} else {
+				got += "0"
+			}
+		}
+		if got != tt.want {
+			t.Errorf("sampled(%v) = %v, want %v", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestSession_sqlLogger_batchMasked(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewSQLLogger(WithLogger(log.NewDefaultLogger(buf, log.DebugLevel, "")), WithMaskedParams("lang"))
+	s, _ := newTestSession(t, WithNestedSelectBatching(true), WithSQLLogger(logger))
+	var blogs []testBlog
+	if err := s.SelectList(context.Background(), "selectBlogs", nil, &blogs); err != nil {
+		t.Fatal(err)
+	}
+	want := "args: [blogId=1, lang=******, blogId=2, lang=******, blogId=3, lang=******, blogId=4, lang=******]"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("log = %v, want contains %v", buf.String(), want)
+	}
+}
@@ -0,0 +1,130 @@
+package mybaits
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// StatementInfo 映射语句的元数据
+type StatementInfo struct {
+	ID            string
+	Kind          string // sql, select, insert, update, delete
+	ParameterType string
+	ResultType    string
+	ResultMap     string
+	Timeout       int // 单位秒，0表示未设置
+	FetchSize     int // 0表示未设置
+	StatementType string
+	DatabaseID    string
+	FlushCache    bool
+	UseCache      bool
+	Includes      []string // 引用的sql片段，包括片段中再引用的片段
+	Params        []Param  // 按文档顺序排列的参数，包括引用片段中的参数
+}
+
+// Statement 获取id对应语句的元数据，未设置的属性使用mybatis的默认值
+func (m *Mapper) Statement(id string) (info *StatementInfo, err error) {
+	child, ok := m.root[id]
+	if !ok {
+		err = fmt.Errorf("statement(%v) not found", id)
+		return
+	}
+
+	info = &StatementInfo{
+		ID:            id,
+		Kind:          child.Tag,
+		ParameterType: child.SelectAttrValue("parameterType", ""),
+		ResultType:    child.SelectAttrValue("resultType", ""),
+		ResultMap:     child.SelectAttrValue("resultMap", ""),
+		StatementType: child.SelectAttrValue("statementType", "PREPARED"),
+		DatabaseID:    child.SelectAttrValue("databaseId", ""),
+	}
+
+	if info.Timeout, err = intAttr(child, "timeout"); err != nil {
+		return nil, err
+	}
+	if info.FetchSize, err = intAttr(child, "fetchSize"); err != nil {
+		return nil, err
+	}
+	isSelect := child.Tag == "select"
+	if info.FlushCache, err = boolAttr(child, "flushCache", !isSelect); err != nil {
+		return nil, err
+	}
+	if info.UseCache, err = boolAttr(child, "useCache", isSelect); err != nil {
+		return nil, err
+	}
+
+	w := &statementWalker{
+		fragments: newFragments(m.root),
+		seen:      make(map[string]bool),
+		listed:    make(map[string]bool),
+	}
+	w.walk(child, nil)
+	info.Includes = w.includes
+	info.Params = w.params
+	return
+}
+
+// statementWalker 按文档顺序遍历语句，展开其中的include
+type statementWalker struct {
+	fragments *fragments
+	includes  []string
+	params    []Param
+	seen      map[string]bool
+	listed    map[string]bool
+}
+
+func (w *statementWalker) walk(e *etree.Element, properties map[string]string) {
+	for _, token := range e.Child {
+		switch t := token.(type) {
+		case *etree.CharData:
+			for _, p := range findParams(convertCDATA(t.Data, false)) {
+				if !w.seen[p.FullName] {
+					w.seen[p.FullName] = true
+					w.params = append(w.params, p)
+				}
+			}
+		case *etree.Element:
+			if t.Tag != "include" {
+				w.walk(t, properties)
+				continue
+			}
+			// 不存在或者循环引用的片段在Bind时报错，这里忽略
+			_ = w.fragments.expand(t, properties, func(refID string, fragment *etree.Element, props map[string]string) error {
+				if !w.listed[refID] {
+					w.listed[refID] = true
+					w.includes = append(w.includes, refID)
+				}
+				w.walk(fragment, props)
+				return nil
+			})
+		}
+	}
+}
+
+func intAttr(e *etree.Element, key string) (int, error) {
+	value := strings.TrimSpace(e.SelectAttrValue(key, ""))
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("attribute %v(%v) is not int. err: %v", key, value, err)
+	}
+	return i, nil
+}
+
+func boolAttr(e *etree.Element, key string, dflt bool) (bool, error) {
+	value := strings.TrimSpace(e.SelectAttrValue(key, ""))
+	if value == "" {
+		return dflt, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("attribute %v(%v) is not bool. err: %v", key, value, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,83 @@
+package mybaits
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapper_Statement(t *testing.T) {
+	initTest()
+	tests := []struct {
+		name    string
+		id      string
+		want    *StatementInfo
+		wantErr bool
+	}{
+		{
+			name: "testAttributes",
+			id:   "testAttributes",
+			want: &StatementInfo{
+				ID:            "testAttributes",
+				Kind:          "select",
+				ParameterType: "map",
+				ResultType:    "Fruit",
+				Timeout:       10,
+				FetchSize:     100,
+				StatementType: "PREPARED",
+				FlushCache:    false,
+				UseCache:      false,
+				Includes:      []string{"sometable"},
+				Params: []Param{
+					{FullName: "#{price,jdbcType=INTEGER}", Name: "price", JdbcType: "INTEGER", MockValue: "?"},
+					{FullName: "${category}", Name: "category", MockValue: "?"},
+				},
+			},
+		},
+		{
+			name: "testInclude",
+			id:   "testInclude",
+			want: &StatementInfo{
+				ID:            "testInclude",
+				Kind:          "select",
+				StatementType: "PREPARED",
+				UseCache:      true,
+				Includes:      []string{"someinclude", "sometable", "somewhere"},
+				Params: []Param{
+					{FullName: "#{category}", Name: "category", MockValue: "?"},
+				},
+			},
+		},
+		{
+			name: "testSet",
+			id:   "testSet",
+			want: &StatementInfo{
+				ID:            "testSet",
+				Kind:          "update",
+				StatementType: "PREPARED",
+				FlushCache:    true,
+				Params: []Param{
+					{FullName: "#{category}", Name: "category", MockValue: "?"},
+					{FullName: "${price}", Name: "price", MockValue: "?"},
+					{FullName: "#{name}", Name: "name", MockValue: "?"},
+				},
+			},
+		},
+		{
+			name:    "notFound",
+			id:      "notFound",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapper.Statement(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Mapper.Statement() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mapper.Statement() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
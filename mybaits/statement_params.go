@@ -0,0 +1,197 @@
+package mybaits
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// StatementParams 语句中所有可达的参数引用，包括include引用的sql片段中的参数
+type StatementParams struct {
+	Inputs []ParamRef // 需要调用方传入的顶层参数，按参数名去重
+	Locals []ParamRef // 引用bind或者foreach中定义的局部变量的参数
+}
+
+// ParamRef 参数引用
+type ParamRef struct {
+	Param
+	Root   string // 参数名的第一段，如user.name的Root为user
+	Source string // 引用的位置: #, $, test, collection, bind
+	Scope  string // 局部参数的定义者，如bind:likeName，foreach:apples，顶层参数为空
+}
+
+// Params 获取id对应语句中所有可达的#{}和${}参数，以及test、foreach的collection、
+// bind的value表达式中引用的参数，并区分顶层参数和局部参数
+func (m *Mapper) Params(id string) (*StatementParams, error) {
+	child, ok := m.root[id]
+	if !ok {
+		return nil, fmt.Errorf("statement(%v) not found", id)
+	}
+
+	w := &paramWalker{
+		fragments: newFragments(m.root),
+		locals:    make(map[string]string),
+		inputs:    make(map[string]bool),
+		seen:      make(map[string]bool),
+		params: &StatementParams{
+			Inputs: []ParamRef{},
+			Locals: []ParamRef{},
+		},
+	}
+	w.walk(child, nil)
+	return w.params, nil
+}
+
+// paramWalker 按文档顺序遍历语句，记录参数所在的作用域
+type paramWalker struct {
+	fragments *fragments
+	locals    map[string]string // 局部变量名到定义者的映射
+	inputs    map[string]bool
+	seen      map[string]bool
+	params    *StatementParams
+}
+
+func (w *paramWalker) walk(e *etree.Element, properties map[string]string) {
+	for _, token := range e.Child {
+		switch t := token.(type) {
+		case *etree.CharData:
+			for _, p := range findParams(convertCDATA(t.Data, false)) {
+				if _, ok := properties[p.Name]; ok && p.FullName[0] == '$' {
+					continue
+				}
+				w.add(p, p.FullName[:1])
+			}
+		case *etree.Element:
+			w.walkElement(t, properties)
+		}
+	}
+}
+
+func (w *paramWalker) walkElement(e *etree.Element, properties map[string]string) {
+	switch e.Tag {
+	case "include":
+		// 不存在或者循环引用的片段在Bind时报错，这里忽略
+		_ = w.fragments.expand(e, properties, func(_ string, fragment *etree.Element, props map[string]string) error {
+			w.walk(fragment, props)
+			return nil
+		})
+	case "if", "when":
+		w.addExpression(e.SelectAttrValue("test", ""), "test")
+		w.walk(e, properties)
+	case "bind":
+		w.addExpression(e.SelectAttrValue("value", ""), "bind")
+		// 和mybatis一致，bind定义的变量在语句的后续部分都可以访问
+		name := e.SelectAttrValue("name", "")
+		w.locals[name] = "bind:" + name
+	case "foreach":
+		collection := e.SelectAttrValue("collection", "")
+		w.addExpression(collection, "collection")
+
+		scope := "foreach:" + collection
+		shadowed := make(map[string]string)
+		var declared []string
+		for _, attr := range []string{"item", "index"} {
+			if name := e.SelectAttrValue(attr, ""); name != "" {
+				if old, ok := w.locals[name]; ok {
+					shadowed[name] = old
+				}
+				w.locals[name] = scope
+				declared = append(declared, name)
+			}
+		}
+		w.walk(e, properties)
+		for _, name := range declared {
+			delete(w.locals, name)
+			if old, ok := shadowed[name]; ok {
+				w.locals[name] = old
+			}
+		}
+	default:
+		w.walk(e, properties)
+	}
+}
+
+func (w *paramWalker) add(p Param, source string) {
+	ref := ParamRef{
+		Param:  p,
+		Root:   paramRoot(p.Name),
+		Source: source,
+		Scope:  w.locals[paramRoot(p.Name)],
+	}
+	if ref.Root == "_databaseId" {
+		return
+	}
+
+	if ref.Scope == "" {
+		if !w.inputs[ref.Name] {
+			w.inputs[ref.Name] = true
+			w.params.Inputs = append(w.params.Inputs, ref)
+		}
+		return
+	}
+
+	key := ref.Scope + "|" + ref.Source + "|" + ref.FullName + ref.Name
+	if !w.seen[key] {
+		w.seen[key] = true
+		w.params.Locals = append(w.params.Locals, ref)
+	}
+}
+
+func (w *paramWalker) addExpression(expr, source string) {
+	for _, name := range expressionIdentifiers(expr) {
+		w.add(Param{Name: name}, source)
+	}
+}
+
+// paramRoot 获取参数名的第一段
+func paramRoot(name string) string {
+	name = strings.TrimSpace(name)
+	if i := strings.IndexAny(name, ".["); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+var expressionKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "null": true, "true": true, "false": true,
+	"eq": true, "neq": true, "lt": true, "lte": true, "gt": true, "gte": true,
+	"in": true, "instanceof": true, "new": true,
+	"shl": true, "shr": true, "ushr": true, "band": true, "bor": true, "xor": true,
+}
+
+// expressionIdentifiers 获取OGNL表达式expr中引用的变量，如name != null and list.size() > 0中的name和list，
+// 表达式无法解析时返回nil
+func expressionIdentifiers(expr string) (names []string) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	isOperator := func(i int, op string) bool {
+		return i >= 0 && i < len(tokens) && tokens[i].kind == tokenOperator && tokens[i].text == op
+	}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		// 属性名和方法名跟在.之后，不是变量
+		if t.kind != tokenIdent || expressionKeywords[t.text] || isOperator(i-1, ".") {
+			continue
+		}
+		// 方法名或者构造函数名，如new Date()中的Date，不是变量
+		if isOperator(i+1, "(") {
+			continue
+		}
+
+		name := t.text
+		for isOperator(i+1, ".") && i+2 < len(tokens) && tokens[i+2].kind == tokenIdent && !isOperator(i+3, "(") {
+			name += "." + tokens[i+2].text
+			i += 2
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return
+}
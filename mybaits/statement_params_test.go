@@ -0,0 +1,118 @@
+package mybaits
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapper_Params(t *testing.T) {
+	initTest()
+	tests := []struct {
+		name    string
+		id      string
+		want    *StatementParams
+		wantErr bool
+	}{
+		{
+			name: "testInclude",
+			id:   "testInclude",
+			want: &StatementParams{
+				Inputs: []ParamRef{
+					{Param: Param{FullName: "#{category}", Name: "category", MockValue: "?"}, Root: "category", Source: "#"},
+				},
+				Locals: []ParamRef{},
+			},
+		},
+		{
+			name: "testForeach",
+			id:   "testForeach",
+			want: &StatementParams{
+				Inputs: []ParamRef{
+					{Param: Param{Name: "apples"}, Root: "apples", Source: "collection"},
+				},
+				Locals: []ParamRef{
+					{Param: Param{Name: "name"}, Root: "name", Source: "test", Scope: "foreach:apples"},
+					{Param: Param{FullName: "#{name}", Name: "name", MockValue: "?"}, Root: "name", Source: "#", Scope: "foreach:apples"},
+				},
+			},
+		},
+		{
+			name: "testInsertMulti",
+			id:   "testInsertMulti",
+			want: &StatementParams{
+				Inputs: []ParamRef{
+					{Param: Param{Name: "fruits"}, Root: "fruits", Source: "collection"},
+				},
+				Locals: []ParamRef{
+					{Param: Param{FullName: "#{fruit.name}", Name: "fruit.name", MockValue: "?"}, Root: "fruit", Source: "#", Scope: "foreach:fruits"},
+					{Param: Param{FullName: "#{fruit.category}", Name: "fruit.category", MockValue: "?"}, Root: "fruit", Source: "#", Scope: "foreach:fruits"},
+					{Param: Param{FullName: "${fruit.price}", Name: "fruit.price", MockValue: "?"}, Root: "fruit", Source: "$", Scope: "foreach:fruits"},
+				},
+			},
+		},
+		{
+			name: "testBind",
+			id:   "testBind",
+			want: &StatementParams{
+				Inputs: []ParamRef{
+					{Param: Param{Name: "name"}, Root: "name", Source: "bind"},
+				},
+				Locals: []ParamRef{
+					{Param: Param{FullName: "#{likeName}", Name: "likeName", MockValue: "?"}, Root: "likeName", Source: "#", Scope: "bind:likeName"},
+				},
+			},
+		},
+		{
+			name: "testIf",
+			id:   "testIf",
+			want: &StatementParams{
+				Inputs: []ParamRef{
+					{Param: Param{Name: "category"}, Root: "category", Source: "test"},
+					{Param: Param{Name: "price"}, Root: "price", Source: "test"},
+				},
+				Locals: []ParamRef{},
+			},
+		},
+		{
+			name:    "notFound",
+			id:      "notFound",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapper.Params(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Mapper.Params() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mapper.Params() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_expressionIdentifiers(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: "name != null and name != ''", want: []string{"name"}},
+		{expr: "list != null and list.size() > 0", want: []string{"list"}},
+		{expr: "user.name == 'it''s' or user.age gte 18", want: []string{"user.name", "user.age"}},
+		{expr: "'%' + name + '%'", want: []string{"name"}},
+		{expr: "@java.lang.Math@max(a, 1) > b.length()", want: []string{"a", "b"}},
+		{expr: "type == 'user.name and x' or type == \"y(\"", want: []string{"type"}},
+		{expr: "name.substring(0, n).length() > list[0].size", want: []string{"name", "n", "list"}},
+		{expr: "name != ", want: []string{"name"}},
+		{expr: "name == 'x", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := expressionIdentifiers(tt.expr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expressionIdentifiers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+import java.io.FileInputStream;
+import java.io.InputStream;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+
+import javax.xml.parsers.DocumentBuilder;
+import javax.xml.parsers.DocumentBuilderFactory;
+
+import org.apache.ibatis.builder.xml.XMLMapperBuilder;
+import org.apache.ibatis.mapping.BoundSql;
+import org.apache.ibatis.session.Configuration;
+import org.w3c.dom.Element;
+import org.w3c.dom.Node;
+import org.w3c.dom.NodeList;
+
+/**
+ * 使用mybatis生成trim.xml中各用例的SQL，输出即为trim_expected.xml。
+ *
+ * 期望结果基于mybatis 3.5.13，修改trim.xml或者升级mybatis后需要重新生成:
+ *
+ * <pre>
+ * curl -O https://repo1.maven.org/maven2/org/mybatis/mybatis/3.5.13/mybatis-3.5.13.jar
+ * java -cp mybatis-3.5.13.jar Generate.java trim.xml > trim_expected.xml
+ * </pre>
+ *
+ * 设置MYBATIS_JAR为jar包的路径后运行go test -run Test_trimRule_conformance，会直接和mybatis生成的SQL比较。
+ *
+ * 所有if的test都使用非空的参数值，使得条件都成立。
+ */
+public class Generate {
+    private static final String[] PARAMS = {"a", "b", "id"};
+
+    public static void main(String[] args) throws Exception {
+        String file = args.length > 0 ? args[0] : "trim.xml";
+
+        Configuration configuration = new Configuration();
+        try (InputStream in = new FileInputStream(file)) {
+            new XMLMapperBuilder(in, configuration, file, configuration.getSqlFragments()).parse();
+        }
+
+        Map<String, Object> params = new HashMap<>();
+        for (String name : PARAMS) {
+            params.put(name, 1);
+        }
+
+        String version = Configuration.class.getPackage().getImplementationVersion();
+        StringBuilder out = new StringBuilder();
+        out.append("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n");
+        out.append("<!-- 由Generate.java使用mybatis ").append(version)
+            .append("生成，不要手动修改，比较时忽略空白字符的差异 -->\n");
+        out.append("<cases>\n");
+        for (String id : statementIds(file)) {
+            BoundSql bound = configuration.getMappedStatement(id).getBoundSql(params);
+            String sql = bound.getSql().trim().replaceAll("\\s+", " ");
+            out.append("    <case id=\"").append(id).append("\">").append(escape(sql)).append("</case>\n");
+        }
+        out.append("</cases>");
+        System.out.println(out);
+    }
+
+    /** 按照文件中的顺序获取语句的id */
+    private static List<String> statementIds(String file) throws Exception {
+        DocumentBuilderFactory factory = DocumentBuilderFactory.newInstance();
+        factory.setFeature("http://apache.org/xml/features/nonvalidating/load-external-dtd", false);
+        DocumentBuilder builder = factory.newDocumentBuilder();
+        NodeList nodes = builder.parse(file).getDocumentElement().getChildNodes();
+
+        List<String> ids = new ArrayList<>();
+        for (int i = 0; i < nodes.getLength(); i++) {
+            Node node = nodes.item(i);
+            if (node instanceof Element && ((Element) node).hasAttribute("id")) {
+                String tag = node.getNodeName();
+                if (!tag.equals("sql") && !tag.equals("resultMap")) {
+                    ids.add(((Element) node).getAttribute("id"));
+                }
+            }
+        }
+        return ids;
+    }
+
+    private static String escape(String s) {
+        return s.replace("&", "&amp;").replace("<", "&lt;").replace(">", "&gt;");
+    }
+}
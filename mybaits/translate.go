@@ -0,0 +1,396 @@
+package mybaits
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blastrain/vitess-sqlparser/sqlparser"
+)
+
+// Translation MySQL语句转换为PostgreSQL语句的结果
+type Translation struct {
+	SQL          string
+	Untranslated []string // 无法转换而原样保留的结构，按出现顺序排列
+}
+
+// TranslateOption 转换的选项
+type TranslateOption func(t *translator)
+
+// WithConflictColumns 设置表table在ON CONFLICT中使用的唯一键列，
+// INSERT ... ON DUPLICATE KEY UPDATE需要它才能转换为ON CONFLICT (...) DO UPDATE
+func WithConflictColumns(table string, columns ...string) TranslateOption {
+	return func(t *translator) {
+		t.conflicts[strings.ToLower(table)] = columns
+	}
+}
+
+// WithBooleanColumns 设置在PostgreSQL中是boolean的列，这些列和0、1的比较以及赋值会转换为false、true
+func WithBooleanColumns(columns ...string) TranslateOption {
+	return func(t *translator) {
+		for _, c := range columns {
+			t.booleans[strings.ToLower(c)] = true
+		}
+	}
+}
+
+type translator struct {
+	conflicts    map[string][]string
+	booleans     map[string]bool
+	untranslated []string
+}
+
+// TranslateToPostgreSQL 使用语句规范化时的解析器将MySQL语句转换为PostgreSQL语句，
+// 转换反引号标识符、LIMIT a,b、IFNULL、NOW()等时间函数、INSERT ... ON DUPLICATE KEY UPDATE、
+// INSERT IGNORE以及布尔字面量，?占位符转换为$n，无法转换的结构会原样保留并记录在Untranslated中
+func TranslateToPostgreSQL(sql string, opts ...TranslateOption) (*Translation, error) {
+	stmt, err := sqlparser.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &translator{
+		conflicts: make(map[string][]string),
+		booleans:  make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	buf := sqlparser.NewTrackedBuffer(t.format)
+	return &Translation{
+		SQL:          buf.WriteNode(stmt).String(),
+		Untranslated: t.untranslated,
+	}, nil
+}
+
+// TranslateToPostgreSQL 将语句id渲染为使用占位符的语句后转换为PostgreSQL语句
+func (m *Mapper) TranslateToPostgreSQL(id string, opts ...TranslateOption) (*Translation, error) {
+	child, ok := m.root[id]
+	if !ok {
+		return nil, fmt.Errorf("statement(%v) not found", id)
+	}
+	cm := m.newChildMapper(child)
+	cm.mocker = &mocker{mode: MockPlaceholder}
+	return TranslateToPostgreSQL(cm.getRawStatement(), opts...)
+}
+
+func (t *translator) note(construct string) {
+	for _, u := range t.untranslated {
+		if u == construct {
+			return
+		}
+	}
+	t.untranslated = append(t.untranslated, construct)
+}
+
+// timeFunctions MySQL的时间函数对应的PostgreSQL表达式
+var timeFunctions = map[string]string{
+	"now":               "now()",
+	"current_timestamp": "current_timestamp",
+	"localtime":         "localtimestamp",
+	"localtimestamp":    "localtimestamp",
+	"sysdate":           "clock_timestamp()",
+	"curdate":           "current_date",
+	"current_date":      "current_date",
+	"curtime":           "current_time",
+	"current_time":      "current_time",
+	"utc_timestamp":     "(now() at time zone 'utc')",
+	"utc_date":          "(now() at time zone 'utc')::date",
+	"utc_time":          "(now() at time zone 'utc')::time",
+}
+
+// mysqlFunctions PostgreSQL中没有对应函数的MySQL函数
+var mysqlFunctions = map[string]bool{
+	"date_format": true, "str_to_date": true, "from_unixtime": true, "unix_timestamp": true,
+	"last_insert_id": true, "found_rows": true, "find_in_set": true, "field": true,
+	"date_add": true, "date_sub": true, "adddate": true, "subdate": true, "timestampdiff": true,
+	"datediff": true, "instr": true, "locate": true, "elt": true,
+}
+
+// convertTypes CAST和CONVERT的MySQL类型对应的PostgreSQL类型
+var convertTypes = map[string]string{
+	"signed": "bigint", "signed integer": "bigint", "unsigned": "bigint", "unsigned integer": "bigint",
+	"char": "text", "nchar": "text", "binary": "bytea", "datetime": "timestamp",
+	"date": "date", "time": "time", "decimal": "numeric", "json": "json",
+}
+
+func (t *translator) format(buf *sqlparser.TrackedBuffer, node sqlparser.SQLNode) {
+	switch n := node.(type) {
+	case sqlparser.ColIdent, sqlparser.TableIdent:
+		buf.WriteString(quoteIdent(sqlparser.String(n)))
+	case *sqlparser.SQLVal:
+		t.formatVal(buf, n)
+	case *sqlparser.Limit:
+		if n == nil {
+			return
+		}
+		buf.Myprintf(" limit %v", n.Rowcount)
+		if n.Offset != nil {
+			buf.Myprintf(" offset %v", n.Offset)
+		}
+	case *sqlparser.IndexHints:
+		// PostgreSQL没有索引提示，去掉后不影响结果
+	case *sqlparser.Select:
+		sel := *n
+		switch strings.TrimSpace(sel.Lock) {
+		case "lock in share mode":
+			sel.Lock = " for share"
+		}
+		if sel.Cache != "" || sel.Hints != "" {
+			sel.Cache, sel.Hints = "", ""
+		}
+		sel.Format(buf)
+	case *sqlparser.FuncExpr:
+		t.formatFunc(buf, n)
+	case *sqlparser.GroupConcatExpr:
+		if len(n.Exprs) != 1 {
+			t.note("group_concat with multiple expressions")
+			n.Format(buf)
+			return
+		}
+		separator := "','"
+		if s := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(n.Separator), "separator")); s != "" {
+			separator = s
+		}
+		buf.Myprintf("string_agg(%s%v::text, %s%v)", n.Distinct, n.Exprs, separator, n.OrderBy)
+	case *sqlparser.ConvertExpr:
+		typ, ok := convertTypes[strings.ToLower(n.Type.Type)]
+		if !ok {
+			buf.Myprintf("cast(%v as %v)", n.Expr, n.Type)
+			return
+		}
+		if (typ == "text" || typ == "numeric") && n.Type.Length != nil {
+			if typ == "text" {
+				typ = "varchar"
+			}
+			buf.Myprintf("cast(%v as %s(%v", n.Expr, typ, n.Type.Length)
+			if n.Type.Scale != nil {
+				buf.Myprintf(", %v", n.Type.Scale)
+			}
+			buf.Myprintf("))")
+			return
+		}
+		buf.Myprintf("cast(%v as %s)", n.Expr, typ)
+	case *sqlparser.ConvertUsingExpr:
+		t.note("convert using " + n.Type)
+		n.Format(buf)
+	case *sqlparser.MatchExpr:
+		t.note("match against")
+		n.Format(buf)
+	case *sqlparser.BinaryExpr:
+		switch n.Operator {
+		case sqlparser.IntDivStr:
+			buf.Myprintf("div(%v, %v)", n.Left, n.Right)
+		case sqlparser.BitXorStr:
+			buf.Myprintf("%v # %v", n.Left, n.Right)
+		default:
+			n.Format(buf)
+		}
+	case *sqlparser.ComparisonExpr:
+		c := *n
+		switch c.Operator {
+		case sqlparser.RegexpStr:
+			c.Operator = "~*"
+		case sqlparser.NotRegexpStr:
+			c.Operator = "!~*"
+		case sqlparser.NullSafeEqualStr:
+			c.Operator = "is not distinct from"
+		case sqlparser.EqualStr, sqlparser.NotEqualStr:
+			c.Left, c.Right = t.boolean(c.Left, c.Right), t.boolean(c.Right, c.Left)
+		}
+		c.Format(buf)
+	case *sqlparser.UpdateExpr:
+		buf.Myprintf("%v = %v", n.Name, t.boolean(n.Expr, n.Name))
+	case *sqlparser.Insert:
+		t.formatInsert(buf, n)
+	default:
+		node.Format(buf)
+	}
+}
+
+// quoteIdent 将标识符转换为PostgreSQL的标识符，解析器只会给MySQL的关键字和特殊字符加反引号，
+// 所以PostgreSQL的保留字(如user)需要单独判断，和PostgreSQL一样转换为小写后加双引号，
+// 包含特殊字符的标识符原样加双引号，其他标识符不加引号
+func quoteIdent(ident string) string {
+	name := ident
+	if strings.HasPrefix(ident, "`") {
+		name = strings.ReplaceAll(ident[1:len(ident)-1], "``", "`")
+	}
+	if !plainIdent(name) {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+	if lower := strings.ToLower(name); postgresReserved[lower] {
+		return `"` + lower + `"`
+	}
+	return name
+}
+
+// plainIdent 判断name是否是PostgreSQL中不需要引号的标识符
+func plainIdent(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && (c >= '0' && c <= '9' || c == '$')) {
+			continue
+		}
+		return false
+	}
+	return name != ""
+}
+
+// postgresReserved PostgreSQL的保留字，作为标识符时必须使用双引号
+var postgresReserved = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true, "as": true,
+	"asc": true, "asymmetric": true, "authorization": true, "binary": true, "both": true, "case": true,
+	"cast": true, "check": true, "collate": true, "collation": true, "column": true, "concurrently": true,
+	"constraint": true, "create": true, "cross": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_schema": true, "current_time": true, "current_timestamp": true,
+	"current_user": true, "default": true, "deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true, "for": true, "foreign": true,
+	"freeze": true, "from": true, "full": true, "grant": true, "group": true, "having": true, "ilike": true,
+	"in": true, "initially": true, "inner": true, "intersect": true, "into": true, "is": true, "isnull": true,
+	"join": true, "lateral": true, "leading": true, "left": true, "like": true, "limit": true,
+	"localtime": true, "localtimestamp": true, "natural": true, "not": true, "notnull": true, "null": true,
+	"offset": true, "on": true, "only": true, "or": true, "order": true, "outer": true, "overlaps": true,
+	"placing": true, "primary": true, "references": true, "returning": true, "right": true, "select": true,
+	"session_user": true, "similar": true, "some": true, "symmetric": true, "system_user": true,
+	"table": true, "tablesample": true, "then": true, "to": true, "trailing": true, "true": true,
+	"union": true, "unique": true, "user": true, "using": true, "variadic": true, "verbose": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
+
+func (t *translator) formatVal(buf *sqlparser.TrackedBuffer, v *sqlparser.SQLVal) {
+	switch v.Type {
+	case sqlparser.StrVal:
+		// PostgreSQL的字符串中反斜杠不是转义字符，只需要转义单引号
+		buf.WriteString("'" + strings.ReplaceAll(string(v.Val), "'", "''") + "'")
+	case sqlparser.ValArg:
+		// 解析时?被转换为:v1、:v2...，按照顺序转换为$1、$2...
+		if name := string(v.Val); strings.HasPrefix(name, ":v") {
+			buf.WriteString("$" + name[2:])
+			return
+		}
+		v.Format(buf)
+	case sqlparser.HexVal:
+		buf.WriteString(`'\x` + string(v.Val) + `'::bytea`)
+	default:
+		v.Format(buf)
+	}
+}
+
+func (t *translator) formatFunc(buf *sqlparser.TrackedBuffer, f *sqlparser.FuncExpr) {
+	name := f.Name.Lowered()
+	if !f.Qualifier.IsEmpty() {
+		f.Format(buf)
+		return
+	}
+	if expr, ok := timeFunctions[name]; ok && len(f.Exprs) == 0 {
+		buf.WriteString(expr)
+		return
+	}
+	switch {
+	case name == "ifnull":
+		buf.Myprintf("coalesce(%v)", f.Exprs)
+	case name == "if" && len(f.Exprs) == 3:
+		buf.Myprintf("case when %v then %v else %v end", f.Exprs[0], f.Exprs[1], f.Exprs[2])
+	case name == "rand" && len(f.Exprs) == 0:
+		buf.WriteString("random()")
+	default:
+		if mysqlFunctions[name] {
+			t.note(name + "()")
+		}
+		f.Format(buf)
+	}
+}
+
+// boolean expr是0或1并且other是布尔列时转换为false或true
+func (t *translator) boolean(expr sqlparser.Expr, other sqlparser.SQLNode) sqlparser.Expr {
+	col, ok := other.(*sqlparser.ColName)
+	if !ok || !t.booleans[col.Name.Lowered()] {
+		return expr
+	}
+	if v, ok := expr.(*sqlparser.SQLVal); ok && v.Type == sqlparser.IntVal {
+		switch string(v.Val) {
+		case "0":
+			return sqlparser.BoolVal(false)
+		case "1":
+			return sqlparser.BoolVal(true)
+		}
+	}
+	return expr
+}
+
+// formatInsert INSERT IGNORE转换为ON CONFLICT DO NOTHING，ON DUPLICATE KEY UPDATE转换为ON CONFLICT DO UPDATE，
+// VALUES(col)转换为excluded.col，更新表达式中没有限定符的列使用表名限定
+func (t *translator) formatInsert(buf *sqlparser.TrackedBuffer, n *sqlparser.Insert) {
+	if n.Action == sqlparser.ReplaceStr {
+		t.note("replace into")
+		n.Format(buf)
+		return
+	}
+
+	rows := n.Rows
+	if values, ok := n.Rows.(sqlparser.Values); ok && len(t.booleans) > 0 {
+		converted := make(sqlparser.Values, len(values))
+		for i, tuple := range values {
+			converted[i] = make(sqlparser.ValTuple, len(tuple))
+			for j, expr := range tuple {
+				converted[i][j] = expr
+				if j < len(n.Columns) {
+					converted[i][j] = t.boolean(expr, &sqlparser.ColName{Name: n.Columns[j]})
+				}
+			}
+		}
+		rows = converted
+	}
+	buf.Myprintf("insert %vinto %v%v %v", n.Comments, n.Table, n.Columns, rows)
+
+	switch {
+	case len(n.OnDup) > 0:
+		buf.WriteString(" on conflict ")
+		if columns, ok := t.conflicts[strings.ToLower(n.Table.Name.String())]; ok {
+			var quoted []string
+			for _, c := range columns {
+				quoted = append(quoted, quoteIdent(sqlparser.String(sqlparser.NewColIdent(c))))
+			}
+			buf.WriteString("(" + strings.Join(quoted, ", ") + ") ")
+		} else {
+			t.note("on duplicate key update without conflict columns of table " + n.Table.Name.String())
+		}
+		buf.WriteString("do update set ")
+		for i, expr := range n.OnDup {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.Myprintf("%v = %v", expr.Name, t.boolean(t.conflictExpr(expr.Expr, n.Table), expr.Name))
+		}
+	case strings.TrimSpace(n.Ignore) != "":
+		buf.WriteString(" on conflict do nothing")
+	}
+}
+
+// conflictExpr 将ON DUPLICATE KEY UPDATE的表达式转换为ON CONFLICT DO UPDATE的表达式
+func (t *translator) conflictExpr(expr sqlparser.Expr, table sqlparser.TableName) sqlparser.Expr {
+	switch e := expr.(type) {
+	case *sqlparser.ValuesFuncExpr:
+		return &sqlparser.ColName{Name: e.Name, Qualifier: sqlparser.TableName{Name: sqlparser.NewTableIdent("excluded")}}
+	case *sqlparser.ColName:
+		if e.Qualifier.IsEmpty() {
+			return &sqlparser.ColName{Name: e.Name, Qualifier: table}
+		}
+	case *sqlparser.BinaryExpr:
+		return &sqlparser.BinaryExpr{Operator: e.Operator, Left: t.conflictExpr(e.Left, table), Right: t.conflictExpr(e.Right, table)}
+	case *sqlparser.ParenExpr:
+		return &sqlparser.ParenExpr{Expr: t.conflictExpr(e.Expr, table)}
+	case *sqlparser.FuncExpr:
+		f := *e
+		f.Exprs = make(sqlparser.SelectExprs, len(e.Exprs))
+		for i, se := range e.Exprs {
+			f.Exprs[i] = se
+			if ae, ok := se.(*sqlparser.AliasedExpr); ok {
+				f.Exprs[i] = &sqlparser.AliasedExpr{Expr: t.conflictExpr(ae.Expr, table), As: ae.As}
+			}
+		}
+		return &f
+	}
+	return expr
+}
@@ -0,0 +1,130 @@
+package mybaits
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslateToPostgreSQL(t *testing.T) {
+	tests := []struct {
+		name             string
+		sql              string
+		opts             []TranslateOption
+		want             string
+		wantUntranslated []string
+	}{
+		{
+			name: "identifiers",
+			sql:  "select `order`, `my col`, `name` from `my table` use index (idx) where `t`.`id` = ?",
+			want: `select "order", "my col", name from "my table" where t.id = $1`,
+		},
+		{
+			name: "postgresReserved",
+			sql:  "select `order`, name, `Order`.`User` from `user` as `Order` join `group` on `group`.id = `Order`.`UserID`",
+			want: `select "order", name, "order"."user" from "user" as "order" join "group" on "group".id = "order".UserID`,
+		},
+		{
+			name: "limit",
+			sql:  "select id from t limit 10, 20",
+			want: "select id from t limit 20 offset 10",
+		},
+		{
+			name: "limitPlaceholders",
+			sql:  "select id from t where a = ? limit ?, ?",
+			want: "select id from t where a = $1 limit $3 offset $2",
+		},
+		{
+			name: "functions",
+			sql:  "select ifnull(a, 0), if(b > 1, 'x', 'y'), now(), current_timestamp(), sysdate(), curdate(), rand() from t",
+			want: "select coalesce(a, 0), case when b > 1 then 'x' else 'y' end, now(), current_timestamp, clock_timestamp(), current_date, random() from t",
+		},
+		{
+			name: "groupConcat",
+			sql:  "select group_concat(distinct name order by name separator ';'), group_concat(id) from t",
+			want: "select string_agg(distinct name::text, ';' order by name asc), string_agg(id::text, ',') from t",
+		},
+		{
+			name: "onDuplicateKey",
+			sql:  "insert into counter (id, hits) values (?, 1) on duplicate key update hits = hits + values(hits)",
+			opts: []TranslateOption{WithConflictColumns("counter", "id")},
+			want: "insert into counter(id, hits) values ($1, 1) on conflict (id) do update set hits = counter.hits + excluded.hits",
+		},
+		{
+			name:             "onDuplicateKeyWithoutConflict",
+			sql:              "insert into counter (id, hits) values (?, 1) on duplicate key update hits = values(hits)",
+			want:             "insert into counter(id, hits) values ($1, 1) on conflict do update set hits = excluded.hits",
+			wantUntranslated: []string{"on duplicate key update without conflict columns of table counter"},
+		},
+		{
+			name: "insertIgnore",
+			sql:  "insert ignore into t (a) values (1)",
+			want: "insert into t(a) values (1) on conflict do nothing",
+		},
+		{
+			name: "booleans",
+			sql:  "update t set deleted = 1, n = 1 where active = 0 and ok = true",
+			opts: []TranslateOption{WithBooleanColumns("deleted", "active")},
+			want: "update t set deleted = true, n = 1 where active = false and ok = true",
+		},
+		{
+			name: "booleanValues",
+			sql:  "insert into t (id, deleted) values (1, 0)",
+			opts: []TranslateOption{WithBooleanColumns("deleted")},
+			want: "insert into t(id, deleted) values (1, false)",
+		},
+		{
+			name: "operators",
+			sql:  "select a div 2, cast(a as unsigned), convert(b, char(10)) from t where c regexp 'x' and d <=> null lock in share mode",
+			want: "select div(a, 2), cast(a as bigint), cast(b as varchar(10)) from t where c ~* 'x' and d is not distinct from null for share",
+		},
+		{
+			name: "strings",
+			sql:  `select 'it''s', 'a\\b' from t`,
+			want: `select 'it''s', 'a\b' from t`,
+		},
+		{
+			name:             "untranslated",
+			sql:              "replace into t (`a`) select ifnull(b, 1) from s where match(c) against ('x')",
+			want:             "replace into t(a) select coalesce(b, 1) from s where match(c) against ('x')",
+			wantUntranslated: []string{"replace into", "match against"},
+		},
+		{
+			name:             "untranslatedFunctions",
+			sql:              "select date_format(b, '%Y'), last_insert_id(), date_format(c, '%m') from s",
+			want:             "select date_format(b, '%Y'), last_insert_id(), date_format(c, '%m') from s",
+			wantUntranslated: []string{"date_format()", "last_insert_id()"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TranslateToPostgreSQL(tt.sql, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SQL != tt.want {
+				t.Errorf("SQL = %v, want %v", got.SQL, tt.want)
+			}
+			if !reflect.DeepEqual(got.Untranslated, tt.wantUntranslated) {
+				t.Errorf("Untranslated = %q, want %q", got.Untranslated, tt.wantUntranslated)
+			}
+		})
+	}
+}
+
+func TestMapper_TranslateToPostgreSQL(t *testing.T) {
+	m, err := NewMapper("testdata/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.TranslateToPostgreSQL("selectOrders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "select o.id, o.name from sales.orders as o join customers as c on o.customer_id = c.id where o.id = $1"
+	if got.SQL != want || got.Untranslated != nil {
+		t.Errorf("TranslateToPostgreSQL() = %+v, want %v", got, want)
+	}
+	if _, err = m.TranslateToPostgreSQL("missing"); err == nil {
+		t.Errorf("TranslateToPostgreSQL() error = nil, want not found")
+	}
+}
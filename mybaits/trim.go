@@ -0,0 +1,77 @@
+package mybaits
+
+import "strings"
+
+// trimRule trim、where、set的规则，和mybatis 3的TrimSqlNode一致
+type trimRule struct {
+	prefix          string
+	suffix          string
+	prefixOverrides []string
+	suffixOverrides []string
+}
+
+var (
+	whereRule = trimRule{
+		prefix:          "WHERE",
+		prefixOverrides: []string{"AND ", "OR ", "AND\n", "OR\n", "AND\r", "OR\r", "AND\t", "OR\t"},
+	}
+	setRule = trimRule{
+		prefix:          "SET",
+		prefixOverrides: []string{","},
+		suffixOverrides: []string{","},
+	}
+)
+
+// newTrimRule 通过trim元素的属性生成规则
+func newTrimRule(prefix, suffix, prefixOverrides, suffixOverrides string) trimRule {
+	return trimRule{
+		prefix:          prefix,
+		suffix:          suffix,
+		prefixOverrides: parseOverrides(prefixOverrides),
+		suffixOverrides: parseOverrides(suffixOverrides),
+	}
+}
+
+// parseOverrides 以|分隔overrides并转为大写，和mybatis一致，保留每一项中的空白字符
+func parseOverrides(overrides string) []string {
+	var list []string
+	for _, o := range strings.Split(overrides, "|") {
+		if o != "" {
+			list = append(list, strings.ToUpper(o))
+		}
+	}
+	return list
+}
+
+// apply 对sql应用规则，sql去掉首尾空白字符后为空时返回空字符串
+func (r trimRule) apply(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if sql == "" {
+		return ""
+	}
+	upper := strings.ToUpper(sql)
+
+	// mybatis只匹配大写后的overrides，但删除的是去掉首尾空白字符后的长度，
+	// 所以AND 只会删除AND，保留后面的空白字符
+	for _, o := range r.prefixOverrides {
+		if strings.HasPrefix(upper, o) {
+			sql = sql[len(strings.TrimSpace(o)):]
+			break
+		}
+	}
+	if r.prefix != "" {
+		sql = r.prefix + " " + sql
+	}
+
+	for _, o := range r.suffixOverrides {
+		trimmed := strings.TrimSpace(o)
+		if strings.HasSuffix(upper, o) || strings.HasSuffix(upper, trimmed) {
+			sql = sql[:len(sql)-len(trimmed)]
+			break
+		}
+	}
+	if r.suffix != "" {
+		sql = sql + " " + r.suffix
+	}
+	return sql
+}
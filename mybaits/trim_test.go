@@ -0,0 +1,104 @@
+package mybaits
+
+import (
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type conformanceCases struct {
+	Cases []struct {
+		ID  string `xml:"id,attr"`
+		SQL string `xml:",chardata"`
+	} `xml:"case"`
+}
+
+func Test_trimRule_conformance(t *testing.T) {
+	m, err := NewMapper("testdata/conformance/trim.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := conformanceExpected(t)
+	if len(expected.Cases) != len(m.IDs()) {
+		t.Fatalf("cases = %v, statements = %v", len(expected.Cases), len(m.IDs()))
+	}
+
+	for _, c := range expected.Cases {
+		t.Run(c.ID, func(t *testing.T) {
+			child, ok := m.root[c.ID]
+			if !ok {
+				t.Fatalf("statement(%v) not found", c.ID)
+			}
+			got := strings.TrimSpace(whitespaceRegex.ReplaceAllString(m.newChildMapper(child).getRawStatement(), " "))
+			want := strings.TrimSpace(whitespaceRegex.ReplaceAllString(c.SQL, " "))
+			if got != want {
+				t.Errorf("getRawStatement() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// conformanceExpected 读取trim_expected.xml，环境变量MYBATIS_JAR为mybatis的jar包时，
+// 使用mybatis运行Generate.java生成期望的SQL，并检查trim_expected.xml是否和生成的一致
+func conformanceExpected(t *testing.T) conformanceCases {
+	data, err := os.ReadFile("testdata/conformance/trim_expected.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected conformanceCases
+	if err = xml.Unmarshal(data, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	jar := os.Getenv("MYBATIS_JAR")
+	if jar == "" {
+		return expected
+	}
+	cmd := exec.Command("java", "-cp", jar, "Generate.java", "trim.xml")
+	cmd.Dir = "testdata/conformance"
+	if data, err = cmd.Output(); err != nil {
+		t.Fatalf("run Generate.java fail. err: %v", err)
+	}
+	var generated conformanceCases
+	if err = xml.Unmarshal(data, &generated); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(generated, expected) {
+		t.Errorf("trim_expected.xml differs from the output of Generate.java, regenerate it:\n%s", data)
+	}
+	return generated
+}
+
+func Test_parseOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+		want      []string
+	}{
+		{
+			name:      "empty",
+			overrides: "",
+			want:      nil,
+		},
+		{
+			name:      "keepSpace",
+			overrides: "and |or ",
+			want:      []string{"AND ", "OR "},
+		},
+		{
+			name:      "skipEmpty",
+			overrides: ",||AND",
+			want:      []string{",", "AND"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseOverrides(tt.overrides); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOverrides() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
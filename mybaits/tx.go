@@ -0,0 +1,170 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Propagation 事务的传播方式
+type Propagation int
+
+// 传播方式
+const (
+	PropagationRequired    Propagation = iota // 上下文中已有事务时加入该事务并使用保存点，否则开启新事务
+	PropagationRequiresNew                    // 总是开启新事务，和上下文中已有的事务互不影响
+)
+
+// TxOption 事务的选项
+type TxOption func(c *txConfig)
+
+type txConfig struct {
+	propagation Propagation
+	opts        sql.TxOptions
+}
+
+// WithPropagation 设置事务的传播方式，默认为PropagationRequired
+func WithPropagation(p Propagation) TxOption {
+	return func(c *txConfig) {
+		c.propagation = p
+	}
+}
+
+// WithIsolation 设置开启新事务时的隔离级别，默认使用数据库的隔离级别，加入已有事务时不生效
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(c *txConfig) {
+		c.opts.Isolation = level
+	}
+}
+
+// WithReadOnly 设置开启新事务时是否只读，加入已有事务时不生效
+func WithReadOnly(readOnly bool) TxOption {
+	return func(c *txConfig) {
+		c.opts.ReadOnly = readOnly
+	}
+}
+
+// TxManager 在*sql.DB上管理事务，事务保存在上下文中，使用同一个*sql.DB的Session会在上下文的事务中执行语句
+type TxManager struct {
+	db *sql.DB
+}
+
+// NewTxManager 生成在db上管理事务的TxManager
+func NewTxManager(db *sql.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// txKey 上下文中保存事务的键，不同的*sql.DB的事务互不影响
+type txKey struct {
+	db *sql.DB
+}
+
+// txState 上下文中的事务
+type txState struct {
+	tx         *sql.Tx
+	mu         sync.Mutex
+	savepoints int
+	done       bool // 事务已经提交或者回滚
+}
+
+func (s *txState) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+}
+
+// activeTx 获取上下文中db未结束的事务
+func activeTx(ctx context.Context, db *sql.DB) (*txState, bool) {
+	state, ok := ctx.Value(txKey{db: db}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state, !state.done
+}
+
+// TxFromContext 获取上下文中db的事务，事务已经提交或者回滚时返回false
+func TxFromContext(ctx context.Context, db *sql.DB) (*sql.Tx, bool) {
+	state, ok := activeTx(ctx, db)
+	if !ok {
+		return nil, false
+	}
+	return state.tx, true
+}
+
+// Tx 在事务中执行fn，fn的上下文中保存了事务。fn返回错误或者panic时回滚，否则提交，
+// 加入已有事务时回滚到进入时的保存点，提交时释放保存点，panic会在回滚后继续抛出
+func (t *TxManager) Tx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) (err error) {
+	c := &txConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if state, ok := activeTx(ctx, t.db); ok && c.propagation == PropagationRequired {
+		return t.savepoint(ctx, state, fn)
+	}
+
+	tx, err := t.db.BeginTx(ctx, &c.opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction fail. err: %v", err)
+	}
+	state := &txState{tx: tx}
+	defer func() {
+		defer state.finish()
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			if rerr := tx.Rollback(); rerr != nil {
+				err = fmt.Errorf("%v, rollback fail. err: %v", err, rerr)
+			}
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commit transaction fail. err: %v", err)
+		}
+	}()
+	return fn(context.WithValue(ctx, txKey{db: t.db}, state))
+}
+
+// savepoint 在已有事务的保存点中执行fn
+func (t *TxManager) savepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) (err error) {
+	state.mu.Lock()
+	state.savepoints++
+	name := "mybaits_sp_" + strconv.Itoa(state.savepoints)
+	state.mu.Unlock()
+
+	if _, err = state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("create savepoint(%v) fail. err: %v", name, err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(r)
+		}
+		if err != nil {
+			if _, rerr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rerr != nil {
+				err = fmt.Errorf("%v, rollback to savepoint(%v) fail. err: %v", err, name, rerr)
+			}
+			return
+		}
+		if _, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+			err = fmt.Errorf("release savepoint(%v) fail. err: %v", name, err)
+		}
+	}()
+	return fn(ctx)
+}
+
+// executor 上下文中有exec对应的事务时使用该事务
+func executor(ctx context.Context, exec Executor) Executor {
+	if db, ok := exec.(*sql.DB); ok {
+		if tx, ok := TxFromContext(ctx, db); ok {
+			return tx
+		}
+	}
+	return exec
+}
@@ -0,0 +1,151 @@
+package mybaits
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTxManager_Tx(t *testing.T) {
+	m, err := NewMapper("testdata/session.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errFail := errors.New("fail")
+	update := func(s *Session, ctx context.Context) error {
+		_, err := s.Exec(ctx, "updateTitle", map[string]interface{}{"id": 1, "title": "go"})
+		return err
+	}
+	const updateQuery = "update blog set title = ? where id = ?"
+
+	tests := []struct {
+		name      string
+		fn        func(tm *TxManager, s *Session) func(ctx context.Context) error
+		opts      []TxOption
+		wantErr   bool
+		wantPanic bool
+		wantCalls []string
+	}{
+		{
+			name: "commit",
+			fn: func(tm *TxManager, s *Session) func(ctx context.Context) error {
+				return func(ctx context.Context) error { return update(s, ctx) }
+			},
+			wantCalls: []string{"BEGIN", updateQuery, "COMMIT"},
+		},
+		{
+			name: "rollback",
+			fn: func(tm *TxManager, s *Session) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					update(s, ctx)
+					return errFail
+				}
+			},
+			wantErr:   true,
+			wantCalls: []string{"BEGIN", updateQuery, "ROLLBACK"},
+		},
+		{
+			name: "panic",
+			fn: func(tm *TxManager, s *Session) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					update(s, ctx)
+					panic(errFail)
+				}
+			},
+			wantPanic: true,
+			wantCalls: []string{"BEGIN", updateQuery, "ROLLBACK"},
+		},
+		{
+			name: "isolation",
+			fn: func(tm *TxManager, s *Session) func(ctx context.Context) error {
+				return func(ctx context.Context) error { return nil }
+			},
+			opts:      []TxOption{WithIsolation(sql.LevelSerializable), WithReadOnly(true)},
+			wantCalls: []string{"BEGIN Serializable READ ONLY", "COMMIT"},
+		},
+		{
+			name: "savepoint",
+			fn: func(tm *TxManager, s *Session) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					if err := tm.Tx(ctx, func(ctx context.Context) error { return update(s, ctx) }); err != nil {
+						return err
+					}
+					// 内层的错误只回滚到保存点，外层可以继续执行
+					if err := tm.Tx(ctx, func(ctx context.Context) error { return errFail }); err != errFail {
+						return errors.New("savepoint error is not returned")
+					}
+					return update(s, ctx)
+				}
+			},
+			wantCalls: []string{
+				"BEGIN",
+				"SAVEPOINT mybaits_sp_1", updateQuery, "RELEASE SAVEPOINT mybaits_sp_1",
+				"SAVEPOINT mybaits_sp_2", "ROLLBACK TO SAVEPOINT mybaits_sp_2",
+				updateQuery, "COMMIT",
+			},
+		},
+		{
+			name: "requiresNew",
+			fn: func(tm *TxManager, s *Session) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					tm.Tx(ctx, func(ctx context.Context) error {
+						return update(s, ctx)
+					}, WithPropagation(PropagationRequiresNew))
+					return errFail
+				}
+			},
+			wantErr:   true,
+			wantCalls: []string{"BEGIN", "BEGIN", updateQuery, "COMMIT", "ROLLBACK"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDriver{}
+			db := newFakeDB(d)
+			tm := NewTxManager(db)
+			s := NewSession(db, []*Mapper{m})
+
+			func() {
+				defer func() {
+					if r := recover(); (r != nil) != tt.wantPanic {
+						t.Errorf("Tx() panic = %v, wantPanic %v", r, tt.wantPanic)
+					}
+				}()
+				if err := tm.Tx(context.Background(), tt.fn(tm, s), tt.opts...); (err != nil) != tt.wantErr {
+					t.Errorf("Tx() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}()
+
+			var got []string
+			for _, c := range d.recorded() {
+				got = append(got, c.Query)
+			}
+			if !reflect.DeepEqual(got, tt.wantCalls) {
+				t.Errorf("calls = %q, want %q", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTxFromContext(t *testing.T) {
+	db, other := newFakeDB(&fakeDriver{}), newFakeDB(&fakeDriver{})
+	if _, ok := TxFromContext(context.Background(), db); ok {
+		t.Errorf("TxFromContext() found transaction in background context")
+	}
+	var txCtx context.Context
+	NewTxManager(db).Tx(context.Background(), func(ctx context.Context) error {
+		txCtx = ctx
+		if tx, ok := TxFromContext(ctx, db); !ok || tx == nil {
+			t.Errorf("TxFromContext() = %v, %v", tx, ok)
+		}
+		if _, ok := TxFromContext(ctx, other); ok {
+			t.Errorf("TxFromContext() found transaction of another db")
+		}
+		return nil
+	})
+	if _, ok := TxFromContext(txCtx, db); ok {
+		t.Errorf("TxFromContext() found committed transaction")
+	}
+}